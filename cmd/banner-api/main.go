@@ -4,7 +4,7 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,11 +13,21 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/numtide/banner-generator/internal/api"
 	"github.com/numtide/banner-generator/internal/banner"
+	"github.com/numtide/banner-generator/internal/cache"
 	"github.com/numtide/banner-generator/internal/config"
+	"github.com/numtide/banner-generator/internal/converter"
 	"github.com/numtide/banner-generator/internal/fonts"
+	"github.com/numtide/banner-generator/internal/forge"
 	"github.com/numtide/banner-generator/internal/github"
+	"github.com/numtide/banner-generator/internal/history"
+	"github.com/numtide/banner-generator/internal/logging"
+	"github.com/numtide/banner-generator/internal/metrics"
 )
 
+// bannerFormats are the file extensions of the banner routes; raster and PDF
+// formats are only served when enabled in [raster]
+const bannerFormats = "svg|png|webp|jpg|jpeg|avif|pdf"
+
 func main() {
 	// Parse command-line flags
 	var configPath string
@@ -32,7 +42,10 @@ func main() {
 	loader := config.NewConfigLoader()
 	appConfig, err := loader.LoadConfig(configPath)
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		fatal("Failed to load configuration", "error", err)
+	}
+	if err := logging.Setup(appConfig.Logging.Level, appConfig.Logging.Format); err != nil {
+		fatal("Failed to configure logging", "error", err)
 	}
 
 	// Create configuration for access control
@@ -41,7 +54,7 @@ func main() {
 		// Combine orgs and users into allowlist format
 		allowedEntries = append(allowedEntries, appConfig.AccessControl.AllowedOrgs...)
 		allowedEntries = append(allowedEntries, appConfig.AccessControl.AllowedUsers...)
-		log.Printf("Access control enabled with allowlist: %v", allowedEntries)
+		slog.Info("Access control enabled", "allowlist", allowedEntries)
 	}
 	cfg := config.NewConfig(allowedEntries)
 
@@ -56,27 +69,58 @@ func main() {
 	// Parse cache durations
 	httpCacheDuration, err := time.ParseDuration(appConfig.Cache.HTTPCacheDuration)
 	if err != nil {
-		log.Printf("Invalid HTTP cache duration '%s', using default 1h: %v", appConfig.Cache.HTTPCacheDuration, err)
+		slog.Warn("Invalid HTTP cache duration, using default 1h", "value", appConfig.Cache.HTTPCacheDuration, "error", err)
 		httpCacheDuration = 1 * time.Hour
 	}
 	cfg.HTTPCacheDuration = httpCacheDuration
 
 	apiCacheDuration, err := time.ParseDuration(appConfig.Cache.APICacheDuration)
 	if err != nil {
-		log.Printf("Invalid API cache duration '%s', using default 1h: %v", appConfig.Cache.APICacheDuration, err)
+		slog.Warn("Invalid API cache duration, using default 1h", "value", appConfig.Cache.APICacheDuration, "error", err)
 		apiCacheDuration = 1 * time.Hour
 	}
 	cfg.APICacheDuration = apiCacheDuration
 
-	log.Printf("Cache configuration: HTTP=%v, API=%v", httpCacheDuration, apiCacheDuration)
+	cacheRetention, err := time.ParseDuration(appConfig.Cache.Retention)
+	if err != nil {
+		slog.Warn("Invalid cache retention, using default", "value", appConfig.Cache.Retention, "default", github.DefaultCacheRetention, "error", err)
+		cacheRetention = github.DefaultCacheRetention
+	}
+
+	slog.Info("Cache configuration", "http", httpCacheDuration, "api", apiCacheDuration, "backend", appConfig.Cache.Backend)
+
+	// Create the API response cache
+	apiCache, err := cache.New(cache.Options{
+		Backend:  appConfig.Cache.Backend,
+		Path:     appConfig.Cache.Path,
+		RedisURL: appConfig.Cache.RedisURL,
+	})
+	if err != nil {
+		fatal("Failed to create cache", "error", err)
+	}
+
+	// Collect Prometheus metrics
+	var appMetrics *api.Metrics
+	if appConfig.Metrics.Enabled {
+		metricsToken, err := appConfig.Metrics.ReadToken()
+		if err != nil {
+			fatal("Failed to read metrics token", "error", err)
+		}
+		appMetrics = api.NewMetrics(metrics.NewRegistry(), metricsToken)
+		if metricsToken == "" {
+			slog.Info("Serving metrics on /metrics without authentication")
+		} else {
+			slog.Info("Serving metrics on /metrics to requests with the bearer token")
+		}
+	}
 
 	// Create font manager from config
 	fontManager := fonts.NewManager(appConfig.Fonts.FontsDir)
-	log.Printf("Font directory: %s", appConfig.Fonts.FontsDir)
+	slog.Info("Font directory", "path", appConfig.Fonts.FontsDir)
 
 	// Use template path from config
 	templatePath := appConfig.TemplatePath
-	log.Printf("Using template: %s", templatePath)
+	slog.Info("Using template", "template", templatePath)
 
 	// Determine base URL for web fonts
 	fontBaseURL := ""
@@ -87,29 +131,215 @@ func main() {
 			// Use local server URL
 			fontBaseURL = fmt.Sprintf("http://%s:%d", appConfig.Server.Host, appConfig.Server.Port)
 		}
-		log.Printf("Web fonts enabled, base URL: %s", fontBaseURL)
+		slog.Info("Web fonts enabled", "base_url", fontBaseURL)
 	}
 
 	// Initialize components
-	svgBuilder := banner.NewSimpleSVGBuilder(fontManager, templatePath, appConfig.Fonts.EnableWebFonts, fontBaseURL)
-	log.Printf("Using simple SVG-based banner generation")
+	simpleBuilder := banner.NewSimpleSVGBuilder(fontManager, templatePath, appConfig.Fonts.EnableWebFonts, fontBaseURL)
+	var svgBuilder banner.Builder = simpleBuilder
+	slog.Info("Using simple SVG-based banner generation")
+	if appMetrics != nil {
+		appMetrics.ObserveTemplate(simpleBuilder)
+		appMetrics.ObserveFonts(fontManager)
+		svgBuilder = appMetrics.Builder(svgBuilder)
+	}
+	if appConfig.Cache.RenderCacheMB > 0 {
+		renderCache := banner.NewRenderCache(svgBuilder, int64(appConfig.Cache.RenderCacheMB)<<20)
+		if appMetrics != nil {
+			appMetrics.ObserveRenderCache("render", renderCache)
+		}
+		svgBuilder = renderCache
+		slog.Info("Caching rendered banners", "max_mb", appConfig.Cache.RenderCacheMB)
+	}
 
-	githubClient := github.NewClient(appConfig.GitHub.Token, cfg.APICacheDuration)
+	githubOptions, err := github.OptionsFromConfig(appConfig.GitHub)
+	if err != nil {
+		fatal("Failed to load GitHub configuration", "error", err)
+	}
+	githubOptions.CacheDuration = cfg.APICacheDuration
+	forgeCache := apiCache
+	if appMetrics != nil {
+		forgeCache = appMetrics.ObserveCache("api", apiCache)
+		githubOptions.Observe = appMetrics.ObserveGitHubRequest
+	}
+	githubOptions.Cache = forgeCache
+	githubOptions.CacheRetention = cacheRetention
+
+	switch {
+	case githubOptions.App != nil:
+		slog.Info("Authenticating as GitHub App", "app_id", githubOptions.App.AppID)
+	case len(githubOptions.Tokens) > 1:
+		slog.Info("Spreading GitHub API requests across tokens", "tokens", len(githubOptions.Tokens))
+	}
+	if githubOptions.BaseURL != "" {
+		slog.Info("Using GitHub API", "base_url", githubOptions.BaseURL)
+	}
+	for _, host := range githubOptions.Hosts {
+		slog.Info("Routing owners to GitHub API", "owners", host.Owners, "base_url", host.BaseURL)
+	}
+
+	githubClient, err := github.NewClientWithOptions(githubOptions)
+	if err != nil {
+		fatal("Failed to create GitHub client", "error", err)
+	}
+	if appMetrics != nil {
+		appMetrics.ObserveRateLimit(githubClient)
+	}
+	defer func() {
+		if err := githubClient.Close(); err != nil {
+			slog.Warn("Failed to close cache", "error", err)
+		}
+	}()
+
+	// Record star and fork history for sparklines
+	record := func(p forge.Provider) forge.Provider { return p }
+	if appConfig.History.Enabled {
+		historyStore, err := history.Open(appConfig.History.Path)
+		if err != nil {
+			fatal("Failed to open history", "error", err)
+		}
+		defer func() {
+			if err := historyStore.Close(); err != nil {
+				slog.Warn("Failed to close history", "error", err)
+			}
+		}()
+
+		interval, err := time.ParseDuration(appConfig.History.Interval)
+		if err != nil {
+			slog.Warn("Invalid history interval, using default 1h", "value", appConfig.History.Interval, "error", err)
+			interval = time.Hour
+		}
+		window, err := time.ParseDuration(appConfig.History.Window)
+		if err != nil {
+			slog.Warn("Invalid history window, using default 2160h", "value", appConfig.History.Window, "error", err)
+			window = 90 * 24 * time.Hour
+		}
+
+		record = func(p forge.Provider) forge.Provider {
+			return history.Recording(p, historyStore, interval, window)
+		}
+		slog.Info("Recording star and fork history", "path", appConfig.History.Path)
+	}
+
+	// Other forges share the GitHub client's cache settings
+	providers := forge.NewRegistry(record(githubClient))
+	for name, forgeConfig := range appConfig.Forges {
+		provider, err := forge.NewFromConfig(name, forgeConfig)
+		if err != nil {
+			fatal("Failed to configure forge", "error", err)
+		}
+		providers.Register(record(forge.Cached(provider, forgeCache, cfg.APICacheDuration, cacheRetention)))
+		slog.Info("Serving banners for forge", "forge", name)
+	}
 
 	// Create handler
-	handler := api.NewHandler(svgBuilder, githubClient, cfg)
+	handler := api.NewHandler(svgBuilder, githubClient, providers, cfg)
+	if appConfig.Private.Enabled {
+		privateAccess, err := api.NewPrivateAccess(appConfig.Private.Owners)
+		if err != nil {
+			fatal("Failed to configure private repository access", "error", err)
+		}
+		handler.EnablePrivateAccess(privateAccess)
+		slog.Info("Private repository banners enabled", "owners", privateAccess.Owners())
+	}
+	signingKey, err := appConfig.Signing.ReadKey()
+	if err != nil {
+		fatal("Failed to read signing key", "error", err)
+	}
+	if signingKey != "" {
+		handler.EnableURLSigning(api.NewURLSigner([]byte(signingKey)))
+		slog.Info("Signed URL customizations enabled")
+	}
+	if appConfig.Custom.Enabled {
+		// Custom banners render any text, so only signed URLs are served
+		if signingKey == "" {
+			fatal("Custom banners require a signing key in [signing]")
+		}
+		handler.EnableCustomBanners()
+		slog.Info("Custom banners enabled for signed URLs")
+	}
+	if appConfig.Raster.Enabled {
+		rasterCacheDuration, err := time.ParseDuration(appConfig.Raster.CacheDuration)
+		if err != nil {
+			slog.Warn("Invalid raster cache duration, using default 24h", "value", appConfig.Raster.CacheDuration, "error", err)
+			rasterCacheDuration = 24 * time.Hour
+		}
+
+		// A chromium rasterizer keeps a warm browser with one tab per
+		// concurrent conversion
+		rasterizer, err := converter.NewRasterizer(converter.RasterizerOptions{
+			Backend: appConfig.Raster.Renderer,
+			Tabs:    appConfig.Raster.Concurrency,
+			Fonts:   fontManager,
+		})
+		if err != nil {
+			fatal("Failed to configure rasterizer", "error", err)
+		}
+		defer func() {
+			if err := rasterizer.Close(); err != nil {
+				slog.Warn("Failed to close rasterizer", "error", err)
+			}
+		}()
+
+		var formats []converter.Format
+		for _, name := range appConfig.Raster.Formats {
+			format, err := converter.ParseFormat(name)
+			if err != nil {
+				fatal("Failed to configure raster formats", "error", err)
+			}
+			if format == converter.FormatPDF && appConfig.Raster.Renderer == converter.BackendGo {
+				fatal("Failed to configure raster formats", "error", converter.ErrPDFUnsupported)
+			}
+			formats = append(formats, format)
+		}
+
+		// Rasterized banners are large, so in memory they get a cache of
+		// their own with a size limit
+		var rasterize api.RasterizeFunc = rasterizer.Render
+		rasterCache := apiCache
+		if _, ok := apiCache.(*cache.Memory); ok {
+			rasterCache = cache.NewBoundedMemory(int64(appConfig.Raster.CacheMB) << 20)
+		}
+		if appMetrics != nil {
+			rasterize = appMetrics.Rasterize(rasterize)
+			rasterCache = appMetrics.ObserveCache("raster", rasterCache)
+		}
+
+		handler.EnableRaster(api.NewRaster(rasterize, rasterCache, api.RasterOptions{
+			Concurrency:   appConfig.Raster.Concurrency,
+			MaxScale:      appConfig.Raster.MaxScale,
+			CacheDuration: rasterCacheDuration,
+			Formats:       formats,
+			Quality:       appConfig.Raster.Quality,
+		}))
+		slog.Info("Raster banners enabled", "formats", formats, "renderer", appConfig.Raster.Renderer, "concurrency", appConfig.Raster.Concurrency)
+	}
 
 	// Setup routes
 	r := mux.NewRouter()
 	r.HandleFunc("/health", handler.HealthCheck).Methods("GET")
-	r.HandleFunc("/banner/{owner}/{repo}.svg", handler.GenerateBanner).Methods("GET")
+	if appConfig.Custom.Enabled {
+		r.HandleFunc("/banner/custom.{format:"+bannerFormats+"}", handler.GenerateCustomBanner).Methods("GET")
+	}
+	r.HandleFunc("/banner/{owner}/{repo}.{format:"+bannerFormats+"}", handler.GenerateBanner).Methods("GET")
+	r.HandleFunc("/banner/{forge}/{owner:.+}/{repo}.{format:"+bannerFormats+"}", handler.GenerateBanner).Methods("GET")
+	if appConfig.Private.Enabled {
+		r.HandleFunc("/private/banner/{owner}/{repo}.{format:"+bannerFormats+"}", handler.GeneratePrivateBanner).Methods("GET")
+		r.HandleFunc("/private/banner/{forge}/{owner:.+}/{repo}.{format:"+bannerFormats+"}", handler.GeneratePrivateBanner).Methods("GET")
+	}
 	r.HandleFunc("/", handler.Index).Methods("GET")
+	if appMetrics != nil {
+		r.Handle("/metrics", appMetrics).Methods("GET")
+	}
 
 	// Serve font files using font manager
 	r.PathPrefix("/fonts/").Handler(fontManager)
 
 	// Setup middleware
 	r.Use(api.LoggingMiddleware)
+	if appMetrics != nil {
+		appMetrics.Instrument(r)
+	}
 
 	// Create server
 	readTimeout, _ := time.ParseDuration(appConfig.Server.ReadTimeout)
@@ -125,9 +355,9 @@ func main() {
 
 	// Start server
 	go func() {
-		log.Printf("Starting server on %s:%d", appConfig.Server.Host, appConfig.Server.Port)
+		slog.Info("Starting server", "addr", srv.Addr)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
+			fatal("Failed to start server", "error", err)
 		}
 	}()
 
@@ -140,7 +370,13 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Printf("Server forced to shutdown: %v", err)
+		slog.Warn("Server forced to shutdown", "error", err)
 	}
-	log.Println("Server exiting")
+	slog.Info("Server exiting")
+}
+
+// fatal logs an error and exits, like log.Fatalf
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
@@ -3,9 +3,14 @@ package main
 import (
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/numtide/banner-generator/internal/api"
 	"github.com/numtide/banner-generator/internal/cli"
 	"github.com/numtide/banner-generator/internal/config"
+	"github.com/numtide/banner-generator/internal/converter"
+	"github.com/numtide/banner-generator/internal/github"
+	"github.com/numtide/banner-generator/internal/history"
 	"github.com/spf13/cobra"
 )
 
@@ -25,60 +30,300 @@ After generating the banner, upload it manually via:
 	var (
 		configPath  string
 		githubToken string
+		githubURL   string
 		outputPath  string
 		noStats     bool
 		darkMode    bool
+		renderer    string
+		format      string
+		quality     int
+	)
+
+	// Offline metadata flags
+	var (
+		metadataPath string
+		fromDir      string
+		name         string
+		description  string
+		language     string
+		stars        int
 	)
 
 	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Path to configuration file")
 	rootCmd.PersistentFlags().StringVar(&githubToken, "token", "", "GitHub API token (overrides config)")
+	rootCmd.PersistentFlags().StringVar(&githubURL, "github-url", "", "GitHub Enterprise Server API URL (overrides config)")
 
 	// Generate command
 	var generateCmd = &cobra.Command{
-		Use:   "generate [owner/repo]",
-		Short: "Generate a PNG banner for a repository",
-		Long: `Generate a PNG banner for a GitHub repository.
+		Use:   "generate [[forge:]owner/repo]",
+		Short: "Generate a banner image for a repository",
+		Long: `Generate a PNG, WebP, JPEG, AVIF or PDF banner for a GitHub repository.
+The format is taken from --format or the output file's extension. PDFs keep
+text and shapes as vectors at the banner's exact size, for print and slides.
+
+Repositories on other forges are given with a forge prefix configured in
+[forges], e.g. gitlab:group/project or codeberg:owner/repo.
+
+With --name, --metadata or --from-dir, the banner is generated from local
+data without calling any API, e.g. in sandboxed builds. --from-dir derives
+the name, description and language from a project checkout.
+
+Banners are rendered with headless Chromium unless --renderer go selects the
+built-in rasterizer, which needs no browser but supports less CSS and cannot
+produce PDFs.
 
 After generating, upload the banner as social preview via:
   https://github.com/OWNER/REPO/settings > Social preview > Edit`,
-		Args: cobra.ExactArgs(1),
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Load configuration
 			appConfig, err := loadConfig(configPath)
 			if err != nil {
 				return fmt.Errorf("failed to load configuration: %w", err)
 			}
+			if renderer != "" {
+				appConfig.Raster.Renderer = renderer
+			}
+
+			imageFormat, outputPath, err := cli.ResolveOutput(format, outputPath, cmd.Flags().Changed("output"))
+			if err != nil {
+				return err
+			}
+			imageOptions := cli.ImageOptions{
+				NoStats:  noStats,
+				DarkMode: darkMode,
+				Format:   imageFormat,
+				Quality:  quality,
+			}
+
+			// Generate from local data when given, skipping the network
+			if fromDir != "" || metadataPath != "" || cmd.Flags().Changed("name") {
+				repoData := &github.Repository{}
+				switch {
+				case len(args) > 0:
+					return fmt.Errorf("a repository cannot be combined with --name, --metadata or --from-dir")
+				case fromDir != "" && metadataPath != "":
+					return fmt.Errorf("--from-dir and --metadata cannot be combined")
+				case fromDir != "":
+					if repoData, err = cli.RepositoryFromDir(fromDir); err != nil {
+						return err
+					}
+				case metadataPath != "":
+					if repoData, err = cli.LoadMetadata(metadataPath); err != nil {
+						return err
+					}
+				}
+				if cmd.Flags().Changed("name") {
+					repoData.Name = name
+				}
+				if cmd.Flags().Changed("description") {
+					repoData.Description = description
+				}
+				if cmd.Flags().Changed("language") {
+					repoData.Language = language
+				}
+				if cmd.Flags().Changed("stars") {
+					repoData.StargazersCount = stars
+				}
+				if repoData.Name == "" {
+					return fmt.Errorf("a repository name is required, from --name or the local data")
+				}
+
+				generator, err := cli.NewOfflineGenerator(appConfig)
+				if err != nil {
+					return fmt.Errorf("failed to initialize generator: %w", err)
+				}
+				defer func() { _ = generator.Close() }()
+				return generator.GenerateFromRepository(repoData, outputPath, imageOptions)
+			}
+
+			if len(args) != 1 {
+				return fmt.Errorf("a repository is required unless --name, --metadata or --from-dir is given")
+			}
 
 			// Override token if provided
 			if githubToken != "" {
 				appConfig.GitHub.Token = githubToken
+				appConfig.GitHub.Tokens = nil
+				appConfig.GitHub.TokenFiles = nil
+			}
+
+			// Override API URL if provided
+			if githubURL != "" {
+				appConfig.GitHub.BaseURL = githubURL
+				appConfig.GitHub.UploadURL = ""
 			}
 
 			generator, err := cli.NewGeneratorWithConfig(appConfig)
 			if err != nil {
 				return fmt.Errorf("failed to initialize generator: %w", err)
 			}
+			defer func() { _ = generator.Close() }()
 
 			repoPath := args[0]
-			if err := generator.GeneratePNG(repoPath, outputPath, noStats, darkMode); err != nil {
+			if err := generator.Generate(repoPath, outputPath, imageOptions); err != nil {
 				return err
 			}
 
 			// Print instructions for setting social preview
-			fmt.Println()
-			fmt.Println("To set as social preview, go to:")
-			fmt.Printf("  https://github.com/%s/settings\n", repoPath)
-			fmt.Println("Then scroll to 'Social preview' and click 'Edit' to upload the generated PNG.")
+			if settingsURL, ok := generator.SocialPreviewURL(repoPath); ok {
+				fmt.Println()
+				fmt.Println("To set as social preview, go to:")
+				fmt.Printf("  %s\n", settingsURL)
+				fmt.Println("Then scroll to 'Social preview' and click 'Edit' to upload the generated image.")
+			}
 
 			return nil
 		},
 	}
 
-	generateCmd.Flags().StringVarP(&outputPath, "output", "o", "banner.png", "Output path for PNG file")
+	generateCmd.Flags().StringVarP(&outputPath, "output", "o", "banner.png", "Output path for the image file")
+	generateCmd.Flags().StringVar(&format, "format", "", "Output format: png, webp, jpg, avif or pdf (default from the output extension)")
+	generateCmd.Flags().IntVar(&quality, "quality", converter.DefaultQuality, "Quality of webp, jpg and avif output (1-100)")
 	generateCmd.Flags().BoolVar(&noStats, "no-stats", false, "Omit stars, forks, and language from banner")
 	generateCmd.Flags().BoolVar(&darkMode, "dark", false, "Use dark color scheme (default is light)")
+	generateCmd.Flags().StringVar(&renderer, "renderer", "", "Rasterizer backend: chromium or go (overrides config)")
+	generateCmd.Flags().StringVar(&metadataPath, "metadata", "", "Read repository data from a JSON file instead of the API")
+	generateCmd.Flags().StringVar(&fromDir, "from-dir", "", "Derive repository data from a local project directory")
+	generateCmd.Flags().StringVar(&name, "name", "", "Repository name (generates offline)")
+	generateCmd.Flags().StringVar(&description, "description", "", "Repository description (overrides local data)")
+	generateCmd.Flags().StringVar(&language, "language", "", "Primary language (overrides local data)")
+	generateCmd.Flags().IntVar(&stars, "stars", 0, "Star count (overrides local data)")
 	rootCmd.AddCommand(generateCmd)
 
+	// History commands
+	var historyCmd = &cobra.Command{
+		Use:   "history",
+		Short: "Export or import recorded star and fork history",
+		Long: `Export or import the star and fork history recorded by banner-api
+when [history] is enabled, e.g. to move it between hosts or to seed it
+from another source. The database must not be in use by a running server.`,
+	}
+
+	var exportCmd = &cobra.Command{
+		Use:   "export [file.csv]",
+		Short: "Write recorded history as CSV (to stdout by default)",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openHistory(configPath)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = store.Close() }()
+
+			if len(args) == 0 {
+				return store.Export(os.Stdout)
+			}
+
+			file, err := os.Create(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to create export file: %w", err)
+			}
+			if err := store.Export(file); err != nil {
+				_ = file.Close()
+				return err
+			}
+			return file.Close()
+		},
+	}
+
+	var importCmd = &cobra.Command{
+		Use:   "import file.csv",
+		Short: "Add history from a CSV file in the export format",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openHistory(configPath)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = store.Close() }()
+
+			file, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open import file: %w", err)
+			}
+			defer func() { _ = file.Close() }()
+
+			count, err := store.Import(file)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Imported %d samples\n", count)
+			return nil
+		},
+	}
+
+	historyCmd.AddCommand(exportCmd, importCmd)
+	rootCmd.AddCommand(historyCmd)
+
+	// Sign URL command
+	var (
+		signingKey string
+		expiresIn  time.Duration
+		owner      string
+	)
+	var signURLCmd = &cobra.Command{
+		Use:   "sign-url URL",
+		Short: "Sign a banner URL with query parameters for banner-api",
+		Long: `Sign a banner URL so that banner-api applies its title, description and
+tag query parameters, or serves it as a /banner/custom.svg banner. The key
+is read from [signing] in the configuration (or SIGNING_KEY) unless --key
+is given.
+
+With --owner, the URL of a private banner is signed with that owner's
+signing_key from [private.owners] instead (?owner_sig=). Sign customized
+private banners first without --owner, then with it.
+
+Examples:
+  banner-cli sign-url 'https://banners.example.com/banner/custom.svg?title=My+Talk&tag=NixCon' --expires 720h
+  banner-cli sign-url 'https://banners.example.com/private/banner/numtide/secret.svg' --owner numtide --expires 720h`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key := signingKey
+			if key == "" {
+				appConfig, err := loadConfig(configPath)
+				if err != nil {
+					return fmt.Errorf("failed to load configuration: %w", err)
+				}
+				if owner != "" {
+					key, err = appConfig.Private.Owners[owner].ReadSigningKey()
+				} else {
+					key, err = appConfig.Signing.ReadKey()
+				}
+				if err != nil {
+					return fmt.Errorf("failed to read signing key: %w", err)
+				}
+			}
+			if key == "" && owner != "" {
+				return fmt.Errorf("no signing key configured for owner '%s' (set signing_key in [private.owners] or --key)", owner)
+			}
+			if key == "" {
+				return fmt.Errorf("no signing key configured (set [signing] key or --key)")
+			}
+
+			var expires time.Time
+			if expiresIn > 0 {
+				expires = time.Now().Add(expiresIn)
+			}
+			var signed string
+			var err error
+			if owner != "" {
+				signed, err = api.SignPrivateURL([]byte(key), args[0], expires)
+			} else {
+				signed, err = api.NewURLSigner([]byte(key)).SignURL(args[0], expires)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Println(signed)
+			return nil
+		},
+	}
+	signURLCmd.Flags().StringVar(&signingKey, "key", "", "Signing key (overrides config)")
+	signURLCmd.Flags().DurationVar(&expiresIn, "expires", 0, "Time until the signature expires, e.g. 720h (default never)")
+	signURLCmd.Flags().StringVar(&owner, "owner", "", "Sign a private banner URL with this owner's key from [private.owners]")
+	rootCmd.AddCommand(signURLCmd)
+
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
 	}
@@ -89,3 +334,12 @@ func loadConfig(configPath string) (*config.AppConfig, error) {
 	loader := config.NewConfigLoader()
 	return loader.LoadConfig(configPath)
 }
+
+// openHistory opens the history database configured in [history]
+func openHistory(configPath string) (*history.Store, error) {
+	appConfig, err := loadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	return history.Open(appConfig.History.Path)
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/numtide/banner-generator/internal/cache"
+)
+
+func TestNotModified(t *testing.T) {
+	etag := `"abc"`
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC)
+
+	tests := []struct {
+		name         string
+		ifNoneMatch  string
+		ifModified   string
+		lastModified time.Time
+		want         bool
+	}{
+		{"no conditions", "", "", modified, false},
+		{"matching etag", `"abc"`, "", modified, true},
+		{"weak match", `W/"abc"`, "", modified, true},
+		{"one of several", `"old", "abc"`, "", modified, true},
+		{"any", "*", "", modified, true},
+		{"other etag", `"old"`, "", modified, false},
+		{"etag wins over date", `"old"`, "Wed, 01 May 2024 13:00:00 GMT", modified, false},
+		{"not modified since", "", "Wed, 01 May 2024 12:00:00 GMT", modified, true},
+		{"modified since", "", "Wed, 01 May 2024 11:59:59 GMT", modified, false},
+		{"invalid date", "", "yesterday", modified, false},
+		{"unknown modification time", "", "Wed, 01 May 2024 12:00:00 GMT", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/banner/numtide/public.svg", nil)
+		if tt.ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", tt.ifNoneMatch)
+		}
+		if tt.ifModified != "" {
+			req.Header.Set("If-Modified-Since", tt.ifModified)
+		}
+		if got := notModified(req, etag, tt.lastModified); got != tt.want {
+			t.Errorf("%s: notModified() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConditionalBanners(t *testing.T) {
+	rasterizer := &countingRasterizer{}
+	r := newRasterTestRouter(NewRaster(rasterizer.rasterize, cache.NewMemory(), RasterOptions{MaxScale: 2}))
+
+	get := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, path := range []string{"/banner/numtide/public.svg", "/banner/numtide/public.png?scale=2"} {
+		first := get(path, nil)
+		etag, lastModified := first.Header().Get("ETag"), first.Header().Get("Last-Modified")
+		if first.Code != http.StatusOK || etag == "" || lastModified == "" {
+			t.Fatalf("%s: got status %d, ETag %q, Last-Modified %q", path, first.Code, etag, lastModified)
+		}
+
+		calls := rasterizer.calls.Load()
+		for _, header := range []http.Header{
+			{"If-None-Match": {etag}},
+			{"If-Modified-Since": {lastModified}},
+		} {
+			rec := get(path, header)
+			if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+				t.Errorf("%s with %v: got status %d and %d bytes, want 304 without body", path, header, rec.Code, rec.Body.Len())
+			}
+			if rec.Header().Get("ETag") != etag || rec.Header().Get("Cache-Control") == "" {
+				t.Errorf("%s with %v: 304 lacks ETag or Cache-Control", path, header)
+			}
+		}
+		if rasterizer.calls.Load() != calls {
+			t.Errorf("%s: revalidation rasterized the banner", path)
+		}
+
+		if rec := get(path, http.Header{"If-None-Match": {`"stale"`}}); rec.Code != http.StatusOK {
+			t.Errorf("%s with stale ETag: got status %d, want 200", path, rec.Code)
+		}
+	}
+
+	// Other formats and scales are other representations
+	svg := get("/banner/numtide/public.svg", nil).Header().Get("ETag")
+	png := get("/banner/numtide/public.png", nil).Header().Get("ETag")
+	png2x := get("/banner/numtide/public.png?scale=2", nil).Header().Get("ETag")
+	if svg == png || png == png2x {
+		t.Errorf("ETags are not distinct: %s, %s, %s", svg, png, png2x)
+	}
+}
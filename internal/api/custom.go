@@ -0,0 +1,111 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/numtide/banner-generator/internal/forge"
+	"github.com/numtide/banner-generator/internal/github"
+)
+
+// Length limits of custom banner parameters, in characters
+const (
+	maxCustomTitle       = 40
+	maxCustomDescription = 150
+	maxCustomTag         = 24
+)
+
+// customizationParams are the query parameters that change banner text
+var customizationParams = []string{"title", "description", "tag"}
+
+// customization holds the banner text set by query parameters. The tag
+// takes the place of the language.
+type customization struct {
+	title       string
+	description string
+	tag         string
+}
+
+// hasCustomization reports whether query sets any banner text
+func hasCustomization(query url.Values) bool {
+	for _, name := range customizationParams {
+		if query.Has(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCustomization validates the title, description and tag query
+// parameters
+func parseCustomization(query url.Values) (customization, error) {
+	var c customization
+	var err error
+	if c.title, err = customParam(query, "title", maxCustomTitle); err != nil {
+		return c, err
+	}
+	if c.description, err = customParam(query, "description", maxCustomDescription); err != nil {
+		return c, err
+	}
+	if c.tag, err = customParam(query, "tag", maxCustomTag); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// apply returns a copy of repo with the fields set by c replaced
+func (c customization) apply(repo *github.Repository) *github.Repository {
+	customized := *repo
+	if c.title != "" {
+		customized.Name = c.title
+	}
+	if c.description != "" {
+		customized.Description = c.description
+	}
+	if c.tag != "" {
+		customized.Language = c.tag
+	}
+	return &customized
+}
+
+// key identifies the customization in render keys
+func (c customization) key() string {
+	return url.Values{"title": {c.title}, "description": {c.description}, "tag": {c.tag}}.Encode()
+}
+
+// customRepository builds the banner model of a custom banner from the
+// title, description and tag query parameters
+func customRepository(query url.Values) (*github.Repository, error) {
+	c, err := parseCustomization(query)
+	if err != nil {
+		return nil, err
+	}
+	if c.title == "" {
+		return nil, errors.New("title is required")
+	}
+	return c.apply(&github.Repository{Visibility: forge.VisibilityPublic}), nil
+}
+
+// customParam returns a trimmed query parameter, rejecting values that are
+// repeated, too long or contain control characters
+func customParam(query url.Values, name string, maxLength int) (string, error) {
+	if len(query[name]) > 1 {
+		return "", fmt.Errorf("%s must be given once", name)
+	}
+
+	value := strings.TrimSpace(query.Get(name))
+	if !utf8.ValidString(value) {
+		return "", fmt.Errorf("%s must be valid UTF-8", name)
+	}
+	if utf8.RuneCountInString(value) > maxLength {
+		return "", fmt.Errorf("%s must be at most %d characters", name, maxLength)
+	}
+	if strings.ContainsFunc(value, unicode.IsControl) {
+		return "", fmt.Errorf("%s must not contain control characters", name)
+	}
+	return value, nil
+}
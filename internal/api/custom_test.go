@@ -0,0 +1,305 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/numtide/banner-generator/internal/config"
+	"github.com/numtide/banner-generator/internal/forge"
+)
+
+func TestURLSigner(t *testing.T) {
+	signer := NewURLSigner([]byte("signing-secret"))
+	sig := signer.Sign("/banner/custom.svg", url.Values{"title": {"Talk"}, "tag": {"NixCon"}})
+
+	tests := []struct {
+		name string
+		url  string
+		want bool
+	}{
+		{"signed", "/banner/custom.svg?title=Talk&tag=NixCon&sig=" + sig, true},
+		{"reordered", "/banner/custom.svg?sig=" + sig + "&tag=NixCon&title=Talk", true},
+		{"changed parameter", "/banner/custom.svg?title=Other&tag=NixCon&sig=" + sig, false},
+		{"added parameter", "/banner/custom.svg?title=Talk&tag=NixCon&description=x&sig=" + sig, false},
+		{"other path", "/banner/custom.png?title=Talk&tag=NixCon&sig=" + sig, false},
+		{"unsigned", "/banner/custom.svg?title=Talk&tag=NixCon", false},
+	}
+
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := signer.Verify(u); got != tt.want {
+			t.Errorf("%s: Verify() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestURLSignerExpiry(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	signer := NewURLSigner([]byte("signing-secret"))
+	signer.now = func() time.Time { return now }
+
+	tests := []struct {
+		name    string
+		expires time.Time
+		want    bool
+	}{
+		{"no expiry", time.Time{}, true},
+		{"not expired", now.Add(time.Hour), true},
+		{"expired", now.Add(-time.Second), false},
+		{"expires now", now, false},
+	}
+
+	for _, tt := range tests {
+		signed, err := signer.SignURL("https://banners.example.com/banner/custom.svg?title=Talk&sig=stale", tt.expires)
+		if err != nil {
+			t.Fatalf("%s: SignURL failed: %v", tt.name, err)
+		}
+		u, err := url.Parse(signed)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := signer.Verify(u); got != tt.want {
+			t.Errorf("%s: Verify(%s) = %v, want %v", tt.name, signed, got, tt.want)
+		}
+	}
+
+	// The expiry cannot be extended without signing again
+	signed, _ := signer.SignURL("/banner/custom.svg?title=Talk", now.Add(time.Hour))
+	u, _ := url.Parse(signed)
+	query := u.Query()
+	query.Set("exp", "9999999999")
+	u.RawQuery = query.Encode()
+	if signer.Verify(u) {
+		t.Error("Verify accepted a changed expiry")
+	}
+}
+
+func TestCustomRepository(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		wantErr bool
+	}{
+		{"title only", "title=My+Talk", false},
+		{"all parameters", "title=My+Talk&description=About+%3Cthings%3E&tag=NixCon", false},
+		{"missing title", "description=x", true},
+		{"blank title", "title=+++", true},
+		{"title too long", "title=" + strings.Repeat("a", maxCustomTitle+1), true},
+		{"multibyte title at limit", "title=" + strings.Repeat("é", maxCustomTitle), false},
+		{"control character", "title=a%0Ab", true},
+		{"repeated parameter", "title=a&title=b", true},
+		{"invalid UTF-8", "title=%ff", true},
+	}
+
+	for _, tt := range tests {
+		query, err := url.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		repo, err := customRepository(query)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: customRepository() error = %v, want error: %v", tt.name, err, tt.wantErr)
+		}
+		if err == nil && repo.IsPrivate() {
+			t.Errorf("%s: custom banner is private", tt.name)
+		}
+	}
+}
+
+func TestCustomBanners(t *testing.T) {
+	signer := NewURLSigner([]byte("signing-secret"))
+	h := NewHandler(stubBuilder{}, nil, forge.NewRegistry(fakeProvider{}), config.NewConfig(nil))
+	h.EnableURLSigning(signer)
+	h.EnableCustomBanners()
+
+	r := mux.NewRouter()
+	r.HandleFunc("/banner/custom.{format:svg|png}", h.GenerateCustomBanner)
+
+	signed := func(query url.Values) string {
+		query.Set("sig", signer.Sign("/banner/custom.svg", query))
+		return "/banner/custom.svg?" + query.Encode()
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		body   string
+	}{
+		{"signed", signed(url.Values{"title": {"Talk"}, "description": {"<b>&"}}), http.StatusOK, "<svg><b>&</svg>"},
+		{"unsigned", "/banner/custom.svg?title=Talk", http.StatusForbidden, ""},
+		{"invalid parameters", signed(url.Values{"title": {strings.Repeat("a", 100)}}), http.StatusBadRequest, ""},
+		{"raster disabled", "/banner/custom.png?title=Talk", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: got status %d, want %d", tt.name, rec.Code, tt.status)
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Errorf("%s: got body %q, want %q", tt.name, rec.Body.String(), tt.body)
+		}
+	}
+}
+
+func TestSignedCustomization(t *testing.T) {
+	signer := NewURLSigner([]byte("signing-secret"))
+	h := NewHandler(stubBuilder{}, nil, forge.NewRegistry(fakeProvider{}), config.NewConfig(nil))
+	h.EnableURLSigning(signer)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/banner/{owner}/{repo}.svg", h.GenerateBanner)
+
+	sign := func(rawURL string, expires time.Time) string {
+		signed, err := signer.SignURL(rawURL, expires)
+		if err != nil {
+			t.Fatalf("SignURL failed: %v", err)
+		}
+		return signed
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		body   string
+	}{
+		{"signed", sign("/banner/numtide/public.svg?description=Slides", time.Time{}), http.StatusOK, "<svg>Slides</svg>"},
+		{"unsigned", "/banner/numtide/public.svg?description=Slides", http.StatusOK, "<svg>open</svg>"},
+		{"wrong signature", "/banner/numtide/public.svg?description=Slides&sig=00", http.StatusOK, "<svg>open</svg>"},
+		{"expired", sign("/banner/numtide/public.svg?description=Slides", time.Now().Add(-time.Minute)), http.StatusOK, "<svg>open</svg>"},
+		{"signed but invalid", sign("/banner/numtide/public.svg?description="+strings.Repeat("a", 200), time.Time{}), http.StatusBadRequest, ""},
+		{"signed private repo", sign("/banner/numtide/secret.svg?description=Slides", time.Time{}), http.StatusNotFound, ""},
+		{"signed other spelling", sign("/banner/numtide/Public.svg?description=Slides", time.Time{}), http.StatusOK, "<svg>Slides</svg>"},
+		{"unsigned other spelling", "/banner/numtide/Public.svg?description=Slides", http.StatusMovedPermanently, ""},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: got status %d, want %d", tt.name, rec.Code, tt.status)
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Errorf("%s: got body %q, want %q", tt.name, rec.Body.String(), tt.body)
+		}
+	}
+}
+
+// transferredProvider reports numtide/moved as transferred to another owner
+type transferredProvider struct {
+	fakeProvider
+}
+
+func (p transferredProvider) GetRepositoryData(ctx context.Context, owner, repo string) (*forge.Repository, error) {
+	if strings.EqualFold(repo, "moved") {
+		return &forge.Repository{Name: "moved", Owner: "elsewhere", Description: "moved", Visibility: forge.VisibilityPublic, FetchedAt: fetchedAt}, nil
+	}
+	return p.fakeProvider.GetRepositoryData(ctx, owner, repo)
+}
+
+func TestAllowListChecksCanonicalOwner(t *testing.T) {
+	signer := NewURLSigner([]byte("signing-secret"))
+	h := NewHandler(stubBuilder{}, nil, forge.NewRegistry(transferredProvider{}), config.NewConfig([]string{"numtide"}))
+	h.EnableURLSigning(signer)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/banner/{owner}/{repo}.svg", h.GenerateBanner)
+
+	signed, err := signer.SignURL("/banner/numtide/moved.svg?description=Slides", time.Time{})
+	if err != nil {
+		t.Fatalf("SignURL failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{"allowed", "/banner/NumTide/public.svg", http.StatusMovedPermanently},
+		{"transferred", "/banner/numtide/moved.svg", http.StatusForbidden},
+		{"signed transferred", signed, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s: got status %d, want %d", tt.name, rec.Code, tt.status)
+		}
+	}
+}
+
+func TestSignedPrivateCustomization(t *testing.T) {
+	signer := NewURLSigner([]byte("customization-secret"))
+	access, err := NewPrivateAccess(map[string]config.PrivateOwnerConfig{
+		"numtide": {SigningKey: "owner-secret"},
+	})
+	if err != nil {
+		t.Fatalf("NewPrivateAccess failed: %v", err)
+	}
+	h := NewHandler(stubBuilder{}, nil, forge.NewRegistry(fakeProvider{}), config.NewConfig(nil))
+	h.EnableURLSigning(signer)
+	h.EnablePrivateAccess(access)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/private/banner/{owner}/{repo}.svg", h.GeneratePrivateBanner)
+
+	const path = "/private/banner/numtide/secret.svg"
+	ownerSig := "owner_sig=" + ownerSignature([]byte("owner-secret"), path, "")
+	customized, err := signer.SignURL(path+"?description=Slides", time.Time{})
+	if err != nil {
+		t.Fatalf("SignURL failed: %v", err)
+	}
+	signedPrivate, err := SignPrivateURL([]byte("owner-secret"), customized, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("SignPrivateURL failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		body   string
+	}{
+		{"owner signature", path + "?" + ownerSig, http.StatusOK, "<svg>internal plans</svg>"},
+		{"both signatures", customized + "&" + ownerSig, http.StatusOK, "<svg>Slides</svg>"},
+		{"customization signature only", customized, http.StatusUnauthorized, ""},
+		{"both signatures with expiry", signedPrivate, http.StatusOK, "<svg>Slides</svg>"},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: got status %d, want %d", tt.name, rec.Code, tt.status)
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Errorf("%s: got body %q, want %q", tt.name, rec.Body.String(), tt.body)
+		}
+	}
+}
+
+func TestCustomBannersDisabled(t *testing.T) {
+	h := NewHandler(stubBuilder{}, nil, forge.NewRegistry(fakeProvider{}), config.NewConfig(nil))
+
+	rec := httptest.NewRecorder()
+	h.GenerateCustomBanner(rec, httptest.NewRequest(http.MethodGet, "/banner/custom.svg?title=Talk", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("got status %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
@@ -4,104 +4,452 @@ import (
 	"context"
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/numtide/banner-generator/internal/banner"
 	"github.com/numtide/banner-generator/internal/config"
+	"github.com/numtide/banner-generator/internal/converter"
+	"github.com/numtide/banner-generator/internal/forge"
 	"github.com/numtide/banner-generator/internal/github"
+	"github.com/numtide/banner-generator/internal/logging"
+	"github.com/numtide/banner-generator/internal/singleflight"
 	"github.com/numtide/banner-generator/internal/version"
 )
 
 //go:embed index.html
 var indexHTML []byte
 
+// formatSVG is the banner format served without rasterizing; the route's
+// other file extensions select a raster format
+const formatSVG = "svg"
+
 // Handler handles HTTP requests
 type Handler struct {
 	svgBuilder   banner.Builder
 	githubClient *github.Client
+	providers    *forge.Registry
 	config       *config.Config
+	private      *PrivateAccess
+	raster       *Raster
+	signer       *URLSigner
+	custom       bool
+	renders      singleflight.Group[string]
 }
 
-// NewHandler creates a new API handler
-func NewHandler(svgBuilder banner.Builder, githubClient *github.Client, cfg *config.Config) *Handler {
+// NewHandler creates a new API handler serving banners for every forge in
+// providers. The GitHub client also reports its quota in health checks.
+func NewHandler(svgBuilder banner.Builder, githubClient *github.Client, providers *forge.Registry, cfg *config.Config) *Handler {
 	return &Handler{
 		svgBuilder:   svgBuilder,
 		githubClient: githubClient,
+		providers:    providers,
 		config:       cfg,
 	}
 }
 
+// EnablePrivateAccess allows banners of private repositories on the
+// /private/banner/ routes for requests authenticated by access
+func (h *Handler) EnablePrivateAccess(access *PrivateAccess) {
+	h.private = access
+	h.config.PrivateOwners = access.Owners()
+}
+
+// EnableRaster serves raster banners on the .png, .webp, .jpg and .avif
+// routes, in the formats raster supports
+func (h *Handler) EnableRaster(raster *Raster) {
+	h.raster = raster
+}
+
+// EnableURLSigning applies the title, description and tag query parameters
+// to repository banners on URLs signed by signer. Unsigned URLs get the
+// plain banner.
+func (h *Handler) EnableURLSigning(signer *URLSigner) {
+	h.signer = signer
+}
+
+// EnableCustomBanners serves banners of arbitrary projects on
+// /banner/custom.{format}, to signed URLs only
+func (h *Handler) EnableCustomBanners() {
+	h.custom = true
+}
+
 // HealthCheck returns the health status of the service
 func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
+	githubStatus := map[string]interface{}{
+		"rate_limit": h.githubClient.RateLimit(),
+	}
+	if tokens := h.githubClient.TokenStatus(); tokens != nil {
+		githubStatus["tokens"] = tokens
+	}
+	if hosts := h.githubClient.HostStatus(); len(hosts) > 1 {
+		githubStatus["hosts"] = hosts
+	}
+
 	response := map[string]interface{}{
 		"status":  "healthy",
 		"version": version.Version,
 		"commit":  version.Commit,
 		"time":    time.Now().Format(time.RFC3339),
+		"github":  githubStatus,
+	}
+	if renderCache, ok := h.svgBuilder.(*banner.RenderCache); ok {
+		response["render_cache"] = renderCache.Stats()
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		// Log error but don't write to response as headers are already sent
-		log.Printf("Failed to encode health check response: %v", err)
+		slog.ErrorContext(r.Context(), "Failed to encode health check response", "error", err)
 	}
 }
 
-// GenerateBanner generates an SVG banner for a GitHub repository
+// GenerateBanner generates a banner for a public repository. The forge route
+// variable selects the provider and defaults to GitHub, the format variable
+// selects SVG (default) or a raster format.
 func (h *Handler) GenerateBanner(w http.ResponseWriter, r *http.Request) {
+	h.serveBanner(w, r, false)
+}
+
+// GeneratePrivateBanner generates a banner for a public or private
+// repository, for requests carrying the owner's bearer token or URL signature
+func (h *Handler) GeneratePrivateBanner(w http.ResponseWriter, r *http.Request) {
+	h.serveBanner(w, r, true)
+}
+
+// serveBanner generates a banner, on the authenticated route if private is set
+func (h *Handler) serveBanner(w http.ResponseWriter, r *http.Request, private bool) {
 	vars := mux.Vars(r)
+	forgeName := vars["forge"]
 	owner := vars["owner"]
 	repo := vars["repo"]
+	format := vars["format"]
+
+	if forgeName == "" {
+		forgeName = forge.DefaultForge
+	}
+	if format == "" {
+		format = formatSVG
+	}
+	logging.Add(r.Context(), "forge", forgeName, "owner", owner, "repo", repo, "format", format)
 
 	if owner == "" || repo == "" {
 		http.Error(w, "Invalid repository format", http.StatusBadRequest)
 		return
 	}
 
+	// Validate raster parameters before doing any work
+	rasterFormat, scale, ok := h.parseFormat(w, r, format)
+	if !ok {
+		return
+	}
+	custom, ok := h.parseSignedCustomization(w, r)
+	if !ok {
+		return
+	}
+
+	provider, err := h.providers.Get(forgeName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
 	// Check access control
-	if !h.config.IsAllowed(owner, repo) {
+	if !h.config.IsAllowedOn(provider.Name(), owner, repo) {
 		http.Error(w, "Access denied: This repository is not allowed", http.StatusForbidden)
 		return
 	}
+	if private && (h.private == nil || !h.private.Authenticate(r, provider.Name(), owner)) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
 
 	// Create context with timeout
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
 	// Fetch repository data
-	repoData, err := h.githubClient.GetRepositoryData(ctx, owner, repo)
-	if err != nil {
+	cacheDuration := h.config.HTTPCacheDuration
+	renderKey := provider.Name() + ":" + owner + "/" + repo
+	repoData, err := provider.GetRepositoryData(ctx, owner, repo)
+	if errors.Is(err, forge.ErrRateLimited) {
+		// Degrade to a banner without stats rather than failing, and make
+		// sure it is not cached past the rate limit reset
+		slog.WarnContext(ctx, "Serving banner without stats", "error", err)
+		repoData = &forge.Repository{Name: repo, Owner: owner, Visibility: forge.VisibilityPublic}
+		renderKey += ":degraded"
+		cacheDuration = min(cacheDuration, h.rateLimitRetry(provider, owner))
+	} else if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to fetch repository data: %v", err), http.StatusNotFound)
 		return
 	}
 
-	// Generate SVG
-	svg, err := h.svgBuilder.BuildBanner(repoData)
+	// Check the allowlist again with the names the forge reports, which
+	// differ from the URL's for renamed and transferred repositories, before
+	// redirecting there or serving a signed URL in place
+	if !h.config.IsAllowedOn(provider.Name(), repoData.Owner, repoData.Name) {
+		http.Error(w, "Access denied: This repository is not allowed", http.StatusForbidden)
+		return
+	}
+
+	// Never reveal private repositories on the public route, not even
+	// whether they exist
+	if !h.config.IsAllowed(provider.Name(), repoData.Owner, repoData.Name, repoData.IsPrivate(), private) {
+		http.Error(w, "Failed to fetch repository data: repository not found", http.StatusNotFound)
+		return
+	}
+	cacheScope := "public"
+	if private {
+		cacheScope = "private"
+	}
+
+	// With canonical names, providers report the owner and name as the
+	// forge spells them; send other spellings, renamed and transferred
+	// repositories to the canonical banner URL. Signed URLs are served in
+	// place, as their signatures cover the path and would not verify there.
+	if (repoData.Owner != owner || repoData.Name != repo) && !signed(r.URL.Query()) {
+		target := url.URL{
+			Path:     bannerPath(private, vars["forge"], repoData.Owner, repoData.Name, format),
+			RawQuery: r.URL.RawQuery,
+		}
+		w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", cacheScope, int(cacheDuration.Seconds())))
+		http.Redirect(w, r, target.String(), http.StatusMovedPermanently)
+		return
+	}
+
+	if custom != nil {
+		repoData = custom.apply(repoData)
+		renderKey += "?" + custom.key()
+	}
+
+	// Generate SVG, sharing the work with concurrent requests for the same repository
+	svg, _, err := h.renders.Do(ctx, renderKey, func(ctx context.Context) (string, error) {
+		return h.svgBuilder.BuildBanner(ctx, repoData)
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to generate banner: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeBanner(w, r, svg, bannerResponse{
+		format:        rasterFormat,
+		scale:         scale,
+		private:       private,
+		cacheDuration: cacheDuration,
+		lastModified:  dataModified(repoData),
+	})
+}
+
+// GenerateCustomBanner generates a banner of an arbitrary project, such as a
+// talk or a blog post, from the title, description and tag query parameters.
+// The URL must be signed since the endpoint renders any text.
+func (h *Handler) GenerateCustomBanner(w http.ResponseWriter, r *http.Request) {
+	if !h.custom || h.signer == nil {
+		http.Error(w, "Custom banners are not enabled", http.StatusNotFound)
+		return
+	}
+	format := mux.Vars(r)["format"]
+	if format == "" {
+		format = formatSVG
+	}
+	logging.Add(r.Context(), "custom", true, "format", format)
+
+	rasterFormat, scale, ok := h.parseFormat(w, r, format)
+	if !ok {
+		return
+	}
+	if !h.signer.Verify(r.URL) {
+		http.Error(w, "Invalid, expired or missing URL signature", http.StatusForbidden)
+		return
+	}
+	repoData, err := customRepository(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	svg, err := h.svgBuilder.BuildBanner(r.Context(), repoData)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to generate banner: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	// Set headers
-	w.Header().Set("Content-Type", "image/svg+xml")
-	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.config.HTTPCacheDuration.Seconds())))
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	h.writeBanner(w, r, svg, bannerResponse{
+		format:        rasterFormat,
+		scale:         scale,
+		cacheDuration: h.config.HTTPCacheDuration,
+	})
+}
+
+// parseSignedCustomization returns the banner text set by the query of a
+// signed URL, or nil if there is none or the URL is not signed. It writes an
+// error and returns false if a signed customization is invalid.
+func (h *Handler) parseSignedCustomization(w http.ResponseWriter, r *http.Request) (*customization, bool) {
+	query := r.URL.Query()
+	if !hasCustomization(query) || h.signer == nil || !h.signer.Verify(r.URL) {
+		return nil, true
+	}
+
+	custom, err := parseCustomization(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return &custom, true
+}
+
+// parseFormat validates the format route variable and the raster scale. It
+// returns an empty format for SVG, or writes an error and returns false.
+func (h *Handler) parseFormat(w http.ResponseWriter, r *http.Request, format string) (converter.Format, float64, bool) {
+	if format == formatSVG {
+		return "", 1, true
+	}
+
+	rasterFormat, err := converter.ParseFormat(format)
+	if err != nil || h.raster == nil || !h.raster.Supports(rasterFormat) {
+		http.Error(w, fmt.Sprintf("%s banners are not enabled", strings.ToUpper(format)), http.StatusNotFound)
+		return "", 0, false
+	}
+	scale, err := h.raster.ParseScale(r.URL.Query().Get("scale"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", 0, false
+	}
+	return rasterFormat, scale, true
+}
+
+// bannerResponse describes how writeBanner serves a banner
+type bannerResponse struct {
+	// format is the raster format, or empty for SVG
+	format converter.Format
+	scale  float64
+
+	// private banners are cached privately and vary by credentials
+	private       bool
+	cacheDuration time.Duration
+
+	// lastModified is when the banner's data last changed (optional)
+	lastModified time.Time
+}
 
-	// Write SVG
-	if _, err := w.Write([]byte(svg)); err != nil {
+// dataModified returns when a repository's data last changed: when it was
+// fetched or, if later, when its latest history sample was recorded
+func dataModified(repo *forge.Repository) time.Time {
+	modified := repo.FetchedAt
+	if n := len(repo.History); n > 0 && repo.History[n-1].Time.After(modified) {
+		modified = repo.History[n-1].Time
+	}
+	return modified
+}
+
+// writeBanner writes an SVG banner, rasterized if a raster format is given,
+// or 304 Not Modified if the request's validators match
+func (h *Handler) writeBanner(w http.ResponseWriter, r *http.Request, svg string, resp bannerResponse) {
+	// Validators are known before rasterizing, so revalidation skips it
+	etag := strongETag([]byte(svg))
+	if resp.format != "" {
+		etag = strongETag([]byte(h.raster.key([]byte(svg), resp.format, resp.scale)))
+	}
+	// Banners also change with the template
+	lastModified := resp.lastModified
+	if reloadable, ok := h.svgBuilder.(banner.Reloadable); ok && reloadable.LoadedAt().After(lastModified) {
+		lastModified = reloadable.LoadedAt()
+	}
+
+	if notModified(r, etag, lastModified) {
+		setBannerHeaders(w, etag, lastModified, resp)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	body, contentType := []byte(svg), "image/svg+xml"
+	if resp.format != "" {
+		// Rasterizing outlives the API fetch timeout
+		rasterCtx, cancel := context.WithTimeout(r.Context(), slotTimeout+rasterTimeout)
+		defer cancel()
+
+		var err error
+		body, err = h.raster.Render(rasterCtx, body, resp.format, resp.scale)
+		if errors.Is(err, errRasterBusy) {
+			w.Header().Set("Retry-After", "5")
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		} else if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to rasterize banner", "error", err)
+			http.Error(w, "Failed to generate banner", http.StatusInternalServerError)
+			return
+		}
+		contentType = resp.format.ContentType()
+	}
+
+	setBannerHeaders(w, etag, lastModified, resp)
+	w.Header().Set("Content-Type", contentType)
+
+	// Write banner
+	if _, err := w.Write(body); err != nil {
 		// Log error but can't send error response as headers are already sent
-		log.Printf("Failed to write SVG response: %v", err)
+		slog.WarnContext(r.Context(), "Failed to write banner response", "error", err)
 	}
 }
 
+// setBannerHeaders sets the validator, caching and CORS headers of a banner
+// response, which 304 responses repeat
+func setBannerHeaders(w http.ResponseWriter, etag string, lastModified time.Time, resp bannerResponse) {
+	cacheScope := "public"
+	if resp.private {
+		cacheScope = "private"
+	}
+	w.Header().Set("ETag", etag)
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", cacheScope, int(resp.cacheDuration.Seconds())))
+	if resp.private {
+		w.Header().Set("Vary", "Authorization")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	}
+}
+
+// bannerPath returns the banner URL path of a repository. The forge is
+// omitted for GitHub.
+func bannerPath(private bool, forgeName, owner, repo, format string) string {
+	path := "/banner/"
+	if private {
+		path = "/private/banner/"
+	}
+	if forgeName != "" {
+		path += forgeName + "/"
+	}
+	return fmt.Sprintf("%s%s/%s.%s", path, owner, repo, format)
+}
+
+// rateLimitRetry returns how long to wait before asking a rate-limited
+// provider again about owner, whose GitHub host has its own quota
+func (h *Handler) rateLimitRetry(provider forge.Provider, owner string) time.Duration {
+	if provider.Name() != h.githubClient.Name() {
+		// Other forges do not report a reset time
+		return 5 * time.Minute
+	}
+	return max(time.Until(h.githubClient.RateLimitFor(owner).Reset), time.Minute)
+}
+
 // Index returns a simple landing page
 func (h *Handler) Index(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html")
 	if _, err := w.Write(indexHTML); err != nil {
-		log.Printf("Failed to write HTML response: %v", err)
+		slog.WarnContext(r.Context(), "Failed to write HTML response", "error", err)
 	}
 }
+
+// signed reports whether query carries a customization or owner signature
+func signed(query url.Values) bool {
+	return query.Has("sig") || query.Has(ownerSignatureParam)
+}
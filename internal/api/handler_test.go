@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/numtide/banner-generator/internal/config"
+	"github.com/numtide/banner-generator/internal/forge"
+	"github.com/numtide/banner-generator/internal/github"
+)
+
+// rateLimitedProvider has run out of quota and has nothing cached
+type rateLimitedProvider struct{}
+
+func (rateLimitedProvider) Name() string { return "gitlab" }
+
+func (rateLimitedProvider) GetRepositoryData(ctx context.Context, owner, repo string) (*forge.Repository, error) {
+	return nil, fmt.Errorf("%w until later", forge.ErrRateLimited)
+}
+
+func (rateLimitedProvider) RepositoryURL(owner, repo string) string {
+	return "https://gitlab.com/" + owner + "/" + repo
+}
+
+// nameBuilder renders the owner, name and star count
+type nameBuilder struct{}
+
+func (nameBuilder) BuildBanner(ctx context.Context, repo *github.Repository) (string, error) {
+	return fmt.Sprintf("<svg>%s/%s %d</svg>", repo.Owner, repo.Name, repo.StargazersCount), nil
+}
+
+// historyProvider serves a repository with a recorded history
+type historyProvider struct {
+	fakeProvider
+	history []forge.HistorySample
+}
+
+func (p historyProvider) GetRepositoryData(ctx context.Context, owner, repo string) (*forge.Repository, error) {
+	data, err := p.fakeProvider.GetRepositoryData(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	data.History = p.history
+	return data, nil
+}
+
+// reloadableBuilder is a builder whose template was loaded at loadedAt
+type reloadableBuilder struct {
+	stubBuilder
+	loadedAt time.Time
+}
+
+func (b reloadableBuilder) LoadedAt() time.Time { return b.loadedAt }
+
+func TestLastModified(t *testing.T) {
+	earlier, later := fetchedAt.Add(-time.Hour), fetchedAt.Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		history  []forge.HistorySample
+		loadedAt time.Time
+		want     time.Time
+	}{
+		{"fetch time", []forge.HistorySample{{Time: earlier}}, earlier, fetchedAt},
+		{"later history sample", []forge.HistorySample{{Time: earlier}, {Time: later}}, earlier, later},
+		{"later template load", nil, later, later},
+	}
+
+	for _, tt := range tests {
+		provider := historyProvider{history: tt.history}
+		h := NewHandler(reloadableBuilder{loadedAt: tt.loadedAt}, nil, forge.NewRegistry(provider), config.NewConfig(nil))
+		r := mux.NewRouter()
+		r.HandleFunc("/banner/{owner}/{repo}.svg", h.GenerateBanner)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/banner/numtide/public.svg", nil))
+		if got, want := rec.Header().Get("Last-Modified"), tt.want.Format(http.TimeFormat); got != want {
+			t.Errorf("%s: got Last-Modified %q, want %q", tt.name, got, want)
+		}
+	}
+}
+
+func TestRateLimitedBannerWithoutCache(t *testing.T) {
+	h := NewHandler(nameBuilder{}, nil, forge.NewRegistry(fakeProvider{}, rateLimitedProvider{}), config.NewConfig(nil))
+
+	r := mux.NewRouter()
+	r.HandleFunc("/banner/{forge}/{owner}/{repo}.svg", h.GenerateBanner)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/banner/gitlab/numtide/treefmt.svg", nil))
+
+	// The banner shows what the URL tells, without stats, and is not
+	// cached for long
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got, want := rec.Body.String(), "<svg>numtide/treefmt 0</svg>"; got != want {
+		t.Errorf("got body %q, want %q", got, want)
+	}
+	if got, want := rec.Header().Get("Cache-Control"), "public, max-age=300"; got != want {
+		t.Errorf("got Cache-Control %q, want %q", got, want)
+	}
+}
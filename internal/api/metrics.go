@@ -0,0 +1,255 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/numtide/banner-generator/internal/banner"
+	"github.com/numtide/banner-generator/internal/cache"
+	"github.com/numtide/banner-generator/internal/converter"
+	"github.com/numtide/banner-generator/internal/fonts"
+	"github.com/numtide/banner-generator/internal/github"
+	"github.com/numtide/banner-generator/internal/metrics"
+)
+
+// Metrics records HTTP requests and banner renders and serves them, along
+// with the other metrics of its registry, on /metrics
+type Metrics struct {
+	registry *metrics.Registry
+	token    string
+
+	requests       *metrics.CounterVec
+	latency        *metrics.HistogramVec
+	renders        *metrics.HistogramVec
+	githubRequests *metrics.CounterVec
+	githubLatency  *metrics.HistogramVec
+
+	mu     sync.Mutex
+	caches map[string]func() cacheStats
+}
+
+// cacheStats are the counters reported for every cache
+type cacheStats struct {
+	hits, misses, evictions, invalidations uint64
+}
+
+// NewMetrics registers the request and render metrics in registry. A
+// non-empty token is required as bearer token to scrape them.
+func NewMetrics(registry *metrics.Registry, token string) *Metrics {
+	m := &Metrics{
+		registry: registry,
+		token:    token,
+		requests: registry.Counter("banner_http_requests_total",
+			"HTTP requests by route and status.", "route", "status"),
+		latency: registry.Histogram("banner_http_request_duration_seconds",
+			"HTTP request latency by route and status.", metrics.DefaultBuckets, "route", "status"),
+		renders: registry.Histogram("banner_render_duration_seconds",
+			"Time spent rendering banners, by output format.", metrics.DefaultBuckets, "format"),
+		githubRequests: registry.Counter("banner_github_requests_total",
+			"GitHub API requests by host and status (0 if the request failed).", "host", "status"),
+		githubLatency: registry.Histogram("banner_github_request_duration_seconds",
+			"GitHub API request latency by host.", metrics.DefaultBuckets, "host"),
+		caches: make(map[string]func() cacheStats),
+	}
+
+	cacheCounter := func(name, help string, value func(cacheStats) uint64) {
+		registry.CounterFunc(name, help, []string{"cache"}, func() []metrics.Sample {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			samples := make([]metrics.Sample, 0, len(m.caches))
+			for cacheName, stats := range m.caches {
+				samples = append(samples, metrics.Sample{LabelValues: []string{cacheName}, Value: float64(value(stats()))})
+			}
+			return samples
+		})
+	}
+	cacheCounter("banner_cache_hits_total", "Cache lookups that found an entry.",
+		func(s cacheStats) uint64 { return s.hits })
+	cacheCounter("banner_cache_misses_total", "Cache lookups that found no entry.",
+		func(s cacheStats) uint64 { return s.misses })
+	cacheCounter("banner_cache_evictions_total", "Entries evicted to stay within the size limit or purged after expiring.",
+		func(s cacheStats) uint64 { return s.evictions })
+	cacheCounter("banner_cache_invalidations_total", "Entries dropped because the template or fonts changed.",
+		func(s cacheStats) uint64 { return s.invalidations })
+	return m
+}
+
+// ObserveGitHubRequest records a GitHub API request; it is meant for
+// github.Options.Observe
+func (m *Metrics) ObserveGitHubRequest(host string, status int, duration time.Duration) {
+	m.githubRequests.With(host, strconv.Itoa(status)).Inc()
+	m.githubLatency.With(host).Observe(duration.Seconds())
+}
+
+// ObserveRateLimit reports the remaining GitHub API quota of every host
+// of client
+func (m *Metrics) ObserveRateLimit(client *github.Client) {
+	m.registry.GaugeFunc("banner_github_rate_limit_remaining",
+		"Remaining GitHub API requests until the rate limit resets.", []string{"host"}, func() []metrics.Sample {
+			var samples []metrics.Sample
+			for _, state := range client.HostStatus() {
+				if state.RateLimit.Known {
+					samples = append(samples, metrics.Sample{LabelValues: []string{state.Host}, Value: float64(state.RateLimit.Remaining)})
+				}
+			}
+			return samples
+		})
+}
+
+// ObserveCache wraps c to count its hits and misses under name, along with
+// the evictions of backends that report them
+func (m *Metrics) ObserveCache(name string, c cache.Cache) cache.Cache {
+	counted := cache.NewCounted(c)
+	m.addCache(name, func() cacheStats {
+		stats := counted.Stats()
+		return cacheStats{hits: stats.Hits, misses: stats.Misses, evictions: stats.Evictions}
+	})
+	return counted
+}
+
+// ObserveRenderCache reports the counters of a render cache under name
+func (m *Metrics) ObserveRenderCache(name string, renderCache *banner.RenderCache) {
+	m.addCache(name, func() cacheStats {
+		stats := renderCache.Stats()
+		return cacheStats{hits: stats.Hits, misses: stats.Misses, evictions: stats.Evictions, invalidations: stats.Invalidations}
+	})
+}
+
+func (m *Metrics) addCache(name string, stats func() cacheStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.caches[name] = stats
+}
+
+// ObserveTemplate reports how often builder reloaded its template
+func (m *Metrics) ObserveTemplate(builder *banner.SimpleSVGBuilder) {
+	m.registry.CounterFunc("banner_template_reloads_total",
+		"Times the banner template was read again after changing on disk.", nil, func() []metrics.Sample {
+			return []metrics.Sample{{Value: float64(builder.Reloads())}}
+		})
+}
+
+// ObserveFonts reports how often fontManager reloaded font files
+func (m *Metrics) ObserveFonts(fontManager fonts.Manager) {
+	m.registry.CounterFunc("banner_font_reloads_total",
+		"Times a font file was read again after changing on disk.", nil, func() []metrics.Sample {
+			return []metrics.Sample{{Value: float64(fontManager.Reloads())}}
+		})
+}
+
+// Instrument counts the requests of router and their latency by route
+// template, so banners of different repositories share a series. Requests
+// no route matches, which router middleware never sees, are counted as
+// "unmatched" by wrapping the router's not found and method not allowed
+// handlers.
+func (m *Metrics) Instrument(router *mux.Router) {
+	router.Use(m.Middleware)
+
+	notFound := router.NotFoundHandler
+	if notFound == nil {
+		notFound = http.NotFoundHandler()
+	}
+	router.NotFoundHandler = m.record("unmatched", notFound)
+
+	methodNotAllowed := router.MethodNotAllowedHandler
+	if methodNotAllowed == nil {
+		methodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		})
+	}
+	router.MethodNotAllowedHandler = m.record("unmatched", methodNotAllowed)
+}
+
+// Middleware counts requests of matched routes by their template
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unmatched"
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+		m.record(route, next).ServeHTTP(w, r)
+	})
+}
+
+func (m *Metrics) record(route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(wrapped, r)
+
+		status := strconv.Itoa(wrapped.statusCode)
+		m.requests.With(route, status).Inc()
+		m.latency.With(route, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+// ServeHTTP serves the metrics, to requests with the bearer token if one
+// is set
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if m.token != "" {
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(bearer), []byte(m.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+	}
+	m.registry.ServeHTTP(w, r)
+}
+
+// Builder wraps builder to record how long SVG banners take to build
+func (m *Metrics) Builder(builder banner.Builder) banner.Builder {
+	return &timedBuilder{builder: builder, renders: m.renders}
+}
+
+// Rasterize wraps rasterize to record how long conversions take by format
+func (m *Metrics) Rasterize(rasterize RasterizeFunc) RasterizeFunc {
+	return func(ctx context.Context, svg []byte, opts converter.Options) ([]byte, error) {
+		start := time.Now()
+		data, err := rasterize(ctx, svg, opts)
+		if err == nil {
+			m.renders.With(opts.Format.Extension()).Observe(time.Since(start).Seconds())
+		}
+		return data, err
+	}
+}
+
+// timedBuilder records the duration of successful builds
+type timedBuilder struct {
+	builder banner.Builder
+	renders *metrics.HistogramVec
+}
+
+func (b *timedBuilder) BuildBanner(ctx context.Context, repo *github.Repository) (string, error) {
+	start := time.Now()
+	svg, err := b.builder.BuildBanner(ctx, repo)
+	if err == nil {
+		b.renders.With(formatSVG).Observe(time.Since(start).Seconds())
+	}
+	return svg, err
+}
+
+// Version passes on the wrapped builder's version, so a render cache in
+// front still notices template reloads
+func (b *timedBuilder) Version() (string, error) {
+	if versioned, ok := b.builder.(banner.Versioned); ok {
+		return versioned.Version()
+	}
+	return "", nil
+}
+
+// LoadedAt passes on when the wrapped builder loaded its template
+func (b *timedBuilder) LoadedAt() time.Time {
+	if reloadable, ok := b.builder.(banner.Reloadable); ok {
+		return reloadable.LoadedAt()
+	}
+	return time.Time{}
+}
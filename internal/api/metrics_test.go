@@ -0,0 +1,68 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/numtide/banner-generator/internal/config"
+	"github.com/numtide/banner-generator/internal/forge"
+	"github.com/numtide/banner-generator/internal/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics(metrics.NewRegistry(), "scrape-token")
+	h := NewHandler(m.Builder(stubBuilder{}), nil, forge.NewRegistry(fakeProvider{}), config.NewConfig(nil))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/banner/{owner}/{repo}.{format:svg}", h.GenerateBanner)
+	router.Handle("/metrics", m)
+	m.Instrument(router)
+
+	for _, path := range []string{"/banner/numtide/public.svg", "/banner/numtide/other.svg", "/banner/numtide/missing.svg", "/nothing/here"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"without token", "", http.StatusUnauthorized},
+		{"with wrong token", "guess", http.StatusUnauthorized},
+		{"with token", "scrape-token", http.StatusOK},
+	}
+	var body string
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/metrics", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if rec.Code == http.StatusOK {
+				body = rec.Body.String()
+			}
+		})
+	}
+
+	// Requests are counted by route template, not by repository
+	for _, want := range []string{
+		`banner_http_requests_total{route="/banner/{owner}/{repo}.{format:svg}",status="200"} 1`,
+		`banner_http_requests_total{route="/banner/{owner}/{repo}.{format:svg}",status="404"} 2`,
+		`banner_http_requests_total{route="/metrics",status="401"} 2`,
+		`banner_http_requests_total{route="unmatched",status="404"} 1`,
+		`banner_http_request_duration_seconds_count{route="/banner/{owner}/{repo}.{format:svg}",status="404"} 2`,
+		`banner_render_duration_seconds_count{format="svg"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics do not contain %s:\n%s", want, body)
+		}
+	}
+}
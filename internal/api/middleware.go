@@ -1,32 +1,74 @@
 package api
 
 import (
-	"log"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
 	"net/http"
 	"time"
+
+	"github.com/numtide/banner-generator/internal/logging"
 )
 
-// LoggingMiddleware logs HTTP requests
+// RequestIDHeader carries the ID of a request in responses, and in requests
+// from proxies that assign one
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds request IDs taken from proxies
+const maxRequestIDLength = 64
+
+// LoggingMiddleware gives every request an ID, returned in X-Request-ID and
+// attached to every log record written while serving it, and logs the
+// request with the fields gathered on the way once it completes
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		ctx := logging.NewContext(r.Context(), id)
+
 		// Create a custom response writer to capture status code
 		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 
-		next.ServeHTTP(wrapped, r)
+		next.ServeHTTP(wrapped, r.WithContext(ctx))
 
-		log.Printf(
-			"%s %s %d %v %s",
-			r.Method,
-			r.RequestURI,
-			wrapped.statusCode,
-			time.Since(start),
-			r.RemoteAddr,
+		slog.LogAttrs(ctx, slog.LevelInfo, "Request",
+			slog.String("method", r.Method),
+			slog.String("uri", r.RequestURI),
+			slog.Int("status", wrapped.statusCode),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("remote", r.RemoteAddr),
 		)
 	})
 }
 
+// validRequestID reports whether a request ID set by a proxy is safe to log
+// and return
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random request ID
+func newRequestID() string {
+	var id [8]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/numtide/banner-generator/internal/cache"
+	"github.com/numtide/banner-generator/internal/config"
+	"github.com/numtide/banner-generator/internal/forge"
+	"github.com/numtide/banner-generator/internal/logging"
+)
+
+func TestLoggingMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, "info", logging.FormatText)
+	if err != nil {
+		t.Fatalf("logging.New failed: %v", err)
+	}
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	defer slog.SetDefault(previous)
+
+	h := NewHandler(stubBuilder{}, nil, forge.NewRegistry(forge.Cached(fakeProvider{}, cache.NewMemory(), time.Hour, time.Hour)), config.NewConfig(nil))
+	r := mux.NewRouter()
+	r.HandleFunc("/banner/{owner}/{repo}.svg", h.GenerateBanner)
+	r.Use(LoggingMiddleware)
+
+	// The repository is fetched once and then served from the cache
+	tests := []struct {
+		name     string
+		header   string
+		assigned bool
+		cache    string
+	}{
+		{"generated", "", true, "miss"},
+		{"from proxy", "proxy-id.42", false, "hit"},
+		{"unsafe from proxy", "bad id\n", true, "hit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest("GET", "/banner/numtide/public.svg", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(RequestIDHeader)
+			if tt.assigned && (id == "" || id == tt.header) {
+				t.Errorf("%s = %q, want a new ID", RequestIDHeader, id)
+			}
+			if !tt.assigned && id != tt.header {
+				t.Errorf("%s = %q, want %q", RequestIDHeader, id, tt.header)
+			}
+
+			line := buf.String()
+			for _, field := range []string{"msg=Request", "request_id=" + id, "owner=numtide", "repo=public", "cache=" + tt.cache, "status=200", "duration="} {
+				if !strings.Contains(line, field) {
+					t.Errorf("request log lacks %s: %s", field, line)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/numtide/banner-generator/internal/config"
+)
+
+// PrivateAccess authenticates requests for private repository banners
+type PrivateAccess struct {
+	owners []privateOwner
+	now    func() time.Time
+}
+
+// privateOwner holds the credentials of one org/user entry
+type privateOwner struct {
+	// entry is the owner as configured ("org" or "forge:org")
+	entry      string
+	token      string
+	signingKey []byte
+}
+
+// NewPrivateAccess creates the authenticator from per-owner credentials
+func NewPrivateAccess(owners map[string]config.PrivateOwnerConfig) (*PrivateAccess, error) {
+	p := &PrivateAccess{now: time.Now}
+	for entry, ownerConfig := range owners {
+		token, err := ownerConfig.ReadToken()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token for private owner '%s': %w", entry, err)
+		}
+		signingKey, err := ownerConfig.ReadSigningKey()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signing key for private owner '%s': %w", entry, err)
+		}
+		if token == "" && signingKey == "" {
+			return nil, fmt.Errorf("private owner '%s' has neither a token nor a signing key", entry)
+		}
+
+		p.owners = append(p.owners, privateOwner{
+			entry:      entry,
+			token:      token,
+			signingKey: []byte(signingKey),
+		})
+	}
+
+	// Keep lookups deterministic
+	sort.Slice(p.owners, func(i, j int) bool { return p.owners[i].entry < p.owners[j].entry })
+	return p, nil
+}
+
+// Owners returns the configured owner entries, for config.Config.PrivateOwners
+func (p *PrivateAccess) Owners() []string {
+	entries := make([]string, 0, len(p.owners))
+	for _, owner := range p.owners {
+		entries = append(entries, owner.entry)
+	}
+	return entries
+}
+
+// Authenticate reports whether r carries the bearer token of an entry
+// covering owner on forge, or an unexpired URL signature made with its
+// signing key
+func (p *PrivateAccess) Authenticate(r *http.Request, forge, owner string) bool {
+	bearer, hasBearer := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	query := r.URL.Query()
+	sig, exp := query.Get(ownerSignatureParam), query.Get(ownerExpiryParam)
+	if exp != "" {
+		expires, err := strconv.ParseInt(exp, 10, 64)
+		if err != nil || !p.now().Before(time.Unix(expires, 0)) {
+			sig = ""
+		}
+	}
+
+	for _, o := range p.owners {
+		if !config.MatchesOwner(o.entry, forge, owner) {
+			continue
+		}
+		if hasBearer && o.token != "" && subtle.ConstantTimeCompare([]byte(bearer), []byte(o.token)) == 1 {
+			return true
+		}
+		if sig != "" && len(o.signingKey) > 0 && hmac.Equal([]byte(sig), []byte(ownerSignature(o.signingKey, r.URL.Path, exp))) {
+			return true
+		}
+	}
+	return false
+}
+
+// ownerSignatureParam carries the signature of a private banner's path. It
+// is separate from the ?sig= of customized banners, which is made with
+// another key and covers the query as well, so a URL can carry both.
+const ownerSignatureParam = "owner_sig"
+
+// ownerExpiryParam carries the optional expiry time of an owner signature
+// (Unix seconds)
+const ownerExpiryParam = "owner_exp"
+
+// SignPrivateURL returns rawURL with an owner signature made with key,
+// expiring at expires unless it is zero. An existing owner signature is
+// replaced; other query parameters are left as they are.
+func SignPrivateURL(key []byte, rawURL string, expires time.Time) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse URL: %w", err)
+	}
+
+	query := u.Query()
+	query.Del(ownerSignatureParam)
+	query.Del(ownerExpiryParam)
+	var exp string
+	if !expires.IsZero() {
+		exp = strconv.FormatInt(expires.Unix(), 10)
+		query.Set(ownerExpiryParam, exp)
+	}
+	query.Set(ownerSignatureParam, ownerSignature(key, u.Path, exp))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// ownerSignature signs a banner URL path and, if set, the expiry time
+func ownerSignature(key []byte, path, exp string) string {
+	if exp != "" {
+		path += "?" + ownerExpiryParam + "=" + exp
+	}
+	return signHMAC(key, path)
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/numtide/banner-generator/internal/config"
+	"github.com/numtide/banner-generator/internal/forge"
+	"github.com/numtide/banner-generator/internal/github"
+)
+
+// fetchedAt is when fakeProvider's repositories were fetched
+var fetchedAt = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// fakeProvider serves one public and one private repository, reporting
+// their names in lower case like a forge with canonical names
+type fakeProvider struct{}
+
+func (fakeProvider) Name() string { return forge.DefaultForge }
+
+func (fakeProvider) GetRepositoryData(ctx context.Context, owner, repo string) (*forge.Repository, error) {
+	owner, repo = strings.ToLower(owner), strings.ToLower(repo)
+	switch repo {
+	case "public":
+		return &forge.Repository{Name: repo, Owner: owner, Description: "open", Visibility: forge.VisibilityPublic, FetchedAt: fetchedAt}, nil
+	case "secret":
+		return &forge.Repository{Name: repo, Owner: owner, Description: "internal plans", Visibility: forge.VisibilityPrivate, FetchedAt: fetchedAt}, nil
+	}
+	return nil, forge.ErrNotFound
+}
+
+func (fakeProvider) RepositoryURL(owner, repo string) string {
+	return "https://github.com/" + owner + "/" + repo
+}
+
+// stubBuilder renders the description only
+type stubBuilder struct{}
+
+func (stubBuilder) BuildBanner(ctx context.Context, repo *github.Repository) (string, error) {
+	return "<svg>" + repo.Description + "</svg>", nil
+}
+
+// newPrivateTestRouter serves the public and private banner routes with
+// credentials for the numtide org
+func newPrivateTestRouter(t *testing.T) *mux.Router {
+	t.Helper()
+
+	access, err := NewPrivateAccess(map[string]config.PrivateOwnerConfig{
+		"numtide": {Token: "team-token", SigningKey: "signing-secret"},
+	})
+	if err != nil {
+		t.Fatalf("NewPrivateAccess failed: %v", err)
+	}
+
+	h := NewHandler(stubBuilder{}, nil, forge.NewRegistry(fakeProvider{}), config.NewConfig(nil))
+	h.EnablePrivateAccess(access)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/banner/{owner}/{repo}.svg", h.GenerateBanner)
+	r.HandleFunc("/private/banner/{owner}/{repo}.svg", h.GeneratePrivateBanner)
+	return r
+}
+
+func TestPrivateBanners(t *testing.T) {
+	r := newPrivateTestRouter(t)
+	signature := ownerSignature([]byte("signing-secret"), "/private/banner/numtide/secret.svg", "")
+	signWithExpiry := func(expires time.Time) string {
+		signed, err := SignPrivateURL([]byte("signing-secret"), "/private/banner/numtide/secret.svg", expires)
+		if err != nil {
+			t.Fatalf("SignPrivateURL failed: %v", err)
+		}
+		return signed
+	}
+	unexpired := signWithExpiry(time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name         string
+		path         string
+		token        string
+		status       int
+		cacheControl string
+	}{
+		{"public repo on public route", "/banner/numtide/public.svg", "", http.StatusOK, "public, max-age=3600"},
+		{"private repo on public route", "/banner/numtide/secret.svg", "team-token", http.StatusNotFound, ""},
+		{"private route without credentials", "/private/banner/numtide/secret.svg", "", http.StatusUnauthorized, ""},
+		{"private route with wrong token", "/private/banner/numtide/secret.svg", "guess", http.StatusUnauthorized, ""},
+		{"private route with token", "/private/banner/numtide/secret.svg", "team-token", http.StatusOK, "private, max-age=3600"},
+		{"private route with signature", "/private/banner/numtide/secret.svg?owner_sig=" + signature, "", http.StatusOK, "private, max-age=3600"},
+		{"signature for another repo", "/private/banner/numtide/public.svg?owner_sig=" + signature, "", http.StatusUnauthorized, ""},
+		{"unexpired signature", unexpired, "", http.StatusOK, "private, max-age=3600"},
+		{"expired signature", signWithExpiry(time.Now().Add(-time.Minute)), "", http.StatusUnauthorized, ""},
+		{"extended expiry", strings.Replace(unexpired, "owner_exp=", "owner_exp=9", 1), "", http.StatusUnauthorized, ""},
+		{"expiry removed", "/private/banner/numtide/secret.svg?" + unexpired[strings.Index(unexpired, "owner_sig="):], "", http.StatusUnauthorized, ""},
+		{"token for another owner", "/private/banner/someone/secret.svg", "team-token", http.StatusUnauthorized, ""},
+		{"other spelling with token", "/private/banner/numtide/Secret.svg", "team-token", http.StatusMovedPermanently, "private, max-age=3600"},
+		{"other spelling with signature", "/private/banner/numtide/Secret.svg?owner_sig=" + ownerSignature([]byte("signing-secret"), "/private/banner/numtide/Secret.svg", ""), "", http.StatusOK, "private, max-age=3600"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: got status %d, want %d", tt.name, rec.Code, tt.status)
+		}
+		if tt.cacheControl != "" && rec.Header().Get("Cache-Control") != tt.cacheControl {
+			t.Errorf("%s: got Cache-Control %q, want %q", tt.name, rec.Header().Get("Cache-Control"), tt.cacheControl)
+		}
+		if rec.Code != http.StatusOK && strings.Contains(rec.Body.String(), "internal plans") {
+			t.Errorf("%s: leaked private description", tt.name)
+		}
+	}
+}
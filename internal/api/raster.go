@@ -0,0 +1,177 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/numtide/banner-generator/internal/cache"
+	"github.com/numtide/banner-generator/internal/converter"
+	"github.com/numtide/banner-generator/internal/logging"
+	"github.com/numtide/banner-generator/internal/singleflight"
+)
+
+// rasterTimeout bounds a single conversion
+const rasterTimeout = 30 * time.Second
+
+// slotTimeout bounds the wait for a conversion slot
+const slotTimeout = 10 * time.Second
+
+// scales are the accepted ?scale= values, so every banner has a handful of
+// renderings at most
+var scales = []float64{1, 1.5, 2, 3}
+
+// errRasterBusy is returned when no conversion slot frees up in time
+var errRasterBusy = errors.New("too many banners are being rasterized")
+
+// RasterizeFunc converts an SVG banner to a raster image
+type RasterizeFunc func(ctx context.Context, svg []byte, opts converter.Options) ([]byte, error)
+
+// RasterOptions configures NewRaster
+type RasterOptions struct {
+	// Concurrency is the maximum number of conversions at once (default 1)
+	Concurrency int
+
+	// MaxScale is the largest accepted scale, out of 1, 1.5, 2 and 3
+	// (default 1)
+	MaxScale float64
+
+	// CacheDuration is how long results are cached (zero never expires)
+	CacheDuration time.Duration
+
+	// Formats are the image formats served (default PNG only)
+	Formats []converter.Format
+
+	// Quality is the quality of lossy formats (default
+	// converter.DefaultQuality)
+	Quality int
+}
+
+// Raster turns SVG banners into raster images. Results are cached by SVG
+// content, so a banner is only rasterized again when its data or template
+// changes, and a bounded number of conversions run at once.
+type Raster struct {
+	rasterize     RasterizeFunc
+	cache         cache.Cache
+	cacheDuration time.Duration
+	maxScale      float64
+	formats       map[converter.Format]bool
+	quality       int
+	slots         chan struct{}
+	slotTimeout   time.Duration
+	renders       singleflight.Group[[]byte]
+}
+
+// NewRaster creates a Raster converting with rasterize and caching in c
+func NewRaster(rasterize RasterizeFunc, c cache.Cache, opts RasterOptions) *Raster {
+	formats := map[converter.Format]bool{converter.FormatPNG: true}
+	if len(opts.Formats) > 0 {
+		formats = make(map[converter.Format]bool, len(opts.Formats))
+		for _, format := range opts.Formats {
+			formats[format] = true
+		}
+	}
+	quality := opts.Quality
+	if quality <= 0 {
+		quality = converter.DefaultQuality
+	}
+
+	return &Raster{
+		rasterize:     rasterize,
+		cache:         c,
+		cacheDuration: opts.CacheDuration,
+		maxScale:      max(opts.MaxScale, 1),
+		formats:       formats,
+		quality:       quality,
+		slots:         make(chan struct{}, max(opts.Concurrency, 1)),
+		slotTimeout:   slotTimeout,
+	}
+}
+
+// Supports reports whether banners are served in format
+func (r *Raster) Supports(format converter.Format) bool {
+	return r.formats[format]
+}
+
+// key identifies the rendering of svg in format at scale, with the
+// settings that affect the output
+func (r *Raster) key(svg []byte, format converter.Format, scale float64) string {
+	if format == converter.FormatPDF {
+		// PDFs are vectors, so every scale is the same document
+		scale = 1
+	}
+	sum := sha256.Sum256(svg)
+	key := fmt.Sprintf("%s:%s:%s", format, hex.EncodeToString(sum[:]), strconv.FormatFloat(scale, 'f', -1, 64))
+	if format != converter.FormatPNG && format != converter.FormatPDF {
+		key += ":" + strconv.Itoa(r.quality)
+	}
+	return key
+}
+
+// ParseScale validates a ?scale= value; empty means 1
+func (r *Raster) ParseScale(value string) (float64, error) {
+	if value == "" {
+		return 1, nil
+	}
+	scale, err := strconv.ParseFloat(value, 64)
+	if err == nil && scale <= r.maxScale && slices.Contains(scales, scale) {
+		return scale, nil
+	}
+
+	var accepted []string
+	for _, s := range scales {
+		if s <= r.maxScale {
+			accepted = append(accepted, strconv.FormatFloat(s, 'f', -1, 64))
+		}
+	}
+	return 0, fmt.Errorf("scale must be one of %s", strings.Join(accepted, ", "))
+}
+
+// Render returns the rendering of svg in format at the given scale. PDFs
+// ignore the scale.
+func (r *Raster) Render(ctx context.Context, svg []byte, format converter.Format, scale float64) ([]byte, error) {
+	key := r.key(svg, format, scale)
+
+	if data, ok, err := r.cache.Get(ctx, key); err != nil {
+		slog.WarnContext(ctx, "Failed to read raster cache", "error", err)
+	} else if ok {
+		logging.Add(ctx, "raster_cache", "hit")
+		return data, nil
+	}
+	logging.Add(ctx, "raster_cache", "miss")
+
+	// Identical banners requested at once are rasterized once. Only waiting
+	// for a slot counts as busy; a conversion that times out is an error.
+	data, _, err := r.renders.Do(ctx, key, func(ctx context.Context) ([]byte, error) {
+		wait := time.NewTimer(r.slotTimeout)
+		defer wait.Stop()
+		select {
+		case r.slots <- struct{}{}:
+			defer func() { <-r.slots }()
+		case <-wait.C:
+			return nil, errRasterBusy
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, rasterTimeout)
+		defer cancel()
+
+		data, err := r.rasterize(ctx, svg, converter.Options{Scale: scale, Format: format, Quality: r.quality})
+		if err != nil {
+			return nil, fmt.Errorf("failed to rasterize banner: %w", err)
+		}
+		if err := r.cache.Set(ctx, key, data, r.cacheDuration); err != nil {
+			slog.WarnContext(ctx, "Failed to cache raster banner", "format", format.Extension(), "error", err)
+		}
+		return data, nil
+	})
+	return data, err
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/numtide/banner-generator/internal/cache"
+	"github.com/numtide/banner-generator/internal/config"
+	"github.com/numtide/banner-generator/internal/converter"
+	"github.com/numtide/banner-generator/internal/forge"
+)
+
+// countingRasterizer returns the SVG with the scale appended and counts calls
+type countingRasterizer struct {
+	calls atomic.Int32
+}
+
+func (c *countingRasterizer) rasterize(ctx context.Context, svg []byte, opts converter.Options) ([]byte, error) {
+	c.calls.Add(1)
+	return append(svg, strconv.FormatFloat(opts.Scale, 'f', -1, 64)...), nil
+}
+
+func newRasterTestRouter(raster *Raster) *mux.Router {
+	h := NewHandler(stubBuilder{}, nil, forge.NewRegistry(fakeProvider{}), config.NewConfig(nil))
+	if raster != nil {
+		h.EnableRaster(raster)
+	}
+
+	r := mux.NewRouter()
+	r.HandleFunc("/banner/{owner}/{repo}.{format:svg|png|webp|jpg|jpeg|avif|pdf}", h.GenerateBanner)
+	return r
+}
+
+func TestPNGBanners(t *testing.T) {
+	rasterizer := &countingRasterizer{}
+	r := newRasterTestRouter(NewRaster(rasterizer.rasterize, cache.NewMemory(), RasterOptions{MaxScale: 2}))
+
+	tests := []struct {
+		name        string
+		path        string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"svg", "/banner/numtide/public.svg", http.StatusOK, "image/svg+xml", "<svg>open</svg>"},
+		{"png", "/banner/numtide/public.png", http.StatusOK, "image/png", "<svg>open</svg>1"},
+		{"png at 2x", "/banner/numtide/public.png?scale=2", http.StatusOK, "image/png", "<svg>open</svg>2"},
+		{"png at 1.5x", "/banner/numtide/public.png?scale=1.5", http.StatusOK, "image/png", "<svg>open</svg>1.5"},
+		{"scale too large", "/banner/numtide/public.png?scale=3", http.StatusBadRequest, "", ""},
+		{"unlisted scale", "/banner/numtide/public.png?scale=1.0001", http.StatusBadRequest, "", ""},
+		{"invalid scale", "/banner/numtide/public.png?scale=big", http.StatusBadRequest, "", ""},
+		{"private repo", "/banner/numtide/secret.png", http.StatusNotFound, "", ""},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: got status %d, want %d", tt.name, rec.Code, tt.status)
+		}
+		if tt.contentType != "" && rec.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("%s: got Content-Type %q, want %q", tt.name, rec.Header().Get("Content-Type"), tt.contentType)
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Errorf("%s: got body %q, want %q", tt.name, rec.Body.String(), tt.body)
+		}
+	}
+
+	// Unchanged banners are served from the cache
+	before := rasterizer.calls.Load()
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/banner/numtide/public.png?scale=2", nil))
+	if rec.Code != http.StatusOK || rasterizer.calls.Load() != before {
+		t.Errorf("cached PNG was rasterized again (status %d)", rec.Code)
+	}
+}
+
+func TestRasterFormats(t *testing.T) {
+	rasterize := func(ctx context.Context, svg []byte, opts converter.Options) ([]byte, error) {
+		return append(svg, fmt.Sprintf("%s@%d", opts.Format, opts.Quality)...), nil
+	}
+	r := newRasterTestRouter(NewRaster(rasterize, cache.NewMemory(), RasterOptions{
+		Formats: []converter.Format{converter.FormatPNG, converter.FormatWebP, converter.FormatJPEG, converter.FormatPDF},
+		Quality: 70,
+	}))
+
+	tests := []struct {
+		path        string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"/banner/numtide/public.png", http.StatusOK, "image/png", "<svg>open</svg>png@70"},
+		{"/banner/numtide/public.webp", http.StatusOK, "image/webp", "<svg>open</svg>webp@70"},
+		{"/banner/numtide/public.jpg", http.StatusOK, "image/jpeg", "<svg>open</svg>jpeg@70"},
+		{"/banner/numtide/public.jpeg", http.StatusOK, "image/jpeg", "<svg>open</svg>jpeg@70"},
+		{"/banner/numtide/public.pdf", http.StatusOK, "application/pdf", "<svg>open</svg>pdf@70"},
+		{"/banner/numtide/public.avif", http.StatusNotFound, "", ""},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: got status %d, want %d", tt.path, rec.Code, tt.status)
+		}
+		if tt.contentType != "" && rec.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("%s: got Content-Type %q, want %q", tt.path, rec.Header().Get("Content-Type"), tt.contentType)
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Errorf("%s: got body %q, want %q", tt.path, rec.Body.String(), tt.body)
+		}
+	}
+}
+
+func TestPNGBannersDisabled(t *testing.T) {
+	r := newRasterTestRouter(nil)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/banner/numtide/public.png", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("got status %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestRasterConcurrencyLimit(t *testing.T) {
+	var running, peak atomic.Int32
+	rasterize := func(ctx context.Context, svg []byte, opts converter.Options) ([]byte, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return svg, nil
+	}
+	raster := NewRaster(rasterize, cache.NewMemory(), RasterOptions{Concurrency: 2})
+
+	var wg sync.WaitGroup
+	for i := range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := raster.Render(context.Background(), []byte(strconv.Itoa(i)), converter.FormatPNG, 1); err != nil {
+				t.Errorf("Render failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak.Load() > 2 {
+		t.Errorf("%d conversions ran at once, want at most 2", peak.Load())
+	}
+}
+
+func TestRasterBusy(t *testing.T) {
+	release := make(chan struct{})
+	rasterize := func(ctx context.Context, svg []byte, opts converter.Options) ([]byte, error) {
+		<-release
+		return svg, nil
+	}
+	raster := NewRaster(rasterize, cache.NewMemory(), RasterOptions{Concurrency: 1})
+	raster.slotTimeout = 20 * time.Millisecond
+	defer close(release)
+
+	// Occupy the only slot
+	go func() { _, _ = raster.Render(context.Background(), []byte("first"), converter.FormatPNG, 1) }()
+	time.Sleep(10 * time.Millisecond)
+
+	if _, err := raster.Render(context.Background(), []byte("second"), converter.FormatPNG, 1); err != errRasterBusy {
+		t.Errorf("Render() error = %v, want %v", err, errRasterBusy)
+	}
+}
+
+func TestRasterConversionTimeout(t *testing.T) {
+	rasterize := func(ctx context.Context, svg []byte, opts converter.Options) ([]byte, error) {
+		return nil, fmt.Errorf("failed to load page: %w", context.DeadlineExceeded)
+	}
+	r := newRasterTestRouter(NewRaster(rasterize, cache.NewMemory(), RasterOptions{}))
+
+	// A conversion that times out is a failure, not a full queue
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/banner/numtide/public.png", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("got status %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if rec.Header().Get("Retry-After") != "" {
+		t.Errorf("got Retry-After %q, want none", rec.Header().Get("Retry-After"))
+	}
+}
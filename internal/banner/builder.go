@@ -1,8 +1,22 @@
 package banner
 
-import "github.com/numtide/banner-generator/internal/github"
+import (
+	"context"
+	"time"
 
-// Builder is the interface for SVG banner builders
+	"github.com/numtide/banner-generator/internal/github"
+)
+
+// Builder is the interface for SVG banner builders. ctx carries the
+// request's log fields.
 type Builder interface {
-	BuildBanner(repo *github.Repository) (string, error)
+	BuildBanner(ctx context.Context, repo *github.Repository) (string, error)
+}
+
+// Reloadable is implemented by builders that load what they render with,
+// such as a template, from disk and reload it when it changes
+type Reloadable interface {
+	// LoadedAt returns when the template in use was loaded, or the zero
+	// time before it is first used
+	LoadedAt() time.Time
 }
@@ -0,0 +1,78 @@
+package banner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/numtide/banner-generator/internal/github"
+	"github.com/numtide/banner-generator/internal/svg"
+	"github.com/numtide/banner-generator/internal/utils"
+)
+
+// Contributors slot defaults, overridden by attributes on the group:
+//
+//	<g id="contributors" data-count="5" data-size="48" data-spacing="8" data-show-total="true"/>
+const (
+	defaultContributorCount   = 5
+	defaultContributorSize    = 48
+	defaultContributorSpacing = 8
+)
+
+// renderContributors fills the contributors group with circular avatars of
+// the top contributors, optionally followed by the total contributor count.
+// The group is hidden when no contributors are known.
+func renderContributors(doc *svg.SimpleDocument, repo *github.Repository) error {
+	attrs, err := doc.ElementAttributes("contributors")
+	if err != nil {
+		return err
+	}
+	if len(repo.Contributors) == 0 {
+		return doc.HideElementByID("contributors")
+	}
+
+	count := intAttribute(attrs, "data-count", defaultContributorCount)
+	size := intAttribute(attrs, "data-size", defaultContributorSize)
+	spacing := intAttribute(attrs, "data-spacing", defaultContributorSpacing)
+	radius := size / 2
+
+	shown := repo.Contributors[:min(count, len(repo.Contributors))]
+
+	var content strings.Builder
+	for i, contributor := range shown {
+		x := i * (size + spacing)
+		login := svg.EscapeXML(contributor.Login)
+
+		if contributor.Avatar == "" {
+			// Placeholder keeps the strip evenly spaced
+			fmt.Fprintf(&content, `<circle cx="%d" cy="%d" r="%d" fill="currentColor" opacity="0.3"><title>%s</title></circle>`,
+				x+radius, radius, radius, login)
+			continue
+		}
+
+		clipID := fmt.Sprintf("contributor-avatar-%d", i)
+		fmt.Fprintf(&content, `<clipPath id="%s"><circle cx="%d" cy="%d" r="%d"/></clipPath>`, clipID, x+radius, radius, radius)
+		fmt.Fprintf(&content, `<image href="%s" x="%d" y="0" width="%d" height="%d" clip-path="url(#%s)"><title>%s</title></image>`,
+			contributor.Avatar, x, size, size, clipID, login)
+	}
+
+	if attrs["data-show-total"] == "true" && repo.ContributorCount > 0 {
+		label := "contributors"
+		if repo.ContributorCount == 1 {
+			label = "contributor"
+		}
+		fmt.Fprintf(&content, `<text x="%d" y="%d" dominant-baseline="central">%s %s</text>`,
+			len(shown)*(size+spacing), radius, utils.FormatCount(repo.ContributorCount), label)
+	}
+
+	return doc.SetElementContent("contributors", content.String())
+}
+
+// intAttribute parses a positive integer attribute, falling back to def
+func intAttribute(attrs map[string]string, name string, def int) int {
+	value, err := strconv.Atoi(attrs[name])
+	if err != nil || value <= 0 {
+		return def
+	}
+	return value
+}
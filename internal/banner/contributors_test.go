@@ -0,0 +1,50 @@
+package banner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/numtide/banner-generator/internal/forge"
+	"github.com/numtide/banner-generator/internal/github"
+	"github.com/numtide/banner-generator/internal/svg"
+)
+
+func TestRenderContributors(t *testing.T) {
+	doc := svg.NewSimpleDocument(`<svg><g id="contributors" data-count="2" data-size="40" data-spacing="10" data-show-total="true"/></svg>`)
+	repo := &github.Repository{
+		Contributors: []forge.Contributor{
+			{Login: "zimbatm", Avatar: "data:image/png;base64,AAAA"},
+			{Login: "brianmcgee"},
+			{Login: "Mic92", Avatar: "data:image/png;base64,BBBB"},
+		},
+		ContributorCount: 1234,
+	}
+
+	if err := renderContributors(doc, repo); err != nil {
+		t.Fatalf("renderContributors failed: %v", err)
+	}
+	result := doc.String()
+
+	for _, expected := range []string{
+		`<image href="data:image/png;base64,AAAA" x="0" y="0" width="40" height="40" clip-path="url(#contributor-avatar-0)">`,
+		`<circle cx="70" cy="20" r="20" fill="currentColor" opacity="0.3"><title>brianmcgee</title></circle>`,
+		`<text x="100" y="20" dominant-baseline="central">1.2k contributors</text>`,
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("result does not contain %s:\n%s", expected, result)
+		}
+	}
+	if strings.Contains(result, "Mic92") {
+		t.Error("rendered more contributors than data-count")
+	}
+}
+
+func TestRenderContributorsHidesEmptySlot(t *testing.T) {
+	doc := svg.NewSimpleDocument(`<svg><g id="contributors"/></svg>`)
+	if err := renderContributors(doc, &github.Repository{}); err != nil {
+		t.Fatalf("renderContributors failed: %v", err)
+	}
+	if doc.String() != `<svg><g id="contributors" visibility="hidden"/></svg>` {
+		t.Errorf("empty slot not hidden: %s", doc.String())
+	}
+}
@@ -0,0 +1,65 @@
+package banner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/numtide/banner-generator/internal/github"
+	"github.com/numtide/banner-generator/internal/linguist"
+	"github.com/numtide/banner-generator/internal/svg"
+)
+
+// Language bar slot defaults, overridden by attributes on the group:
+//
+//	<g id="language-bar" data-width="600" data-height="8" data-legend="3" data-legend-width="180"/>
+//
+// data-legend is the number of languages listed below the bar (0 for none).
+const (
+	defaultLanguageBarWidth  = 600
+	defaultLanguageBarHeight = 8
+	defaultLegendItemWidth   = 180
+)
+
+// renderLanguageBar fills the language-bar group with a bar of the language
+// breakdown in Linguist colors, optionally followed by a legend of the top
+// languages. The group is hidden when no breakdown is known.
+func renderLanguageBar(doc *svg.SimpleDocument, repo *github.Repository) error {
+	attrs, err := doc.ElementAttributes("language-bar")
+	if err != nil {
+		return err
+	}
+	if len(repo.Languages) == 0 {
+		return doc.HideElementByID("language-bar")
+	}
+
+	width := intAttribute(attrs, "data-width", defaultLanguageBarWidth)
+	height := intAttribute(attrs, "data-height", defaultLanguageBarHeight)
+	legendItems := intAttribute(attrs, "data-legend", 0)
+	legendWidth := intAttribute(attrs, "data-legend-width", defaultLegendItemWidth)
+
+	var content strings.Builder
+
+	// Segments are clipped to a bar with rounded ends
+	fmt.Fprintf(&content, `<clipPath id="language-bar-clip"><rect width="%d" height="%d" rx="%g"/></clipPath>`, width, height, float64(height)/2)
+	content.WriteString(`<g clip-path="url(#language-bar-clip)">`)
+	var x float64
+	for _, language := range repo.Languages {
+		segment := float64(width) * language.Percent / 100
+		fmt.Fprintf(&content, `<rect x="%.2f" width="%.2f" height="%d" fill="%s"><title>%s %.1f%%</title></rect>`,
+			x, segment, height, linguist.Color(language.Name), svg.EscapeXML(language.Name), language.Percent)
+		x += segment
+	}
+	content.WriteString(`</g>`)
+
+	// Legend entries sit in a row below the bar
+	radius := height
+	y := height + 2*radius + radius
+	for i, language := range repo.Languages[:min(legendItems, len(repo.Languages))] {
+		lx := i * legendWidth
+		fmt.Fprintf(&content, `<circle cx="%d" cy="%d" r="%d" fill="%s"/>`, lx+radius, y, radius, linguist.Color(language.Name))
+		fmt.Fprintf(&content, `<text x="%d" y="%d" dominant-baseline="central">%s %.1f%%</text>`,
+			lx+3*radius, y, svg.EscapeXML(language.Name), language.Percent)
+	}
+
+	return doc.SetElementContent("language-bar", content.String())
+}
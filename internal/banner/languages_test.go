@@ -0,0 +1,35 @@
+package banner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/numtide/banner-generator/internal/forge"
+	"github.com/numtide/banner-generator/internal/github"
+	"github.com/numtide/banner-generator/internal/svg"
+)
+
+func TestRenderLanguageBar(t *testing.T) {
+	doc := svg.NewSimpleDocument(`<svg><g id="language-bar" data-width="200" data-height="10" data-legend="1"/></svg>`)
+	repo := &github.Repository{
+		Languages: forge.LanguageShares(map[string]float64{"Go": 750, "Nix": 250}),
+	}
+
+	if err := renderLanguageBar(doc, repo); err != nil {
+		t.Fatalf("renderLanguageBar failed: %v", err)
+	}
+	result := doc.String()
+
+	for _, expected := range []string{
+		`<rect x="0.00" width="150.00" height="10" fill="#00add8"><title>Go 75.0%</title></rect>`,
+		`<rect x="150.00" width="50.00" height="10" fill="#7e7eff"><title>Nix 25.0%</title></rect>`,
+		`>Go 75.0%</text>`,
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("result does not contain %s:\n%s", expected, result)
+		}
+	}
+	if strings.Contains(result, ">Nix 25.0%</text>") {
+		t.Error("legend lists more languages than data-legend")
+	}
+}
@@ -0,0 +1,183 @@
+package banner
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/numtide/banner-generator/internal/github"
+	"github.com/numtide/banner-generator/internal/logging"
+)
+
+// Versioned is implemented by builders whose output depends on more than
+// the repository, such as a template and fonts loaded from disk
+type Versioned interface {
+	// Version identifies what the builder renders with; it changes when
+	// the template is reloaded
+	Version() (string, error)
+}
+
+// RenderCacheStats are the counters of a RenderCache
+type RenderCacheStats struct {
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Evictions     uint64 `json:"evictions"`
+	Invalidations uint64 `json:"invalidations"`
+	Entries       int    `json:"entries"`
+	Bytes         int64  `json:"bytes"`
+}
+
+// renderEntry is a cached banner
+type renderEntry struct {
+	key string
+	svg string
+}
+
+// RenderCache is a Builder keeping rendered banners in memory, keyed by a
+// hash of the builder's version and the repository data. The least recently
+// used banners are evicted beyond the size limit, and all banners are
+// dropped when the builder's version changes.
+type RenderCache struct {
+	builder  Builder
+	maxBytes int64
+	now      func() time.Time
+
+	mu      sync.Mutex
+	version string
+	entries map[string]*list.Element
+	order   *list.List
+	bytes   int64
+	stats   RenderCacheStats
+}
+
+// NewRenderCache wraps builder with a cache of at most maxBytes of SVG
+func NewRenderCache(builder Builder, maxBytes int64) *RenderCache {
+	return &RenderCache{
+		builder:  builder,
+		maxBytes: maxBytes,
+		now:      time.Now,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// BuildBanner returns the cached banner for repo, building it on a miss
+func (c *RenderCache) BuildBanner(ctx context.Context, repo *github.Repository) (string, error) {
+	var version string
+	if versioned, ok := c.builder.(Versioned); ok {
+		var err error
+		if version, err = versioned.Version(); err != nil {
+			return "", err
+		}
+	}
+	key, err := c.key(version, repo)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	if version != c.version {
+		c.invalidate(version)
+	}
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		c.stats.Hits++
+		svg := element.Value.(*renderEntry).svg
+		c.mu.Unlock()
+		logging.Add(ctx, "render_cache", "hit")
+		return svg, nil
+	}
+	c.stats.Misses++
+	c.mu.Unlock()
+	logging.Add(ctx, "render_cache", "miss")
+
+	svg, err := c.builder.BuildBanner(ctx, repo)
+	if err != nil {
+		return "", err
+	}
+	c.add(version, key, svg)
+	return svg, nil
+}
+
+// LoadedAt passes on when the wrapped builder loaded its template, or the
+// zero time if it does not load one
+func (c *RenderCache) LoadedAt() time.Time {
+	if reloadable, ok := c.builder.(Reloadable); ok {
+		return reloadable.LoadedAt()
+	}
+	return time.Time{}
+}
+
+// Stats returns the cache's counters
+func (c *RenderCache) Stats() RenderCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	stats.Bytes = c.bytes
+	return stats
+}
+
+// key hashes what a banner is rendered from. Sparklines depend on the
+// current time, so banners with history are only reused within the hour.
+func (c *RenderCache) key(version string, repo *github.Repository) (string, error) {
+	data, err := json.Marshal(repo)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode repository: %w", err)
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00", version)
+	hash.Write(data)
+	if len(repo.History) > 0 {
+		fmt.Fprintf(hash, "\x00%d", c.now().Truncate(time.Hour).Unix())
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// add caches a banner built with version, evicting the least recently used
+// banners to stay within the size limit
+func (c *RenderCache) add(version, key, svg string) {
+	size := int64(len(svg))
+	if size > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The template may have been reloaded while building
+	if version != c.version {
+		return
+	}
+	if _, ok := c.entries[key]; ok {
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&renderEntry{key: key, svg: svg})
+	c.bytes += size
+	for c.bytes > c.maxBytes {
+		oldest := c.order.Back()
+		entry := c.order.Remove(oldest).(*renderEntry)
+		delete(c.entries, entry.key)
+		c.bytes -= int64(len(entry.svg))
+		c.stats.Evictions++
+	}
+}
+
+// invalidate drops all banners when the builder's version changes
+func (c *RenderCache) invalidate(version string) {
+	if len(c.entries) > 0 {
+		c.stats.Invalidations++
+	}
+	c.version = version
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+	c.bytes = 0
+}
@@ -0,0 +1,186 @@
+package banner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/numtide/banner-generator/internal/fonts"
+	"github.com/numtide/banner-generator/internal/forge"
+	"github.com/numtide/banner-generator/internal/github"
+)
+
+// countingBuilder renders the repository name at a version and counts calls
+type countingBuilder struct {
+	version string
+	calls   int
+}
+
+func (b *countingBuilder) BuildBanner(ctx context.Context, repo *github.Repository) (string, error) {
+	b.calls++
+	if repo.Name == "" {
+		return "", errors.New("no name")
+	}
+	return b.version + ":" + repo.Name, nil
+}
+
+func (b *countingBuilder) Version() (string, error) {
+	return b.version, nil
+}
+
+func TestRenderCache(t *testing.T) {
+	builder := &countingBuilder{version: "v1"}
+	c := NewRenderCache(builder, 12)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	build := func(repo *github.Repository, want string) {
+		t.Helper()
+		svg, err := c.BuildBanner(context.Background(), repo)
+		if err != nil {
+			t.Fatalf("BuildBanner failed: %v", err)
+		}
+		if svg != want {
+			t.Errorf("BuildBanner() = %q, want %q", svg, want)
+		}
+	}
+
+	treefmt := &github.Repository{Name: "treefmt", StargazersCount: 1}
+	build(treefmt, "v1:treefmt")
+	build(treefmt, "v1:treefmt")
+	if builder.calls != 1 {
+		t.Errorf("unchanged repository built %d times, want 1", builder.calls)
+	}
+
+	// Changed data is rendered again, and the size limit evicts the least
+	// recently used banner
+	build(&github.Repository{Name: "treefmt", StargazersCount: 2}, "v1:treefmt")
+	if builder.calls != 2 {
+		t.Errorf("changed repository was not built again")
+	}
+	if stats := c.Stats(); stats.Entries != 1 || stats.Bytes != 10 || stats.Evictions != 1 {
+		t.Errorf("stats after eviction = %+v, want 1 entry of 10 bytes and 1 eviction", stats)
+	}
+
+	// A reloaded template invalidates every banner
+	builder.version = "v2"
+	build(treefmt, "v2:treefmt")
+
+	// Banners with history are only reused within the hour
+	withHistory := &github.Repository{Name: "nix", History: []forge.HistorySample{{Stars: 1}}}
+	build(withHistory, "v2:nix")
+	calls := builder.calls
+	now = now.Add(30 * time.Minute)
+	build(withHistory, "v2:nix")
+	now = now.Add(time.Hour)
+	build(withHistory, "v2:nix")
+	if builder.calls != calls+1 {
+		t.Errorf("banner with history built %d more times, want 1", builder.calls-calls)
+	}
+
+	// Failures are not cached
+	for range 2 {
+		if _, err := c.BuildBanner(context.Background(), &github.Repository{}); err == nil {
+			t.Error("BuildBanner succeeded without a name")
+		}
+	}
+
+	stats := c.Stats()
+	if stats.Hits != 2 || stats.Misses != 7 || stats.Invalidations != 1 {
+		t.Errorf("stats = %+v, want 2 hits, 7 misses and 1 invalidation", stats)
+	}
+}
+
+func TestSimpleSVGBuilderReloadsTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "banner.svg")
+	write := func(content string, modTime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	modTime := time.Now().Add(-time.Hour)
+	write(`<svg><text id="repo-name">old</text></svg>`, modTime)
+	b := NewSimpleSVGBuilder(fonts.NewManager(t.TempDir()), path, false, "")
+	repo := &github.Repository{Name: "treefmt"}
+
+	before, err := b.Version()
+	if err != nil {
+		t.Fatalf("Version failed: %v", err)
+	}
+	if svg, _ := b.BuildBanner(context.Background(), repo); !strings.Contains(svg, "<tspan>treefmt</tspan>") {
+		t.Errorf("banner does not show the name: %s", svg)
+	}
+
+	write(`<svg><text id="repo-name" class="new">old</text></svg>`, modTime.Add(time.Minute))
+	after, err := b.Version()
+	if err != nil {
+		t.Fatalf("Version failed: %v", err)
+	}
+	if after == before {
+		t.Error("version did not change with the template")
+	}
+	if svg, _ := b.BuildBanner(context.Background(), repo); !strings.Contains(svg, `class="new"`) {
+		t.Errorf("banner does not use the changed template: %s", svg)
+	}
+	if got := b.Reloads(); got != 1 {
+		t.Errorf("Reloads() = %d, want 1", got)
+	}
+}
+
+func TestRenderCacheNoticesFontChanges(t *testing.T) {
+	fontDir := t.TempDir()
+	writeFiles := func(files map[string]string, modTime time.Time) {
+		t.Helper()
+		for name, content := range files {
+			path := filepath.Join(fontDir, name)
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(path, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	modTime := time.Now().Add(-time.Hour)
+	writeFiles(map[string]string{
+		"fonts.toml":   "[[fonts]]\nfamily = \"test\"\nname = \"Test\"\nvariants = { ttf = \"test.ttf\" }\n",
+		"test.ttf":     "old font",
+		"template.svg": `<svg><style id="font-css"></style><text id="repo-name" font-family="test">old</text></svg>`,
+	}, modTime)
+
+	fontManager := fonts.NewManager(fontDir)
+	c := NewRenderCache(NewSimpleSVGBuilder(fontManager, filepath.Join(fontDir, "template.svg"), false, ""), 1<<20)
+	repo := &github.Repository{Name: "treefmt"}
+
+	build := func() string {
+		t.Helper()
+		svg, err := c.BuildBanner(context.Background(), repo)
+		if err != nil {
+			t.Fatalf("BuildBanner failed: %v", err)
+		}
+		return svg
+	}
+	before := build()
+	build()
+
+	writeFiles(map[string]string{"test.ttf": "new font"}, modTime.Add(time.Minute))
+	after := build()
+	if after == before {
+		t.Error("banner still embeds the old font")
+	}
+	if stats := c.Stats(); stats.Hits != 1 || stats.Misses != 2 || stats.Invalidations != 1 {
+		t.Errorf("stats = %+v, want 1 hit, 2 misses and 1 invalidation", stats)
+	}
+	if got := fontManager.Reloads(); got != 1 {
+		t.Errorf("Reloads() = %d, want 1", got)
+	}
+}
@@ -1,25 +1,53 @@
 package banner
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/numtide/banner-generator/internal/fonts"
 	"github.com/numtide/banner-generator/internal/github"
+	"github.com/numtide/banner-generator/internal/linguist"
+	"github.com/numtide/banner-generator/internal/logging"
 	"github.com/numtide/banner-generator/internal/svg"
 	"github.com/numtide/banner-generator/internal/utils"
 )
 
-// SimpleSVGBuilder builds banners using simple string manipulation
+// SimpleSVGBuilder builds banners using simple string manipulation. The
+// template is kept in memory and read again when the file changes.
 type SimpleSVGBuilder struct {
 	fontManager     fonts.Manager
 	templatePath    string
 	enableWebFonts  bool
 	webFontsBaseURL string
+
+	mu       sync.Mutex
+	template *loadedTemplate
+	reloads  uint64
+}
+
+// loadedTemplate is a template read from disk
+type loadedTemplate struct {
+	content  string
+	modTime  time.Time
+	size     int64
+	loadedAt time.Time
+
+	// version hashes the content with the fonts and options it renders with
+	version string
+
+	// fontPaths are the font files embedded in banners
+	fontPaths []string
 }
 
 // NewSimpleSVGBuilder creates a new simple SVG-based banner builder
@@ -32,32 +60,37 @@ func NewSimpleSVGBuilder(fontManager fonts.Manager, templatePath string, enableW
 	}
 }
 
-// BuildBanner generates a banner for the given repository
-func (b *SimpleSVGBuilder) BuildBanner(repo *github.Repository) (string, error) {
+// BuildBanner generates a banner for the given repository. Slots missing
+// from the template are logged at debug level.
+func (b *SimpleSVGBuilder) BuildBanner(ctx context.Context, repo *github.Repository) (string, error) {
 	// Load template
-	templateContent, err := os.ReadFile(b.templatePath)
+	template, err := b.loadTemplate()
 	if err != nil {
-		return "", fmt.Errorf("failed to read template: %w", err)
+		return "", err
+	}
+	logging.Add(ctx, "template", b.templatePath)
+	missing := func(element string, err error) {
+		slog.DebugContext(ctx, "Template element not found", "element", element, "error", err)
 	}
 
 	// Create simple document
-	doc := svg.NewSimpleDocument(string(templateContent))
+	doc := svg.NewSimpleDocument(template.content)
 
 	// Update repository name
 	if err := doc.UpdateTextByID("repo-name", repo.Name); err != nil {
-		log.Printf("debug: repo-name element not found in template: %v", err)
+		missing("repo-name", err)
 	}
 
 	// Update description with multi-line support
 	if repo.Description != "" {
 		lines := wrapText(repo.Description, 50) // 50 chars per line
 		if err := doc.UpdateMultilineText("description", lines); err != nil {
-			log.Printf("debug: description element not found in template: %v", err)
+			missing("description", err)
 		}
 	} else {
 		// Hide description if empty
 		if err := doc.HideElementByID("description"); err != nil {
-			log.Printf("debug: description element not found in template: %v", err)
+			missing("description", err)
 		}
 	}
 
@@ -66,32 +99,58 @@ func (b *SimpleSVGBuilder) BuildBanner(repo *github.Repository) (string, error)
 		// Format star count
 		stars := utils.FormatCount(repo.StargazersCount)
 		if err := doc.UpdateTextByID("stats-stars", fmt.Sprintf("⭐ %s", stars)); err != nil {
-			log.Printf("debug: stats-stars element not found in template: %v", err)
+			missing("stats-stars", err)
 		}
 
 		// Format fork count
 		forks := utils.FormatCount(repo.ForksCount)
 		if err := doc.UpdateTextByID("stats-forks", fmt.Sprintf("🍴 %s", forks)); err != nil {
-			log.Printf("debug: stats-forks element not found in template: %v", err)
+			missing("stats-forks", err)
 		}
 
 		// Update language if available
 		if repo.Language != "" {
 			if err := doc.UpdateTextByID("stats-language", repo.Language); err != nil {
-				log.Printf("debug: stats-language element not found in template: %v", err)
+				missing("stats-language", err)
+			}
+			if err := doc.SetAttributeByID("stats-language-dot", "fill", linguist.Color(repo.Language)); err != nil {
+				missing("stats-language-dot", err)
 			}
 		} else {
 			if err := doc.HideElementByID("stats-language"); err != nil {
-				log.Printf("debug: stats-language element not found in template: %v", err)
+				missing("stats-language", err)
+			}
+			if err := doc.HideElementByID("stats-language-dot"); err != nil {
+				missing("stats-language-dot", err)
 			}
 		}
+	} else if repo.Language != "" {
+		// Show the language alone, e.g. the tag of a custom banner
+		if err := showLanguageOnly(doc, repo.Language); err != nil {
+			missing("stats", err)
+		}
 	} else {
 		// Hide stats group if no data
 		if err := doc.HideElementByID("stats-group"); err != nil {
-			log.Printf("debug: stats-group element not found in template: %v", err)
+			missing("stats-group", err)
 		}
 	}
 
+	// Render contributors avatar strip
+	if err := renderContributors(doc, repo); err != nil {
+		missing("contributors", err)
+	}
+
+	// Render language breakdown bar
+	if err := renderLanguageBar(doc, repo); err != nil {
+		missing("language-bar", err)
+	}
+
+	// Render star/fork trend from recorded history
+	if err := renderSparkline(doc, repo, time.Now()); err != nil {
+		missing("sparkline", err)
+	}
+
 	// Generate font CSS
 	fontCSS, err := b.generateFontCSS(doc.String())
 	if err != nil {
@@ -100,40 +159,148 @@ func (b *SimpleSVGBuilder) BuildBanner(repo *github.Repository) (string, error)
 
 	// Inject font CSS
 	if err := doc.InjectCSS("font-css", fontCSS); err != nil {
-		log.Printf("debug: font-css style element not found in template: %v", err)
+		missing("font-css", err)
 	}
 
 	return doc.String(), nil
 }
 
-// generateFontCSS generates @font-face CSS for fonts used in the SVG
-func (b *SimpleSVGBuilder) generateFontCSS(svgContent string) (string, error) {
-	// Find all font-family references
-	fontFamilies := make(map[string]bool)
+// Version identifies the template, fonts and options banners are rendered
+// with. It changes when the template file or an embedded font file changes.
+func (b *SimpleSVGBuilder) Version() (string, error) {
+	template, err := b.loadTemplate()
+	if err != nil {
+		return "", err
+	}
+	for _, fontPath := range template.fontPaths {
+		// Missing fonts are left out of banners, as in generateEmbeddedFontCSS
+		_ = b.fontManager.Refresh(fontPath)
+	}
+	return template.version + "-" + strconv.FormatUint(b.fontManager.Reloads(), 10), nil
+}
 
-	// Pattern to find font-family in attributes
-	attrPattern := `font-family="([^"]*)"`
-	attrRe := regexp.MustCompile(attrPattern)
-	for _, match := range attrRe.FindAllStringSubmatch(svgContent, -1) {
-		if len(match) > 1 {
-			fontFamilies[match[1]] = true
+// LoadedAt returns when the template in use was read
+func (b *SimpleSVGBuilder) LoadedAt() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.template == nil {
+		return time.Time{}
+	}
+	return b.template.loadedAt
+}
+
+// Reloads returns how often the template was read again after changing
+func (b *SimpleSVGBuilder) Reloads() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.reloads
+}
+
+// loadTemplate returns the template, reading the file again if its size or
+// modification time changed
+func (b *SimpleSVGBuilder) loadTemplate() (*loadedTemplate, error) {
+	info, err := os.Stat(b.templatePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t := b.template; t != nil && t.modTime.Equal(info.ModTime()) && t.size == info.Size() {
+		return t, nil
+	}
+
+	content, err := os.ReadFile(b.templatePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+	if b.template != nil {
+		slog.Info("Reloaded template", "template", b.templatePath)
+		b.reloads++
+	}
+	b.template = &loadedTemplate{
+		content:   string(content),
+		modTime:   info.ModTime(),
+		size:      info.Size(),
+		loadedAt:  time.Now(),
+		version:   b.templateVersion(string(content)),
+		fontPaths: b.embeddedFontPaths(string(content)),
+	}
+	return b.template, nil
+}
+
+// embeddedFontPaths returns the font files embedded in banners of the
+// template; web fonts are linked instead
+func (b *SimpleSVGBuilder) embeddedFontPaths(content string) []string {
+	if b.enableWebFonts {
+		return nil
+	}
+	var paths []string
+	for _, family := range fontFamilies(content) {
+		if font := b.fontManager.GetFont(family); font != nil && font.GetFontPath() != "" {
+			paths = append(paths, font.GetFontPath())
 		}
 	}
+	return paths
+}
 
-	// Pattern to find font-family in style
-	stylePattern := `font-family:\s*'?([^'";]+)'?`
-	styleRe := regexp.MustCompile(stylePattern)
-	for _, match := range styleRe.FindAllStringSubmatch(svgContent, -1) {
-		if len(match) > 1 {
-			family := strings.TrimSpace(match[1])
-			family = strings.Trim(family, `"'`)
-			fontFamilies[family] = true
+// templateVersion hashes template content with the fonts it references and
+// the font options
+func (b *SimpleSVGBuilder) templateVersion(content string) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%t\x00%s\x00", content, b.enableWebFonts, b.webFontsBaseURL)
+	for _, family := range fontFamilies(content) {
+		if font := b.fontManager.GetFont(family); font != nil {
+			fmt.Fprintf(hash, "%s=%s\x00", family, font.GetFontPath())
 		}
 	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
 
+// showLanguageOnly hides the star and fork counts and moves the language
+// with its dot to where the stars start
+func showLanguageOnly(doc *svg.SimpleDocument, language string) error {
+	stars, err := doc.ElementAttributes("stats-stars")
+	if err != nil {
+		return err
+	}
+	dot, err := doc.ElementAttributes("stats-language-dot")
+	if err != nil {
+		return err
+	}
+	text, err := doc.ElementAttributes("stats-language")
+	if err != nil {
+		return err
+	}
+
+	starsX, _ := strconv.ParseFloat(stars["x"], 64)
+	cx, _ := strconv.ParseFloat(dot["cx"], 64)
+	r, _ := strconv.ParseFloat(dot["r"], 64)
+	x, _ := strconv.ParseFloat(text["x"], 64)
+	shift := cx - r - starsX
+
+	for _, id := range []string{"stats-stars", "stats-forks"} {
+		if err := doc.HideElementByID(id); err != nil {
+			return err
+		}
+	}
+	if err := doc.SetAttributeByID("stats-language-dot", "cx", strconv.FormatFloat(cx-shift, 'f', -1, 64)); err != nil {
+		return err
+	}
+	if err := doc.SetAttributeByID("stats-language-dot", "fill", linguist.Color(language)); err != nil {
+		return err
+	}
+	if err := doc.SetAttributeByID("stats-language", "x", strconv.FormatFloat(x-shift, 'f', -1, 64)); err != nil {
+		return err
+	}
+	return doc.UpdateTextByID("stats-language", language)
+}
+
+// generateFontCSS generates @font-face CSS for fonts used in the SVG
+func (b *SimpleSVGBuilder) generateFontCSS(svgContent string) (string, error) {
 	// Generate CSS for each font
 	var cssBuilder strings.Builder
-	for family := range fontFamilies {
+	for _, family := range fontFamilies(svgContent) {
 		font := b.fontManager.GetFont(family)
 		if font == nil {
 			continue // Skip unknown fonts
@@ -152,6 +319,38 @@ func (b *SimpleSVGBuilder) generateFontCSS(svgContent string) (string, error) {
 	return cssBuilder.String(), nil
 }
 
+// fontFamilies returns the font families referenced in SVG content, sorted
+func fontFamilies(svgContent string) []string {
+	families := make(map[string]bool)
+
+	// Pattern to find font-family in attributes
+	attrPattern := `font-family="([^"]*)"`
+	attrRe := regexp.MustCompile(attrPattern)
+	for _, match := range attrRe.FindAllStringSubmatch(svgContent, -1) {
+		if len(match) > 1 {
+			families[match[1]] = true
+		}
+	}
+
+	// Pattern to find font-family in style
+	stylePattern := `font-family:\s*'?([^'";]+)'?`
+	styleRe := regexp.MustCompile(stylePattern)
+	for _, match := range styleRe.FindAllStringSubmatch(svgContent, -1) {
+		if len(match) > 1 {
+			family := strings.TrimSpace(match[1])
+			family = strings.Trim(family, `"'`)
+			families[family] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(families))
+	for family := range families {
+		sorted = append(sorted, family)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
 // generateWebFontCSS generates @font-face CSS with URLs
 func (b *SimpleSVGBuilder) generateWebFontCSS(font *fonts.Font, familyName string) string {
 	var css strings.Builder
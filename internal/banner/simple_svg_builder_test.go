@@ -0,0 +1,34 @@
+package banner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/numtide/banner-generator/internal/linguist"
+	"github.com/numtide/banner-generator/internal/svg"
+)
+
+func TestShowLanguageOnly(t *testing.T) {
+	doc := svg.NewSimpleDocument(`<svg><g id="stats-group">
+<text id="stats-stars" x="50" y="580">⭐ 0</text>
+<text id="stats-forks" x="200" y="580">🍴 0</text>
+<circle id="stats-language-dot" cx="358" cy="568" r="8" fill="white"/>
+<text id="stats-language" x="376" y="580">Go</text>
+</g></svg>`)
+
+	if err := showLanguageOnly(doc, "Talk"); err != nil {
+		t.Fatalf("showLanguageOnly failed: %v", err)
+	}
+	result := doc.String()
+
+	for _, expected := range []string{
+		`<text id="stats-stars" x="50" y="580" visibility="hidden">`,
+		`<text id="stats-forks" x="200" y="580" visibility="hidden">`,
+		`<circle id="stats-language-dot" cx="58" cy="568" r="8" fill="` + linguist.DefaultColor + `"/>`,
+		`<text id="stats-language" x="76" y="580"><tspan>Talk</tspan></text>`,
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("result does not contain %s:\n%s", expected, result)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package banner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/numtide/banner-generator/internal/github"
+	"github.com/numtide/banner-generator/internal/svg"
+	"github.com/numtide/banner-generator/internal/utils"
+)
+
+// Sparkline slot defaults, overridden by attributes on the group:
+//
+//	<g id="sparkline" data-metric="stars" data-mode="line" data-period="720h" data-width="200" data-height="40"/>
+//
+// data-metric is "stars" or "forks". data-mode "line" draws the trend over
+// the period, "delta" writes the change followed by data-label.
+const (
+	defaultSparklinePeriod = 30 * 24 * time.Hour
+	defaultSparklineWidth  = 200
+	defaultSparklineHeight = 40
+	defaultSparklineLabel  = "this month"
+)
+
+// renderSparkline fills the sparkline group from the recorded history. The
+// group is hidden until at least two samples are known.
+func renderSparkline(doc *svg.SimpleDocument, repo *github.Repository, now time.Time) error {
+	attrs, err := doc.ElementAttributes("sparkline")
+	if err != nil {
+		return err
+	}
+
+	period := defaultSparklinePeriod
+	if value, err := time.ParseDuration(attrs["data-period"]); err == nil && value > 0 {
+		period = value
+	}
+	start := now.Add(-period)
+
+	// Keep the latest sample before the period as the baseline
+	samples := repo.History
+	for len(samples) > 1 && !samples[1].Time.After(start) {
+		samples = samples[1:]
+	}
+	if len(samples) < 2 {
+		return doc.HideElementByID("sparkline")
+	}
+
+	metric := func(i int) int { return samples[i].Stars }
+	if attrs["data-metric"] == "forks" {
+		metric = func(i int) int { return samples[i].Forks }
+	}
+
+	if attrs["data-mode"] == "delta" {
+		label := attrs["data-label"]
+		if label == "" {
+			label = defaultSparklineLabel
+		}
+
+		delta := metric(len(samples)-1) - metric(0)
+		sign := "+"
+		if delta < 0 {
+			sign, delta = "-", -delta
+		}
+		return doc.SetElementContent("sparkline", fmt.Sprintf(`<text x="0" y="0">%s%s %s</text>`,
+			sign, utils.FormatCount(delta), svg.EscapeXML(label)))
+	}
+
+	width := float64(intAttribute(attrs, "data-width", defaultSparklineWidth))
+	height := float64(intAttribute(attrs, "data-height", defaultSparklineHeight))
+
+	low, high := metric(0), metric(0)
+	for i := range samples {
+		low, high = min(low, metric(i)), max(high, metric(i))
+	}
+
+	points := make([]string, 0, len(samples))
+	for i, sample := range samples {
+		// The baseline may predate the period; pin it to the left edge
+		x := width * max(sample.Time.Sub(start).Seconds(), 0) / period.Seconds()
+		y := height / 2
+		if high > low {
+			y = height - height*float64(metric(i)-low)/float64(high-low)
+		}
+		points = append(points, fmt.Sprintf("%.1f,%.1f", min(x, width), y))
+	}
+
+	return doc.SetElementContent("sparkline", fmt.Sprintf(
+		`<polyline points="%s" fill="none" stroke="currentColor" stroke-width="2" stroke-linejoin="round" stroke-linecap="round"/>`,
+		strings.Join(points, " ")))
+}
@@ -0,0 +1,67 @@
+package banner
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/numtide/banner-generator/internal/forge"
+	"github.com/numtide/banner-generator/internal/github"
+	"github.com/numtide/banner-generator/internal/svg"
+)
+
+func TestRenderSparkline(t *testing.T) {
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	repo := &github.Repository{
+		History: []forge.HistorySample{
+			{Time: now.Add(-96 * time.Hour), Stars: 5, Forks: 1},
+			{Time: now.Add(-72 * time.Hour), Stars: 10, Forks: 1},
+			{Time: now.Add(-24 * time.Hour), Stars: 20, Forks: 2},
+			{Time: now, Stars: 40, Forks: 3},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "line",
+			template: `<svg><g id="sparkline" data-period="48h" data-width="100" data-height="10"/></svg>`,
+			// The baseline before the period is pinned to the left edge
+			expected: `points="0.0,10.0 50.0,6.7 100.0,0.0"`,
+		},
+		{
+			name:     "delta",
+			template: `<svg><g id="sparkline" data-mode="delta" data-metric="forks" data-period="48h" data-label="this week"/></svg>`,
+			expected: `>+2 this week</text>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := svg.NewSimpleDocument(tt.template)
+			if err := renderSparkline(doc, repo, now); err != nil {
+				t.Fatalf("renderSparkline failed: %v", err)
+			}
+			if result := doc.String(); !strings.Contains(result, tt.expected) {
+				t.Errorf("result does not contain %s:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRenderSparklineWithoutHistory(t *testing.T) {
+	doc := svg.NewSimpleDocument(`<svg><g id="sparkline"/></svg>`)
+	repo := &github.Repository{
+		History: []forge.HistorySample{{Time: time.Now(), Stars: 5}},
+	}
+
+	if err := renderSparkline(doc, repo, time.Now()); err != nil {
+		t.Fatalf("renderSparkline failed: %v", err)
+	}
+	if result := doc.String(); !strings.Contains(result, `visibility="hidden"`) {
+		t.Errorf("sparkline not hidden with a single sample:\n%s", result)
+	}
+}
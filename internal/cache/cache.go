@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Backend names accepted in configuration
+const (
+	BackendMemory = "memory"
+	BackendDisk   = "disk"
+	BackendRedis  = "redis"
+)
+
+// Cache is a byte-oriented key/value store with per-entry expiry
+type Cache interface {
+	// Get returns the value stored under key. The boolean is false if the
+	// key does not exist or has expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+
+	// Set stores value under key. A zero ttl means the entry never expires.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	// Delete removes key from the cache
+	Delete(ctx context.Context, key string) error
+
+	// Close releases any resources held by the cache
+	Close() error
+}
+
+// Options selects and configures a cache backend
+type Options struct {
+	// Backend is one of "memory", "disk" or "redis" (default "memory")
+	Backend string
+
+	// Path is the database file used by the disk backend
+	Path string
+
+	// RedisURL is the server address used by the redis backend
+	RedisURL string
+}
+
+// New creates the cache backend described by opts
+func New(opts Options) (Cache, error) {
+	switch opts.Backend {
+	case "", BackendMemory:
+		return NewMemory(), nil
+	case BackendDisk:
+		if opts.Path == "" {
+			return nil, fmt.Errorf("disk cache requires a path")
+		}
+		return NewDisk(opts.Path)
+	case BackendRedis:
+		if opts.RedisURL == "" {
+			return nil, fmt.Errorf("redis cache requires a redis_url")
+		}
+		return NewRedis(opts.RedisURL)
+	default:
+		return nil, fmt.Errorf("unknown cache backend '%s'", opts.Backend)
+	}
+}
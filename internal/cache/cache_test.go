@@ -0,0 +1,246 @@
+package cache
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	bolt "go.etcd.io/bbolt"
+)
+
+func testCache(t *testing.T, c Cache) {
+	t.Helper()
+	ctx := context.Background()
+
+	if _, ok, err := c.Get(ctx, "missing"); err != nil || ok {
+		t.Errorf("Get(missing) = ok %v, err %v; want miss", ok, err)
+	}
+
+	if err := c.Set(ctx, "key", []byte("value"), time.Hour); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	value, ok, err := c.Get(ctx, "key")
+	if err != nil || !ok || string(value) != "value" {
+		t.Errorf("Get(key) = %q, %v, %v; want \"value\", true, nil", value, ok, err)
+	}
+
+	// An empty value is still an entry
+	if err := c.Set(ctx, "empty", []byte{}, time.Hour); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if value, ok, err := c.Get(ctx, "empty"); err != nil || !ok || len(value) != 0 {
+		t.Errorf("Get(empty) = %q, %v, %v; want \"\", true, nil", value, ok, err)
+	}
+
+	if err := c.Set(ctx, "expiring", []byte("value"), time.Millisecond); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, ok, _ := c.Get(ctx, "expiring"); ok {
+		t.Error("expired entry was returned")
+	}
+
+	if err := c.Delete(ctx, "key"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, ok, _ := c.Get(ctx, "key"); ok {
+		t.Error("deleted entry was returned")
+	}
+}
+
+func TestMemory(t *testing.T) {
+	testCache(t, NewMemory())
+}
+
+func TestBoundedMemory(t *testing.T) {
+	ctx := context.Background()
+	c := NewBoundedMemory(10)
+	testCache(t, c)
+
+	// The least recently used entry is evicted beyond the limit, and values
+	// larger than the whole cache are not stored
+	for _, key := range []string{"a", "b"} {
+		if err := c.Set(ctx, key, []byte("abcd"), 0); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+	evictions := c.Evictions()
+	c.Get(ctx, "a")
+	if err := c.Set(ctx, "c", []byte("abcd"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := c.Set(ctx, "huge", []byte("abcdefghijk"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true, "huge": false} {
+		if _, ok, _ := c.Get(ctx, key); ok != want {
+			t.Errorf("Get(%s) found %v, want %v", key, ok, want)
+		}
+	}
+	if got := NewCounted(c).Stats().Evictions - evictions; got != 1 {
+		t.Errorf("evictions = %d, want 1", got)
+	}
+}
+
+func TestMemorySweepCountsEvictions(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemory()
+	if err := c.Set(ctx, "old", []byte("value"), time.Millisecond); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	c.lastSweep = time.Now().Add(-2 * sweepInterval)
+	if err := c.Set(ctx, "new", []byte("value"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if _, ok := c.entries["old"]; ok {
+		t.Error("expired entry was not swept")
+	}
+	if got := c.Evictions(); got != 1 {
+		t.Errorf("evictions = %d, want 1", got)
+	}
+}
+
+func TestDisk(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	c, err := NewDisk(path)
+	if err != nil {
+		t.Fatalf("NewDisk failed: %v", err)
+	}
+	defer c.Close()
+
+	testCache(t, c)
+}
+
+func TestDiskSweepsExpiredEntries(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewDisk(filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		t.Fatalf("NewDisk failed: %v", err)
+	}
+	defer c.Close()
+
+	for _, key := range []string{"old1", "old2"} {
+		if err := c.Set(ctx, key, []byte("value"), time.Millisecond); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	// Expired entries nobody reads again are purged by a later Set
+	c.lastSweep = time.Now().Add(-2 * sweepInterval)
+	if err := c.Set(ctx, "new", []byte("value"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	var keys int
+	err = c.db.View(func(tx *bolt.Tx) error {
+		keys = tx.Bucket(diskBucket).Stats().KeyN
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("reading the database failed: %v", err)
+	}
+	if keys != 1 {
+		t.Errorf("database holds %d entries, want 1", keys)
+	}
+	if got := c.Evictions(); got != 2 {
+		t.Errorf("evictions = %d, want 2", got)
+	}
+}
+
+func TestDiskSurvivesReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.db")
+
+	c, err := NewDisk(path)
+	if err != nil {
+		t.Fatalf("NewDisk failed: %v", err)
+	}
+	if err := c.Set(ctx, "key", []byte("value"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	c, err = NewDisk(path)
+	if err != nil {
+		t.Fatalf("reopening failed: %v", err)
+	}
+	defer c.Close()
+
+	value, ok, err := c.Get(ctx, "key")
+	if err != nil || !ok || string(value) != "value" {
+		t.Errorf("Get(key) after reopen = %q, %v, %v; want \"value\", true, nil", value, ok, err)
+	}
+}
+
+func TestRedis(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	c, err := NewRedis("redis://" + server.Addr())
+	if err != nil {
+		t.Fatalf("NewRedis failed: %v", err)
+	}
+	defer c.Close()
+
+	if _, ok, err := c.Get(ctx, "missing"); err != nil || ok {
+		t.Errorf("Get(missing) = ok %v, err %v; want miss", ok, err)
+	}
+
+	if err := c.Set(ctx, "key", []byte("value"), time.Hour); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	value, ok, err := c.Get(ctx, "key")
+	if err != nil || !ok || string(value) != "value" {
+		t.Errorf("Get(key) = %q, %v, %v; want \"value\", true, nil", value, ok, err)
+	}
+	if ttl := server.TTL(redisKeyPrefix + "key"); ttl != time.Hour {
+		t.Errorf("got TTL %v, want %v", ttl, time.Hour)
+	}
+
+	// The server expires entries, which then miss
+	server.FastForward(time.Hour)
+	if _, ok, err := c.Get(ctx, "key"); err != nil || ok {
+		t.Errorf("Get(expired) = ok %v, err %v; want miss", ok, err)
+	}
+
+	if err := c.Set(ctx, "deleted", []byte("value"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if ttl := server.TTL(redisKeyPrefix + "deleted"); ttl != 0 {
+		t.Errorf("got TTL %v for an entry without expiry", ttl)
+	}
+	if err := c.Delete(ctx, "deleted"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, ok, _ := c.Get(ctx, "deleted"); ok {
+		t.Error("deleted entry was returned")
+	}
+
+	// Errors are reported rather than taken for misses
+	server.Close()
+	if _, _, err := c.Get(ctx, "key"); err == nil {
+		t.Error("expected error from a closed server")
+	}
+}
+
+func TestNewUnknownBackend(t *testing.T) {
+	if _, err := New(Options{Backend: "memcached"}); err == nil {
+		t.Error("expected error for unknown backend")
+	}
+}
+
+func TestCounted(t *testing.T) {
+	c := NewCounted(NewMemory())
+	testCache(t, c)
+
+	// testCache looks up a missing, a stored, an empty, an expired and a
+	// deleted key
+	if stats := c.Stats(); stats.Hits != 2 || stats.Misses != 3 {
+		t.Errorf("Stats() = %+v, want 2 hits and 3 misses", stats)
+	}
+}
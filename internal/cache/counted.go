@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Stats are the counters of a Counted cache. Evictions stays zero for
+// backends that do not report them.
+type Stats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+}
+
+// evicter is implemented by backends that drop entries on their own
+type evicter interface {
+	Evictions() uint64
+}
+
+// Counted is a cache that counts the hits and misses of its lookups
+type Counted struct {
+	Cache
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// NewCounted wraps c to count the outcome of every successful Get
+func NewCounted(c Cache) *Counted {
+	return &Counted{Cache: c}
+}
+
+func (c *Counted) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, ok, err := c.Cache.Get(ctx, key)
+	if err == nil {
+		if ok {
+			c.hits.Add(1)
+		} else {
+			c.misses.Add(1)
+		}
+	}
+	return value, ok, err
+}
+
+// Stats returns the cache's counters
+func (c *Counted) Stats() Stats {
+	stats := Stats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+	if e, ok := c.Cache.(evicter); ok {
+		stats.Evictions = e.Evictions()
+	}
+	return stats
+}
@@ -0,0 +1,179 @@
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var diskBucket = []byte("cache")
+
+// Disk is a cache persisted in a single embedded database file
+type Disk struct {
+	db *bolt.DB
+
+	mu        sync.Mutex
+	lastSweep time.Time
+	evictions atomic.Uint64
+}
+
+// NewDisk opens (or creates) the cache database at path
+func NewDisk(path string) (*Disk, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cache database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(diskBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to initialize cache database: %w", err)
+	}
+
+	return &Disk{db: db, lastSweep: time.Now()}, nil
+}
+
+// Get returns the value stored under key
+func (d *Disk) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	var value []byte
+	var found, expired bool
+
+	err := d.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(diskBucket).Get([]byte(key))
+		if len(raw) < 8 {
+			return nil
+		}
+
+		if diskExpired(raw, time.Now()) {
+			expired = true
+			return nil
+		}
+
+		// Bolt values are only valid during the transaction. The expiry
+		// prefix tells an empty value apart from a missing entry.
+		value = append([]byte{}, raw[8:]...)
+		found = true
+		return nil
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+
+	if expired {
+		if err := d.Delete(ctx, key); err != nil {
+			return nil, false, err
+		}
+		d.evictions.Add(1)
+		return nil, false, nil
+	}
+
+	return value, found, nil
+}
+
+// Set stores value under key
+func (d *Disk) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	var expires int64
+	if ttl > 0 {
+		expires = time.Now().Add(ttl).UnixNano()
+	}
+
+	raw := make([]byte, 8+len(value))
+	binary.BigEndian.PutUint64(raw[:8], uint64(expires))
+	copy(raw[8:], value)
+
+	err := d.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(diskBucket).Put([]byte(key), raw)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	// Purge expired entries now and then, since entries nobody asks for
+	// again would otherwise stay in the file forever
+	d.mu.Lock()
+	sweep := time.Since(d.lastSweep) > sweepInterval
+	if sweep {
+		d.lastSweep = time.Now()
+	}
+	d.mu.Unlock()
+	if sweep {
+		if err := d.sweep(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sweep deletes all expired entries
+func (d *Disk) sweep() error {
+	now := time.Now()
+	var swept uint64
+	err := d.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(diskBucket)
+		var expired [][]byte
+		err := bucket.ForEach(func(key, raw []byte) error {
+			if len(raw) >= 8 && diskExpired(raw, now) {
+				expired = append(expired, append([]byte(nil), key...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		// Deleting while iterating would skip entries
+		for _, key := range expired {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		swept = uint64(len(expired))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to purge expired cache entries: %w", err)
+	}
+	d.evictions.Add(swept)
+	return nil
+}
+
+// Evictions returns how many expired entries were purged
+func (d *Disk) Evictions() uint64 {
+	return d.evictions.Load()
+}
+
+// Delete removes key from the cache
+func (d *Disk) Delete(ctx context.Context, key string) error {
+	err := d.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(diskBucket).Delete([]byte(key))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete cache entry: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying database
+func (d *Disk) Close() error {
+	return d.db.Close()
+}
+
+// diskExpired reports whether a stored value, prefixed with its expiry time
+// in Unix nanoseconds, has expired
+func diskExpired(raw []byte, now time.Time) bool {
+	expires := int64(binary.BigEndian.Uint64(raw[:8]))
+	return expires != 0 && now.UnixNano() > expires
+}
@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// sweepInterval is how often expired entries are purged from memory
+const sweepInterval = time.Minute
+
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// Memory is an in-process cache that is lost on restart. With a size limit,
+// the least recently used entries are evicted to stay within it.
+type Memory struct {
+	maxBytes int64
+
+	mu        sync.Mutex
+	entries   map[string]*list.Element
+	order     *list.List
+	bytes     int64
+	lastSweep time.Time
+	evictions atomic.Uint64
+}
+
+// NewMemory creates a new in-memory cache without a size limit
+func NewMemory() *Memory {
+	return NewBoundedMemory(0)
+}
+
+// NewBoundedMemory creates an in-memory cache holding at most maxBytes of
+// values; zero means no limit
+func NewBoundedMemory(maxBytes int64) *Memory {
+	return &Memory{
+		maxBytes:  maxBytes,
+		entries:   make(map[string]*list.Element),
+		order:     list.New(),
+		lastSweep: time.Now(),
+	}
+}
+
+// Get returns the value stored under key
+func (m *Memory) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, ok := m.entries[key]
+	if !ok || elem.Value.(*memoryEntry).expired(time.Now()) {
+		return nil, false, nil
+	}
+	m.order.MoveToFront(elem)
+	return elem.Value.(*memoryEntry).value, true, nil
+}
+
+// Set stores value under key
+func (m *Memory) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	now := time.Now()
+	entry := &memoryEntry{key: key, value: value}
+	if ttl > 0 {
+		entry.expires = now.Add(ttl)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if elem, ok := m.entries[key]; ok {
+		m.remove(elem)
+	}
+	if m.maxBytes > 0 && int64(len(value)) > m.maxBytes {
+		// Larger than the whole cache
+		return nil
+	}
+	m.entries[key] = m.order.PushFront(entry)
+	m.bytes += int64(len(value))
+
+	for m.maxBytes > 0 && m.bytes > m.maxBytes {
+		m.remove(m.order.Back())
+		m.evictions.Add(1)
+	}
+
+	// Purge expired entries now and then so the map does not grow forever
+	if now.Sub(m.lastSweep) > sweepInterval {
+		for _, elem := range m.entries {
+			if elem.Value.(*memoryEntry).expired(now) {
+				m.remove(elem)
+				m.evictions.Add(1)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	return nil
+}
+
+// Delete removes key from the cache
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if elem, ok := m.entries[key]; ok {
+		m.remove(elem)
+	}
+	return nil
+}
+
+// Evictions returns how many entries were dropped to stay within the size
+// limit or purged after expiring
+func (m *Memory) Evictions() uint64 {
+	return m.evictions.Load()
+}
+
+// Close is a no-op for the in-memory cache
+func (m *Memory) Close() error {
+	return nil
+}
+
+// remove drops an entry; the caller holds m.mu
+func (m *Memory) remove(elem *list.Element) {
+	entry := m.order.Remove(elem).(*memoryEntry)
+	delete(m.entries, entry.key)
+	m.bytes -= int64(len(entry.value))
+}
+
+func (e *memoryEntry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && now.After(e.expires)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -11,48 +12,132 @@ import (
 	"github.com/numtide/banner-generator/internal/config"
 	"github.com/numtide/banner-generator/internal/converter"
 	"github.com/numtide/banner-generator/internal/fonts"
+	"github.com/numtide/banner-generator/internal/forge"
 	"github.com/numtide/banner-generator/internal/github"
 )
 
-// Generator handles PNG banner generation
+// ImageOptions controls the banner image a Generator writes
+type ImageOptions struct {
+	// NoStats omits stars, forks and language
+	NoStats bool
+
+	// DarkMode renders the dark color scheme
+	DarkMode bool
+
+	// Format is the image format (default PNG)
+	Format converter.Format
+
+	// Quality is the quality of lossy formats (default
+	// converter.DefaultQuality)
+	Quality int
+}
+
+// Generator handles banner image generation. Close it when done to stop
+// the browser it converts with.
 type Generator struct {
-	svgBuilder   banner.Builder
-	githubClient *github.Client
+	svgBuilder banner.Builder
+	providers  *forge.Registry
+	rasterizer converter.Rasterizer
 }
 
-// NewGeneratorWithConfig creates a new PNG generator with provided config
+// NewGeneratorWithConfig creates a new banner generator with provided config
 func NewGeneratorWithConfig(appConfig *config.AppConfig) (*Generator, error) {
+	githubOptions, err := github.OptionsFromConfig(appConfig.GitHub)
+	if err != nil {
+		return nil, err
+	}
+	githubOptions.CacheDuration = 1 * time.Hour // Use 1 hour cache for CLI
+
+	githubClient, err := github.NewClientWithOptions(githubOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	providers := forge.NewRegistry(githubClient)
+	for name, forgeConfig := range appConfig.Forges {
+		provider, err := forge.NewFromConfig(name, forgeConfig)
+		if err != nil {
+			return nil, err
+		}
+		providers.Register(provider)
+	}
+
+	generator, err := NewOfflineGenerator(appConfig)
+	if err != nil {
+		return nil, err
+	}
+	generator.providers = providers
+	return generator, nil
+}
+
+// NewOfflineGenerator creates a banner generator that never touches the
+// network. Repository data must be passed to GenerateFromRepository.
+func NewOfflineGenerator(appConfig *config.AppConfig) (*Generator, error) {
 	// Create font manager from config
 	fontManager := fonts.NewManager(appConfig.Fonts.FontsDir)
 
+	rasterizer, err := converter.NewRasterizer(converter.RasterizerOptions{
+		Backend: appConfig.Raster.Renderer,
+		Tabs:    1,
+		Fonts:   fontManager,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Generator{
+		svgBuilder: newSVGBuilder(appConfig, fontManager),
+		rasterizer: rasterizer,
+	}, nil
+}
+
+// Close releases the rasterizer of the generator
+func (g *Generator) Close() error {
+	return g.rasterizer.Close()
+}
+
+// newSVGBuilder creates the banner builder from the font and template config
+func newSVGBuilder(appConfig *config.AppConfig, fontManager fonts.Manager) banner.Builder {
 	// Use template path from config
 	templatePath := appConfig.TemplatePath
 
-	svgBuilder := banner.NewSimpleSVGBuilder(
+	return banner.NewSimpleSVGBuilder(
 		fontManager,
 		templatePath,
 		appConfig.Fonts.EnableWebFonts,
 		appConfig.Fonts.WebFontsBaseURL,
 	)
+}
 
-	return &Generator{
-		svgBuilder:   svgBuilder,
-		githubClient: github.NewClient(appConfig.GitHub.Token, 1*time.Hour), // Use 1 hour cache for CLI
-	}, nil
+// SocialPreviewURL returns the settings page where the generated banner can
+// be uploaded as social preview. Only GitHub supports social previews.
+func (g *Generator) SocialPreviewURL(repoPath string) (string, bool) {
+	forgeName, owner, repo, err := forge.ParseRepositoryPath(repoPath)
+	if err != nil || forgeName != forge.DefaultForge || g.providers == nil {
+		return "", false
+	}
+	provider, err := g.providers.Get(forgeName)
+	if err != nil {
+		return "", false
+	}
+	return provider.RepositoryURL(owner, repo) + "/settings", true
 }
 
-// GeneratePNG generates a PNG banner for the specified repository
-func (g *Generator) GeneratePNG(repoPath, outputPath string, noStats, darkMode bool) error {
-	// Parse owner/repo format
-	parts := strings.Split(repoPath, "/")
-	if len(parts) != 2 {
-		return fmt.Errorf("invalid repository format, expected owner/repo")
+// Generate generates a banner image for the specified repository, given as
+// owner/repo for GitHub or forge:owner/repo for other forges
+func (g *Generator) Generate(repoPath, outputPath string, opts ImageOptions) error {
+	if g.providers == nil {
+		return fmt.Errorf("repository data cannot be fetched in offline mode")
 	}
-	owner, repo := parts[0], parts[1]
 
-	// Set default output path
-	if outputPath == "" {
-		outputPath = "banner.png"
+	// Parse [forge:]owner/repo format
+	forgeName, owner, repo, err := forge.ParseRepositoryPath(repoPath)
+	if err != nil {
+		return err
+	}
+	provider, err := g.providers.Get(forgeName)
+	if err != nil {
+		return err
 	}
 
 	fmt.Printf("Fetching repository data for %s/%s...\n", owner, repo)
@@ -61,13 +146,29 @@ func (g *Generator) GeneratePNG(repoPath, outputPath string, noStats, darkMode b
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	repoData, err := g.githubClient.GetRepositoryData(ctx, owner, repo)
+	repoData, err := provider.GetRepositoryData(ctx, owner, repo)
 	if err != nil {
 		return fmt.Errorf("failed to fetch repository data: %w", err)
 	}
 
+	return g.GenerateFromRepository(repoData, outputPath, opts)
+}
+
+// GenerateFromRepository generates a banner image from repository data that
+// is already known, without calling any API
+func (g *Generator) GenerateFromRepository(repoData *github.Repository, outputPath string, opts ImageOptions) error {
+	format := opts.Format
+	if format == "" {
+		format = converter.FormatPNG
+	}
+
+	// Set default output path
+	if outputPath == "" {
+		outputPath = "banner." + format.Extension()
+	}
+
 	// Clear stats if --no-stats flag is set
-	if noStats {
+	if opts.NoStats {
 		repoData.StargazersCount = 0
 		repoData.ForksCount = 0
 		repoData.Language = ""
@@ -79,29 +180,56 @@ func (g *Generator) GeneratePNG(repoPath, outputPath string, noStats, darkMode b
 	}
 
 	// Generate SVG
-	svg, err := g.svgBuilder.BuildBanner(repoData)
+	svg, err := g.svgBuilder.BuildBanner(context.Background(), repoData)
 	if err != nil {
 		return fmt.Errorf("failed to generate SVG: %w", err)
 	}
 
-	// Convert SVG to PNG
+	// Convert SVG to the image format
 	colorScheme := converter.ColorSchemeLight
-	if darkMode {
+	if opts.DarkMode {
 		colorScheme = converter.ColorSchemeDark
-		fmt.Println("Converting SVG to PNG (dark mode)...")
-	} else {
-		fmt.Println("Converting SVG to PNG (light mode)...")
 	}
-	pngData, err := converter.SVGToPNGWithColorScheme([]byte(svg), colorScheme)
+	fmt.Printf("Converting SVG to %s (%s mode)...\n", strings.ToUpper(format.Extension()), colorScheme)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	imageData, err := g.rasterizer.Render(ctx, []byte(svg), converter.Options{
+		ColorScheme: colorScheme,
+		Format:      format,
+		Quality:     opts.Quality,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to convert SVG to PNG: %w", err)
+		return fmt.Errorf("failed to convert SVG to %s: %w", strings.ToUpper(format.Extension()), err)
 	}
 
-	// Save PNG file
-	if err := os.WriteFile(outputPath, pngData, 0644); err != nil {
-		return fmt.Errorf("failed to save PNG file: %w", err)
+	// Save image file
+	if err := os.WriteFile(outputPath, imageData, 0644); err != nil {
+		return fmt.Errorf("failed to save image file: %w", err)
 	}
 
 	fmt.Printf("Banner saved to: %s\n", outputPath)
 	return nil
 }
+
+// ResolveOutput returns the image format and output path for the generate
+// command. An explicit format wins; otherwise the output file's extension
+// selects it, falling back to PNG. Without an explicit output path, the
+// default file name gets the format's extension.
+func ResolveOutput(formatName, outputPath string, outputSet bool) (converter.Format, string, error) {
+	if formatName == "" {
+		format, err := converter.ParseFormat(filepath.Ext(outputPath))
+		if err != nil {
+			format = converter.FormatPNG
+		}
+		return format, outputPath, nil
+	}
+
+	format, err := converter.ParseFormat(formatName)
+	if err != nil {
+		return "", "", err
+	}
+	if !outputSet {
+		outputPath = strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + "." + format.Extension()
+	}
+	return format, outputPath, nil
+}
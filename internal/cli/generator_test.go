@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/numtide/banner-generator/internal/converter"
+)
+
+func TestResolveOutput(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     string
+		output     string
+		outputSet  bool
+		wantFormat converter.Format
+		wantOutput string
+		wantErr    bool
+	}{
+		{"defaults", "", "banner.png", false, converter.FormatPNG, "banner.png", false},
+		{"format sets default name", "webp", "banner.png", false, converter.FormatWebP, "banner.webp", false},
+		{"jpg", "jpg", "banner.png", false, converter.FormatJPEG, "banner.jpg", false},
+		{"extension selects format", "", "social.avif", true, converter.FormatAVIF, "social.avif", false},
+		{"unknown extension is png", "", "social.img", true, converter.FormatPNG, "social.img", false},
+		{"format keeps explicit name", "jpeg", "social.png", true, converter.FormatJPEG, "social.png", false},
+		{"unknown format", "bmp", "banner.png", false, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, output, err := ResolveOutput(tt.format, tt.output, tt.outputSet)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveOutput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if format != tt.wantFormat || output != tt.wantOutput {
+				t.Errorf("ResolveOutput() = %q, %q, want %q, %q", format, output, tt.wantFormat, tt.wantOutput)
+			}
+		})
+	}
+}
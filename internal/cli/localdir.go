@@ -0,0 +1,349 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/numtide/banner-generator/internal/github"
+)
+
+// flakeDescription matches the top-level description attribute of a flake.nix
+var flakeDescription = regexp.MustCompile(`(?m)^\s*description\s*=\s*"((?:[^"\\]|\\.)*)"\s*;`)
+
+// languageExtensions maps file extensions to the language names used by GitHub
+var languageExtensions = map[string]string{
+	".c":      "C",
+	".h":      "C",
+	".cc":     "C++",
+	".cpp":    "C++",
+	".cxx":    "C++",
+	".hpp":    "C++",
+	".cs":     "C#",
+	".css":    "CSS",
+	".dart":   "Dart",
+	".ex":     "Elixir",
+	".exs":    "Elixir",
+	".erl":    "Erlang",
+	".go":     "Go",
+	".hare":   "Hare",
+	".hs":     "Haskell",
+	".html":   "HTML",
+	".java":   "Java",
+	".js":     "JavaScript",
+	".jsx":    "JavaScript",
+	".mjs":    "JavaScript",
+	".kt":     "Kotlin",
+	".lua":    "Lua",
+	".nix":    "Nix",
+	".ml":     "OCaml",
+	".php":    "PHP",
+	".py":     "Python",
+	".rb":     "Ruby",
+	".rs":     "Rust",
+	".scala":  "Scala",
+	".sh":     "Shell",
+	".bash":   "Shell",
+	".svelte": "Svelte",
+	".swift":  "Swift",
+	".ts":     "TypeScript",
+	".tsx":    "TypeScript",
+	".vue":    "Vue",
+	".zig":    "Zig",
+}
+
+// skippedDirs are not counted towards the language census
+var skippedDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"target":       true,
+	"result":       true,
+	"dist":         true,
+}
+
+// RepositoryFromDir derives repository data from a local checkout. The name
+// comes from the git remote or the directory, the description from project
+// manifests or the README, and the language from a file-extension census.
+func RepositoryFromDir(dir string) (*github.Repository, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve directory: %w", err)
+	}
+	if info, err := os.Stat(absDir); err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("'%s' is not a directory", dir)
+	}
+
+	owner, name := gitRemoteRepository(absDir)
+	if name == "" {
+		name = filepath.Base(absDir)
+	}
+
+	language, err := dominantLanguage(absDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &github.Repository{
+		Name:        name,
+		Owner:       owner,
+		Description: localDescription(absDir),
+		Language:    language,
+	}, nil
+}
+
+// gitRemoteRepository returns the owner and name from the URL of the
+// "origin" remote (or the first remote), or empty strings if there is none
+func gitRemoteRepository(dir string) (owner, name string) {
+	gitDir := filepath.Join(dir, ".git")
+
+	// Worktrees and submodules use a file pointing at the real git directory
+	if data, err := os.ReadFile(gitDir); err == nil {
+		path, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+		if !ok {
+			return "", ""
+		}
+		gitDir = strings.TrimSpace(path)
+		if !filepath.IsAbs(gitDir) {
+			gitDir = filepath.Join(dir, gitDir)
+		}
+		// Worktrees keep the shared config in the common directory
+		if common, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+			gitDir = filepath.Join(gitDir, strings.TrimSpace(string(common)))
+		}
+	}
+
+	file, err := os.Open(filepath.Join(gitDir, "config"))
+	if err != nil {
+		return "", ""
+	}
+	defer func() { _ = file.Close() }()
+
+	var section, firstURL, originURL string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			section = line
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) != "url" || !strings.HasPrefix(section, "[remote ") {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if firstURL == "" {
+			firstURL = value
+		}
+		if section == `[remote "origin"]` {
+			originURL = value
+		}
+	}
+
+	remote := originURL
+	if remote == "" {
+		remote = firstURL
+	}
+	return parseRemoteURL(remote)
+}
+
+// parseRemoteURL extracts owner and name from remote URLs like
+// https://github.com/numtide/treefmt.git or git@github.com:numtide/treefmt
+func parseRemoteURL(remote string) (owner, name string) {
+	remote = strings.TrimSuffix(strings.TrimSuffix(remote, "/"), ".git")
+	if remote == "" {
+		return "", ""
+	}
+
+	// scp-like syntax separates host and path with a colon
+	if !strings.Contains(remote, "://") {
+		if _, path, ok := strings.Cut(remote, ":"); ok {
+			remote = path
+		}
+	}
+
+	parts := strings.Split(remote, "/")
+	name = parts[len(parts)-1]
+	if len(parts) > 1 {
+		owner = parts[len(parts)-2]
+	}
+	return owner, name
+}
+
+// localDescription returns the first description found in flake.nix,
+// Cargo.toml, go.mod, package.json or the README
+func localDescription(dir string) string {
+	sources := []func(string) string{
+		flakeNixDescription,
+		cargoDescription,
+		goModDescription,
+		packageJSONDescription,
+		readmeDescription,
+	}
+	for _, source := range sources {
+		if description := source(dir); description != "" {
+			return description
+		}
+	}
+	return ""
+}
+
+// flakeNixDescription reads the description attribute of flake.nix
+func flakeNixDescription(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "flake.nix"))
+	if err != nil {
+		return ""
+	}
+	match := flakeDescription.FindSubmatch(data)
+	if match == nil {
+		return ""
+	}
+	return strings.ReplaceAll(string(match[1]), `\"`, `"`)
+}
+
+// cargoDescription reads package.description from Cargo.toml
+func cargoDescription(dir string) string {
+	var manifest struct {
+		Package struct {
+			Description string `toml:"description"`
+		} `toml:"package"`
+	}
+	if _, err := toml.DecodeFile(filepath.Join(dir, "Cargo.toml"), &manifest); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(manifest.Package.Description)
+}
+
+// goModDescription reads the comment directly above the module directive
+// of go.mod, since go.mod has no description field
+func goModDescription(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+
+	var comment []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "//"):
+			comment = append(comment, strings.TrimSpace(strings.TrimPrefix(line, "//")))
+		case strings.HasPrefix(line, "module "):
+			return strings.Join(comment, " ")
+		default:
+			comment = nil
+		}
+	}
+	return ""
+}
+
+// packageJSONDescription reads the description field of package.json
+func packageJSONDescription(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return ""
+	}
+	var manifest struct {
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(manifest.Description)
+}
+
+// readmeDescription returns the first paragraph of prose in the README,
+// skipping headings, badges, images and HTML
+func readmeDescription(dir string) string {
+	var data []byte
+	for _, name := range []string{"README.md", "README", "README.txt", "README.rst", "readme.md"} {
+		var err error
+		if data, err = os.ReadFile(filepath.Join(dir, name)); err == nil {
+			break
+		}
+	}
+
+	var paragraph []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if len(paragraph) > 0 {
+				break
+			}
+			continue
+		}
+		if isReadmeDecoration(line) {
+			if len(paragraph) > 0 {
+				break
+			}
+			continue
+		}
+		paragraph = append(paragraph, line)
+	}
+	return strings.Join(paragraph, " ")
+}
+
+// isReadmeDecoration reports whether a README line is not prose
+func isReadmeDecoration(line string) bool {
+	for _, prefix := range []string{"#", "![", "[![", "<", "===", "---", "```", "..", "|"} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// dominantLanguage returns the language with the most bytes of source code
+func dominantLanguage(dir string) (string, error) {
+	sizes := make(map[string]int64)
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Skip what cannot be read, unless it is the project itself
+			if path == dir {
+				return err
+			}
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if path != dir && (strings.HasPrefix(d.Name(), ".") || skippedDirs[d.Name()]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		language, ok := languageExtensions[strings.ToLower(filepath.Ext(path))]
+		if !ok || !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		sizes[language] += info.Size()
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to scan directory: %w", err)
+	}
+
+	var dominant string
+	var largest int64
+	for language, size := range sizes {
+		// Break ties by name so the result is stable
+		if size > largest || (size == largest && language < dominant) {
+			dominant, largest = language, size
+		}
+	}
+	return dominant, nil
+}
@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFiles creates files with the given contents below dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRepositoryFromDir(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".git/config":         "[core]\n\tbare = false\n[remote \"upstream\"]\n\turl = https://example.com/fork/other.git\n[remote \"origin\"]\n\turl = git@github.com:numtide/treefmt.git\n",
+		"flake.nix":           "{\n  description = \"one CLI to format your repo\";\n  inputs = {};\n}\n",
+		"README.md":           "# treefmt\n\nNot this one.\n",
+		"main.go":             "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"formatting all the things\")\n}\n",
+		"flake-module.nix":    "{ }\n",
+		"node_modules/big.js": string(make([]byte, 4096)),
+	})
+
+	repoData, err := RepositoryFromDir(dir)
+	if err != nil {
+		t.Fatalf("RepositoryFromDir failed: %v", err)
+	}
+	if repoData.Owner != "numtide" || repoData.Name != "treefmt" {
+		t.Errorf("got %s/%s, want numtide/treefmt", repoData.Owner, repoData.Name)
+	}
+	if repoData.Description != "one CLI to format your repo" {
+		t.Errorf("got description %q", repoData.Description)
+	}
+	if repoData.Language != "Go" {
+		t.Errorf("got language %q, want Go", repoData.Language)
+	}
+}
+
+func TestRepositoryFromDirFallbacks(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "my-project")
+	writeFiles(t, dir, map[string]string{
+		"README.md": "# My Project\n\n[![CI](https://example.com/badge.svg)](https://example.com)\n\nA tool that\ndoes things.\n\nMore details.\n",
+		"lib.rs":    "fn main() {}\n",
+	})
+
+	repoData, err := RepositoryFromDir(dir)
+	if err != nil {
+		t.Fatalf("RepositoryFromDir failed: %v", err)
+	}
+	if repoData.Name != "my-project" || repoData.Owner != "" {
+		t.Errorf("got %s/%s, want my-project from the directory", repoData.Owner, repoData.Name)
+	}
+	if repoData.Description != "A tool that does things." {
+		t.Errorf("got description %q", repoData.Description)
+	}
+	if repoData.Language != "Rust" {
+		t.Errorf("got language %q, want Rust", repoData.Language)
+	}
+}
+
+func TestRepositoryFromDirSkipsUnreadable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions do not apply to root")
+	}
+	dir := filepath.Join(t.TempDir(), "project")
+	writeFiles(t, dir, map[string]string{
+		"main.go":           "package main\n",
+		"private/secret.py": "print('hidden')\n",
+	})
+	private := filepath.Join(dir, "private")
+	if err := os.Chmod(private, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chmod(private, 0755) }()
+
+	repoData, err := RepositoryFromDir(dir)
+	if err != nil {
+		t.Fatalf("RepositoryFromDir failed: %v", err)
+	}
+	if repoData.Language != "Go" {
+		t.Errorf("got language %q, want Go", repoData.Language)
+	}
+}
+
+func TestLocalDescriptionSources(t *testing.T) {
+	tests := []struct {
+		file     string
+		content  string
+		expected string
+	}{
+		{"Cargo.toml", "[package]\nname = \"x\"\ndescription = \"A Rust crate\"\n", "A Rust crate"},
+		{"go.mod", "// A Go module\nmodule example.com/x\n\ngo 1.22\n", "A Go module"},
+		{"go.mod", "// Not this one\n\nmodule example.com/x\n", ""},
+		{"package.json", `{"name":"x","description":"A JS package"}`, "A JS package"},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{tt.file: tt.content})
+		if got := localDescription(dir); got != tt.expected {
+			t.Errorf("description from %s = %q, want %q", tt.file, got, tt.expected)
+		}
+	}
+}
+
+func TestParseRemoteURL(t *testing.T) {
+	tests := []struct {
+		remote string
+		owner  string
+		name   string
+	}{
+		{"https://github.com/numtide/treefmt.git", "numtide", "treefmt"},
+		{"git@github.com:numtide/treefmt", "numtide", "treefmt"},
+		{"https://gitlab.com/group/subgroup/project/", "subgroup", "project"},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		owner, name := parseRemoteURL(tt.remote)
+		if owner != tt.owner || name != tt.name {
+			t.Errorf("parseRemoteURL(%q) = %q, %q, want %q, %q", tt.remote, owner, name, tt.owner, tt.name)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/numtide/banner-generator/internal/github"
+)
+
+// LoadMetadata reads repository data from a JSON file using the same field
+// names as the API model, e.g.:
+//
+//	{"name": "treefmt", "description": "one CLI to format your repo", "language": "Go", "stargazers_count": 1200}
+//
+// The name may be left out when it is given otherwise, e.g. with --name.
+func LoadMetadata(path string) (*github.Repository, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata file: %w", err)
+	}
+
+	var repoData github.Repository
+	if err := json.Unmarshal(data, &repoData); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata file '%s': %w", path, err)
+	}
+	return &repoData, nil
+}
@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadMetadata(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "banner.json")
+	content := `{"name":"treefmt","description":"one CLI to format your repo","language":"Go","stargazers_count":1200,"forks_count":90}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	repoData, err := LoadMetadata(path)
+	if err != nil {
+		t.Fatalf("LoadMetadata failed: %v", err)
+	}
+	if repoData.Name != "treefmt" || repoData.Language != "Go" || repoData.StargazersCount != 1200 || repoData.ForksCount != 90 {
+		t.Errorf("unexpected metadata: %+v", repoData)
+	}
+
+	unnamed := filepath.Join(dir, "unnamed.json")
+	if err := os.WriteFile(unnamed, []byte(`{"description":"nameless"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	repoData, err = LoadMetadata(unnamed)
+	if err != nil {
+		t.Fatalf("LoadMetadata without name failed: %v", err)
+	}
+	if repoData.Name != "" || repoData.Description != "nameless" {
+		t.Errorf("unexpected metadata: %+v", repoData)
+	}
+}
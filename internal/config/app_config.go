@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/BurntSushi/toml"
 )
@@ -22,11 +23,35 @@ type AppConfig struct {
 	// GitHub configuration
 	GitHub GitHubConfig `toml:"github"`
 
+	// Other forges by name, used in routes as /banner/{name}/{owner}/{repo}.svg
+	Forges map[string]ForgeConfig `toml:"forges"`
+
 	// Access control configuration
 	AccessControl AccessControlConfig `toml:"access_control"`
 
+	// Private repository banners
+	Private PrivateConfig `toml:"private"`
+
 	// Cache configuration
 	Cache CacheConfig `toml:"cache"`
+
+	// Star and fork history recording
+	History HistoryConfig `toml:"history"`
+
+	// Server-side raster images
+	Raster RasterConfig `toml:"raster"`
+
+	// Secret of signed banner URLs
+	Signing SigningConfig `toml:"signing"`
+
+	// Banners of arbitrary projects built from query parameters
+	Custom CustomConfig `toml:"custom"`
+
+	// Prometheus metrics
+	Metrics MetricsConfig `toml:"metrics"`
+
+	// Log level and format
+	Logging LoggingConfig `toml:"logging"`
 }
 
 // ServerConfig contains HTTP server settings
@@ -57,8 +82,150 @@ type FontsConfig struct {
 
 // GitHubConfig contains GitHub API settings
 type GitHubConfig struct {
+	// GitHub Enterprise Server API URL (default: api.github.com, can be
+	// overridden by GITHUB_API_URL env var)
+	BaseURL string `toml:"base_url"`
+
+	// GitHub Enterprise Server uploads URL (defaults to base_url)
+	UploadURL string `toml:"upload_url"`
+
 	// GitHub API token (can be overridden by env var)
 	Token string `toml:"token,omitempty"`
+
+	// Additional GitHub API tokens to spread requests across
+	// (can be set via comma-separated GITHUB_TOKENS env var)
+	Tokens []string `toml:"tokens,omitempty"`
+
+	// Files each containing one GitHub API token
+	TokenFiles []string `toml:"token_files"`
+
+	// Share of the rate limit, in percent, below which cached data is
+	// served instead of calling the API
+	LowQuotaPercent float64 `toml:"low_quota_percent"`
+
+	// CanonicalNames shows owner and name as GitHub spells them and
+	// redirects other spellings, renamed and transferred repositories to
+	// the canonical banner URL
+	CanonicalNames bool `toml:"canonical_names"`
+
+	// Number of top contributors to fetch with their avatars for templates
+	// with a contributors slot (0 disables fetching them)
+	Contributors int `toml:"contributors"`
+
+	// Fetch the language breakdown for templates with a language-bar slot
+	Languages bool `toml:"languages"`
+
+	// GitHub App authentication (takes precedence over token)
+	App GitHubAppConfig `toml:"app"`
+
+	// Additional hosts (e.g., GitHub Enterprise Server) serving specific owners
+	Hosts []GitHubHostConfig `toml:"hosts"`
+}
+
+// GitHubHostConfig routes a set of owners to another GitHub host
+type GitHubHostConfig struct {
+	// API URL (e.g., "https://github.example.com/api/v3/")
+	BaseURL string `toml:"base_url"`
+
+	// Uploads URL (defaults to base_url)
+	UploadURL string `toml:"upload_url"`
+
+	// API token for this host
+	Token string `toml:"token,omitempty"`
+
+	// Files each containing one API token for this host
+	TokenFiles []string `toml:"token_files"`
+
+	// Organizations and users served by this host
+	Owners []string `toml:"owners"`
+}
+
+// AllTokens returns the host's token followed by those read from token files
+func (c GitHubHostConfig) AllTokens() ([]string, error) {
+	return collectTokens([]string{c.Token}, c.TokenFiles)
+}
+
+// AllTokens returns the configured tokens followed by those read from token
+// files, without duplicates
+func (c GitHubConfig) AllTokens() ([]string, error) {
+	return collectTokens(append([]string{c.Token}, c.Tokens...), c.TokenFiles)
+}
+
+// collectTokens merges tokens with those read from files, dropping empty
+// and duplicate entries
+func collectTokens(candidates []string, files []string) ([]string, error) {
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token file: %w", err)
+		}
+		candidates = append(candidates, string(data))
+	}
+
+	var tokens []string
+	seen := make(map[string]bool)
+	for _, token := range candidates {
+		token = strings.TrimSpace(token)
+		if token == "" || seen[token] {
+			continue
+		}
+		seen[token] = true
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// GitHubAppConfig contains GitHub App authentication settings
+type GitHubAppConfig struct {
+	// GitHub App ID (can be overridden by GITHUB_APP_ID env var)
+	AppID int64 `toml:"app_id"`
+
+	// Path to the app's PEM-encoded private key
+	PrivateKeyFile string `toml:"private_key_file"`
+
+	// PEM-encoded private key (can be set via GITHUB_APP_PRIVATE_KEY env var)
+	PrivateKey string `toml:"private_key,omitempty"`
+
+	// Installation to use for all requests (optional)
+	InstallationID int64 `toml:"installation_id"`
+
+	// Account whose installation is used when the requested owner has none (optional)
+	Owner string `toml:"owner"`
+}
+
+// Enabled reports whether GitHub App authentication is configured
+func (c GitHubAppConfig) Enabled() bool {
+	return c.AppID != 0
+}
+
+// ReadPrivateKey returns the PEM-encoded private key from config or file
+func (c GitHubAppConfig) ReadPrivateKey() ([]byte, error) {
+	if c.PrivateKey != "" {
+		return []byte(c.PrivateKey), nil
+	}
+	if c.PrivateKeyFile == "" {
+		return nil, fmt.Errorf("GitHub App private key is not configured")
+	}
+
+	data, err := os.ReadFile(c.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GitHub App private key: %w", err)
+	}
+	return data, nil
+}
+
+// ForgeConfig contains settings for a non-GitHub forge
+type ForgeConfig struct {
+	// Forge type: "gitlab", "gitea" (also Forgejo), "codeberg" or
+	// "sourcehut" (defaults to the forge name)
+	Type string `toml:"type"`
+
+	// Base URL of the instance (defaults to the public instance for gitlab,
+	// codeberg and sourcehut)
+	BaseURL string `toml:"base_url"`
+
+	// API token (optional, required for sourcehut)
+	Token string `toml:"token,omitempty"`
 }
 
 // AccessControlConfig contains access control settings
@@ -66,13 +233,111 @@ type AccessControlConfig struct {
 	// Enable access control
 	Enabled bool `toml:"enabled"`
 
-	// Allowed GitHub organizations
+	// Allowed organizations ("forge:org" for forges other than GitHub)
 	AllowedOrgs []string `toml:"allowed_orgs"`
 
-	// Allowed GitHub users
+	// Allowed users ("forge:user" for forges other than GitHub)
 	AllowedUsers []string `toml:"allowed_users"`
 }
 
+// PrivateConfig contains settings for banners of private repositories
+type PrivateConfig struct {
+	// Enable the authenticated /private/banner/ routes
+	Enabled bool `toml:"enabled"`
+
+	// Credentials by owner ("forge:owner" for forges other than GitHub)
+	Owners map[string]PrivateOwnerConfig `toml:"owners"`
+}
+
+// PrivateOwnerConfig contains the credentials granting access to the private
+// repositories of one org/user
+type PrivateOwnerConfig struct {
+	// Bearer token accepted in the Authorization header
+	Token string `toml:"token,omitempty"`
+
+	// File containing the bearer token
+	TokenFile string `toml:"token_file"`
+
+	// Secret used to sign banner URLs (?owner_sig=) where headers cannot be set
+	SigningKey string `toml:"signing_key,omitempty"`
+
+	// File containing the signing secret
+	SigningKeyFile string `toml:"signing_key_file"`
+}
+
+// ReadToken returns the bearer token from config or file
+func (c PrivateOwnerConfig) ReadToken() (string, error) {
+	return readSecret(c.Token, c.TokenFile)
+}
+
+// ReadSigningKey returns the URL signing secret from config or file
+func (c PrivateOwnerConfig) ReadSigningKey() (string, error) {
+	return readSecret(c.SigningKey, c.SigningKeyFile)
+}
+
+// readSecret returns value, or the trimmed contents of path if value is empty
+func readSecret(value, path string) (string, error) {
+	if value != "" || path == "" {
+		return value, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// SigningConfig contains the secret used to sign banner URLs whose query
+// parameters change what is rendered
+type SigningConfig struct {
+	// Secret used to sign URLs (can be set via SIGNING_KEY env var)
+	Key string `toml:"key,omitempty"`
+
+	// File containing the signing secret
+	KeyFile string `toml:"key_file"`
+}
+
+// ReadKey returns the signing secret from config or file
+func (c SigningConfig) ReadKey() (string, error) {
+	return readSecret(c.Key, c.KeyFile)
+}
+
+// CustomConfig contains settings for banners of arbitrary projects
+type CustomConfig struct {
+	// Serve /banner/custom.svg?title=&description=&tag= to signed URLs
+	// (requires [signing])
+	Enabled bool `toml:"enabled"`
+}
+
+// LoggingConfig contains log output settings
+type LoggingConfig struct {
+	// Minimum level logged: "debug", "info", "warn" or "error" (can be set
+	// via LOG_LEVEL env var)
+	Level string `toml:"level"`
+
+	// Output format: "text" or "json" (can be set via LOG_FORMAT env var)
+	Format string `toml:"format"`
+}
+
+// MetricsConfig contains settings for the Prometheus /metrics endpoint
+type MetricsConfig struct {
+	// Serve /metrics in the Prometheus text format
+	Enabled bool `toml:"enabled"`
+
+	// Bearer token required to scrape /metrics (optional, can be set via
+	// METRICS_TOKEN env var)
+	Token string `toml:"token,omitempty"`
+
+	// File containing the bearer token
+	TokenFile string `toml:"token_file"`
+}
+
+// ReadToken returns the scrape token from config or file
+func (c MetricsConfig) ReadToken() (string, error) {
+	return readSecret(c.Token, c.TokenFile)
+}
+
 // CacheConfig contains cache-related settings
 type CacheConfig struct {
 	// HTTP cache duration (e.g., "1h", "30m", "300s")
@@ -80,6 +345,66 @@ type CacheConfig struct {
 
 	// API cache duration (e.g., "1h", "30m", "300s")
 	APICacheDuration string `toml:"api_cache_duration"`
+
+	// Cache backend for API responses: "memory", "disk" or "redis"
+	Backend string `toml:"backend"`
+
+	// Path to the cache database file (disk backend)
+	Path string `toml:"path"`
+
+	// Redis server URL (redis backend, can be set via REDIS_URL env var)
+	RedisURL string `toml:"redis_url,omitempty"`
+
+	// How long stale API responses are kept for revalidation (e.g., "168h")
+	Retention string `toml:"retention"`
+
+	// Memory for rendered banners in MB, reused while the template and
+	// repository data are unchanged (0 disables the render cache)
+	RenderCacheMB int `toml:"render_cache_mb"`
+}
+
+// HistoryConfig contains settings for recording star and fork history
+type HistoryConfig struct {
+	// Record a sample of the counts whenever repository data is fetched
+	Enabled bool `toml:"enabled"`
+
+	// Path to the history database file
+	Path string `toml:"path"`
+
+	// Minimum time between samples with unchanged counts (e.g., "1h")
+	Interval string `toml:"interval"`
+
+	// How much history is attached to banners and kept (e.g., "2160h")
+	Window string `toml:"window"`
+}
+
+// RasterConfig contains settings for the raster banner routes
+type RasterConfig struct {
+	// Serve /banner/{owner}/{repo}.png and the other enabled formats
+	Enabled bool `toml:"enabled"`
+
+	// Formats served: png, webp, jpg, avif and pdf (chromium renderer only)
+	Formats []string `toml:"formats"`
+
+	// Quality of lossy formats (1-100)
+	Quality int `toml:"quality"`
+
+	// Rasterizer backend: "chromium" (requires Chromium) or "go" (built in,
+	// with limited CSS support)
+	Renderer string `toml:"renderer"`
+
+	// Maximum number of banners rasterized at once
+	Concurrency int `toml:"concurrency"`
+
+	// Largest accepted ?scale= value, out of 1, 1.5, 2 and 3
+	MaxScale float64 `toml:"max_scale"`
+
+	// How long rasterized banners are cached by SVG content (e.g., "24h")
+	CacheDuration string `toml:"cache_duration"`
+
+	// Memory for rasterized banners in MB with the memory cache backend;
+	// the least recently used are evicted beyond it
+	CacheMB int `toml:"cache_mb"`
 }
 
 // LoadConfig loads configuration from a TOML file
@@ -137,7 +462,9 @@ func DefaultConfig() *AppConfig {
 		},
 		TemplatePath: "", // Required in config file
 		GitHub: GitHubConfig{
-			Token: "",
+			Token:           "",
+			TokenFiles:      []string{},
+			LowQuotaPercent: 2,
 		},
 		AccessControl: AccessControlConfig{
 			Enabled:      false,
@@ -147,6 +474,30 @@ func DefaultConfig() *AppConfig {
 		Cache: CacheConfig{
 			HTTPCacheDuration: "1h",
 			APICacheDuration:  "1h",
+			Backend:           "memory",
+			Path:              "cache/banner-generator.db",
+			Retention:         "168h",
+			RenderCacheMB:     64,
+		},
+		Logging: LoggingConfig{
+			Level:  "info",
+			Format: "text",
+		},
+		History: HistoryConfig{
+			Enabled:  false,
+			Path:     "history/banner-generator-history.db",
+			Interval: "1h",
+			Window:   "2160h",
+		},
+		Raster: RasterConfig{
+			Enabled:       false,
+			Formats:       []string{"png", "webp", "jpg"},
+			Quality:       85,
+			Renderer:      "chromium",
+			Concurrency:   2,
+			MaxScale:      3,
+			CacheDuration: "24h",
+			CacheMB:       64,
 		},
 	}
 }
@@ -165,6 +516,43 @@ func (c *AppConfig) applyEnvOverrides() {
 	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
 		c.GitHub.Token = token
 	}
+	if apiURL := os.Getenv("GITHUB_API_URL"); apiURL != "" {
+		c.GitHub.BaseURL = apiURL
+	}
+	if tokens := os.Getenv("GITHUB_TOKENS"); tokens != "" {
+		c.GitHub.Tokens = strings.Split(tokens, ",")
+	}
+	if appID := os.Getenv("GITHUB_APP_ID"); appID != "" {
+		if _, err := fmt.Sscanf(appID, "%d", &c.GitHub.App.AppID); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: invalid GITHUB_APP_ID value '%s': %v\n", appID, err)
+		}
+	}
+	if key := os.Getenv("GITHUB_APP_PRIVATE_KEY"); key != "" {
+		c.GitHub.App.PrivateKey = key
+	}
+
+	// Cache
+	if redisURL := os.Getenv("REDIS_URL"); redisURL != "" {
+		c.Cache.RedisURL = redisURL
+	}
+
+	// Signing
+	if key := os.Getenv("SIGNING_KEY"); key != "" {
+		c.Signing.Key = key
+	}
+
+	// Metrics
+	if token := os.Getenv("METRICS_TOKEN"); token != "" {
+		c.Metrics.Token = token
+	}
+
+	// Logging
+	if level := os.Getenv("LOG_LEVEL"); level != "" {
+		c.Logging.Level = level
+	}
+	if format := os.Getenv("LOG_FORMAT"); format != "" {
+		c.Logging.Format = format
+	}
 
 	// Access Control
 	if enabled := os.Getenv("ACCESS_CONTROL_ENABLED"); enabled == "true" {
@@ -182,6 +570,57 @@ func (c *AppConfig) resolvePaths(basePath string) error {
 		c.Fonts.WebFontsDir = filepath.Join(basePath, c.Fonts.WebFontsDir)
 	}
 
+	// Resolve token files
+	for i, path := range c.GitHub.TokenFiles {
+		if !filepath.IsAbs(path) {
+			c.GitHub.TokenFiles[i] = filepath.Join(basePath, path)
+		}
+	}
+
+	for i := range c.GitHub.Hosts {
+		for j, path := range c.GitHub.Hosts[i].TokenFiles {
+			if !filepath.IsAbs(path) {
+				c.GitHub.Hosts[i].TokenFiles[j] = filepath.Join(basePath, path)
+			}
+		}
+	}
+
+	// Resolve GitHub App private key path
+	if c.GitHub.App.PrivateKeyFile != "" && !filepath.IsAbs(c.GitHub.App.PrivateKeyFile) {
+		c.GitHub.App.PrivateKeyFile = filepath.Join(basePath, c.GitHub.App.PrivateKeyFile)
+	}
+
+	// Resolve private repository credential files
+	for name, owner := range c.Private.Owners {
+		if owner.TokenFile != "" && !filepath.IsAbs(owner.TokenFile) {
+			owner.TokenFile = filepath.Join(basePath, owner.TokenFile)
+		}
+		if owner.SigningKeyFile != "" && !filepath.IsAbs(owner.SigningKeyFile) {
+			owner.SigningKeyFile = filepath.Join(basePath, owner.SigningKeyFile)
+		}
+		c.Private.Owners[name] = owner
+	}
+
+	// Resolve signing key file
+	if c.Signing.KeyFile != "" && !filepath.IsAbs(c.Signing.KeyFile) {
+		c.Signing.KeyFile = filepath.Join(basePath, c.Signing.KeyFile)
+	}
+
+	// Resolve metrics token file
+	if c.Metrics.TokenFile != "" && !filepath.IsAbs(c.Metrics.TokenFile) {
+		c.Metrics.TokenFile = filepath.Join(basePath, c.Metrics.TokenFile)
+	}
+
+	// Resolve cache database path
+	if c.Cache.Path != "" && !filepath.IsAbs(c.Cache.Path) {
+		c.Cache.Path = filepath.Join(basePath, c.Cache.Path)
+	}
+
+	// Resolve history database path
+	if c.History.Path != "" && !filepath.IsAbs(c.History.Path) {
+		c.History.Path = filepath.Join(basePath, c.History.Path)
+	}
+
 	// Resolve template path
 	if c.TemplatePath != "" && !filepath.IsAbs(c.TemplatePath) {
 		c.TemplatePath = filepath.Join(basePath, c.TemplatePath)
@@ -6,12 +6,17 @@ import (
 	"time"
 )
 
+// defaultForge is the forge that allowlist entries without a prefix apply to
+const defaultForge = "github"
+
 // Config holds the server configuration
 type Config struct {
 	// AllowList contains allowed orgs/users and repos
 	// Entries can be:
 	//   - "org" or "user" to allow all repos from that org/user
 	//   - "owner/repo" to allow a specific repo
+	// Entries apply to GitHub unless prefixed with a forge name
+	// (e.g., "gitlab:org" or "codeberg:owner/repo").
 	AllowList []string
 
 	// HTTPCacheDuration is how long browsers/CDNs should cache banner images
@@ -19,6 +24,10 @@ type Config struct {
 
 	// APICacheDuration is how long to cache GitHub API responses
 	APICacheDuration time.Duration
+
+	// PrivateOwners lists the orgs/users whose private repositories may be
+	// shown to authenticated requests, in the same format as AllowList
+	PrivateOwners []string
 }
 
 // NewConfig creates a new config from a list of allowed entries
@@ -30,8 +39,31 @@ func NewConfig(allowList []string) *Config {
 	}
 }
 
-// IsAllowed checks if a repository is allowed
-func (c *Config) IsAllowed(owner, repo string) bool {
+// IsAllowed checks if a repository on the given forge may be shown. It
+// must be on the allowlist, and private repositories are only shown to
+// authenticated requests for owners listed in PrivateOwners.
+func (c *Config) IsAllowed(forge, owner, repo string, private, authenticated bool) bool {
+	if !c.IsAllowedOn(forge, owner, repo) {
+		return false
+	}
+	if !private {
+		return true
+	}
+	if !authenticated {
+		return false
+	}
+
+	for _, entry := range c.PrivateOwners {
+		if MatchesOwner(entry, forge, owner) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAllowedOn checks if a repository on the given forge is on the
+// allowlist, before its visibility is known
+func (c *Config) IsAllowedOn(forge, owner, repo string) bool {
 	// Empty allowlist means allow everything
 	if len(c.AllowList) == 0 {
 		return true
@@ -40,17 +72,41 @@ func (c *Config) IsAllowed(owner, repo string) bool {
 	fullRepo := fmt.Sprintf("%s/%s", owner, repo)
 
 	// Check each entry in the allowlist
-	for _, allowed := range c.AllowList {
+	for _, entry := range c.AllowList {
+		entryForge, allowed := splitForge(entry)
+		if !strings.EqualFold(entryForge, forge) {
+			continue
+		}
+
 		// Check if it's a specific repo match
 		if strings.EqualFold(allowed, fullRepo) {
 			return true
 		}
 
 		// Check if it's an org/user match (no slash means org/user)
-		if !strings.Contains(allowed, "/") && strings.EqualFold(allowed, owner) {
+		if !strings.Contains(allowed, "/") && MatchesOwner(entry, forge, owner) {
 			return true
 		}
 	}
 
 	return false
 }
+
+// MatchesOwner reports whether an org/user entry ("org" or "forge:org")
+// covers owner on the given forge, including nested groups on forges like
+// GitLab
+func MatchesOwner(entry, forge, owner string) bool {
+	entryForge, entryOwner := splitForge(entry)
+	if !strings.EqualFold(entryForge, forge) {
+		return false
+	}
+	return strings.EqualFold(entryOwner, owner) || strings.HasPrefix(strings.ToLower(owner), strings.ToLower(entryOwner)+"/")
+}
+
+// splitForge splits an optional "forge:" prefix off an entry
+func splitForge(entry string) (forge, rest string) {
+	if prefix, rest, ok := strings.Cut(entry, ":"); ok {
+		return prefix, rest
+	}
+	return defaultForge, entry
+}
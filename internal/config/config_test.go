@@ -0,0 +1,30 @@
+package config
+
+import "testing"
+
+func TestIsAllowed(t *testing.T) {
+	cfg := NewConfig([]string{"numtide", "gitlab:group"})
+	cfg.PrivateOwners = []string{"numtide", "gitlab:group"}
+
+	tests := []struct {
+		forge         string
+		owner         string
+		private       bool
+		authenticated bool
+		expected      bool
+	}{
+		{"github", "numtide", false, false, true},
+		{"github", "numtide", true, false, false},
+		{"github", "numtide", true, true, true},
+		{"gitlab", "group/subgroup", true, true, true},
+		{"gitlab", "numtide", true, true, false},
+		{"github", "other", false, false, false},
+	}
+
+	for _, tt := range tests {
+		if got := cfg.IsAllowed(tt.forge, tt.owner, "repo", tt.private, tt.authenticated); got != tt.expected {
+			t.Errorf("IsAllowed(%s, %s, private=%v, authenticated=%v) = %v, want %v",
+				tt.forge, tt.owner, tt.private, tt.authenticated, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package converter
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// transformFunction matches one function of a transform list
+var transformFunction = regexp.MustCompile(`([a-zA-Z]+)\s*\(([^)]*)\)`)
+
+// affine is an SVG transform matrix [a b c d e f], mapping (x, y) to
+// (a*x + c*y + e, b*x + d*y + f)
+type affine [6]float64
+
+// identity is the transform that changes nothing
+var identity = affine{1, 0, 0, 1, 0, 0}
+
+// multiply returns the transform applying n, then m
+func (m affine) multiply(n affine) affine {
+	return affine{
+		m[0]*n[0] + m[2]*n[1],
+		m[1]*n[0] + m[3]*n[1],
+		m[0]*n[2] + m[2]*n[3],
+		m[1]*n[2] + m[3]*n[3],
+		m[0]*n[4] + m[2]*n[5] + m[4],
+		m[1]*n[4] + m[3]*n[5] + m[5],
+	}
+}
+
+// apply transforms a point
+func (m affine) apply(x, y float64) (float64, float64) {
+	return m[0]*x + m[2]*y + m[4], m[1]*x + m[3]*y + m[5]
+}
+
+// scale returns the transform's average scale factor
+func (m affine) scale() float64 {
+	return math.Sqrt(math.Abs(m[0]*m[3] - m[1]*m[2]))
+}
+
+// parseTransform parses an SVG transform list. Unknown functions are
+// ignored.
+func parseTransform(value string) affine {
+	m := identity
+	for _, match := range transformFunction.FindAllStringSubmatch(value, -1) {
+		var args []float64
+		for _, field := range strings.FieldsFunc(match[2], func(r rune) bool { return r == ' ' || r == ',' }) {
+			v, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				break
+			}
+			args = append(args, v)
+		}
+		arg := func(i int, fallback float64) float64 {
+			if i < len(args) {
+				return args[i]
+			}
+			return fallback
+		}
+
+		switch strings.ToLower(match[1]) {
+		case "matrix":
+			if len(args) == 6 {
+				m = m.multiply(affine{args[0], args[1], args[2], args[3], args[4], args[5]})
+			}
+		case "translate":
+			m = m.multiply(affine{1, 0, 0, 1, arg(0, 0), arg(1, 0)})
+		case "scale":
+			sx := arg(0, 1)
+			m = m.multiply(affine{sx, 0, 0, arg(1, sx), 0, 0})
+		case "rotate":
+			angle := arg(0, 0) * math.Pi / 180
+			cx, cy := arg(1, 0), arg(2, 0)
+			sin, cos := math.Sincos(angle)
+			m = m.multiply(affine{1, 0, 0, 1, cx, cy}).
+				multiply(affine{cos, sin, -sin, cos, 0, 0}).
+				multiply(affine{1, 0, 0, 1, -cx, -cy})
+		case "skewx":
+			m = m.multiply(affine{1, 0, math.Tan(arg(0, 0) * math.Pi / 180), 1, 0, 0})
+		case "skewy":
+			m = m.multiply(affine{1, math.Tan(arg(0, 0) * math.Pi / 180), 0, 1, 0, 0})
+		}
+	}
+	return m
+}
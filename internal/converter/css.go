@@ -0,0 +1,188 @@
+package converter
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// cssComment matches CSS comments
+var cssComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+
+// cssVar matches var(--name) and var(--name, fallback) without nesting
+var cssVar = regexp.MustCompile(`var\(\s*(--[\w-]+)\s*(?:,\s*([^()]*))?\)`)
+
+// cssRule is a style rule with a single simple selector
+type cssRule struct {
+	tag, id, class string
+	root           bool
+	specificity    int
+	order          int
+	decls          map[string]string
+}
+
+// parseStylesheet returns the rules of a stylesheet that apply for the color
+// scheme. Only simple selectors (tag, #id, .class, tag.class, :root, *) are
+// supported; other rules and at-rules are ignored.
+func parseStylesheet(css string, scheme ColorScheme, rules []cssRule) []cssRule {
+	css = cssComment.ReplaceAllString(css, "")
+
+	for {
+		open := strings.IndexByte(css, '{')
+		if open < 0 {
+			return rules
+		}
+		prelude := strings.TrimSpace(css[:open])
+		body, rest := matchingBlock(css[open+1:])
+		css = rest
+
+		switch {
+		case strings.HasPrefix(prelude, "@media"):
+			if mediaMatches(prelude, scheme) {
+				rules = parseStylesheet(body, scheme, rules)
+			}
+		case strings.HasPrefix(prelude, "@"):
+			// @font-face, @import and friends do not style elements
+		default:
+			decls := parseDeclarations(body)
+			for _, selector := range strings.Split(prelude, ",") {
+				if rule, ok := parseSelector(strings.TrimSpace(selector)); ok {
+					rule.order = len(rules)
+					rule.decls = decls
+					rules = append(rules, rule)
+				}
+			}
+		}
+	}
+}
+
+// matchingBlock splits s after the brace closing an already opened block
+func matchingBlock(s string) (body, rest string) {
+	depth := 1
+	for i, c := range s {
+		switch c {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return s[:i], s[i+1:]
+			}
+		}
+	}
+	return s, ""
+}
+
+// mediaMatches reports whether a @media prelude applies to the color scheme.
+// Queries on anything but prefers-color-scheme never match.
+func mediaMatches(prelude string, scheme ColorScheme) bool {
+	query := strings.ReplaceAll(strings.ToLower(prelude), " ", "")
+	return strings.Contains(query, "prefers-color-scheme:"+string(scheme))
+}
+
+// parseDeclarations parses "name: value; ..." into a map
+func parseDeclarations(block string) map[string]string {
+	decls := make(map[string]string)
+	for _, decl := range strings.Split(block, ";") {
+		name, value, ok := strings.Cut(decl, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "!important"))
+		decls[strings.ToLower(strings.TrimSpace(name))] = value
+	}
+	return decls
+}
+
+// parseSelector parses a simple selector
+func parseSelector(selector string) (cssRule, bool) {
+	switch {
+	case selector == "":
+		return cssRule{}, false
+	case selector == ":root":
+		return cssRule{root: true, specificity: 10}, true
+	case selector == "*":
+		return cssRule{}, true
+	case strings.ContainsAny(selector, " >+~[:"):
+		// Combinators, attribute selectors and pseudo-classes are not supported
+		return cssRule{}, false
+	}
+
+	var rule cssRule
+	if id, ok := strings.CutPrefix(selector, "#"); ok {
+		rule.id, rule.specificity = id, 100
+		return rule, !strings.ContainsAny(id, "#.")
+	}
+	tag, class, hasClass := strings.Cut(selector, ".")
+	rule.tag = tag
+	if tag != "" {
+		rule.specificity = 1
+	}
+	if hasClass {
+		rule.class = class
+		rule.specificity += 10
+	}
+	return rule, !strings.ContainsAny(class, "#.")
+}
+
+// matches reports whether the rule selects an element
+func (r cssRule) matches(tag, id string, classes []string, isRoot bool) bool {
+	switch {
+	case r.root:
+		return isRoot
+	case r.id != "":
+		return r.id == id
+	case r.tag != "" && r.tag != tag:
+		return false
+	case r.class != "":
+		for _, class := range classes {
+			if class == r.class {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// matchingDeclarations returns the declarations of the rules selecting an
+// element, in cascade order (later entries win)
+func matchingDeclarations(rules []cssRule, tag, id string, classes []string, isRoot bool) []map[string]string {
+	var matched []cssRule
+	for _, rule := range rules {
+		if rule.matches(tag, id, classes, isRoot) {
+			matched = append(matched, rule)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].specificity != matched[j].specificity {
+			return matched[i].specificity < matched[j].specificity
+		}
+		return matched[i].order < matched[j].order
+	})
+
+	decls := make([]map[string]string, len(matched))
+	for i, rule := range matched {
+		decls[i] = rule.decls
+	}
+	return decls
+}
+
+// resolveVars substitutes var() references with the custom properties in
+// scope, falling back to the reference's default
+func resolveVars(value string, vars map[string]string) string {
+	// Substitute repeatedly so fallbacks may refer to other variables
+	for range 8 {
+		if !strings.Contains(value, "var(") {
+			return value
+		}
+		value = cssVar.ReplaceAllStringFunc(value, func(ref string) string {
+			match := cssVar.FindStringSubmatch(ref)
+			if v, ok := vars[match[1]]; ok {
+				return v
+			}
+			return strings.TrimSpace(match[2])
+		})
+	}
+	return value
+}
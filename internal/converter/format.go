@@ -0,0 +1,128 @@
+package converter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"strings"
+
+	"github.com/gen2brain/avif"
+	"github.com/gen2brain/webp"
+)
+
+// Format is an output format for rendered SVGs
+type Format string
+
+// Supported output formats. PDF keeps the document as vectors and is only
+// produced by the chromium backend.
+const (
+	FormatPNG  Format = "png"
+	FormatWebP Format = "webp"
+	FormatJPEG Format = "jpeg"
+	FormatAVIF Format = "avif"
+	FormatPDF  Format = "pdf"
+)
+
+// ErrPDFUnsupported is returned by rasterizers that cannot produce PDFs
+var ErrPDFUnsupported = errors.New("PDF output requires the chromium renderer")
+
+// DefaultQuality is the quality of lossy formats when none is given
+const DefaultQuality = 85
+
+// ParseFormat parses a format name or file extension, e.g. "jpg"
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(name, ".")) {
+	case "png":
+		return FormatPNG, nil
+	case "webp":
+		return FormatWebP, nil
+	case "jpg", "jpeg":
+		return FormatJPEG, nil
+	case "avif":
+		return FormatAVIF, nil
+	case "pdf":
+		return FormatPDF, nil
+	default:
+		return "", fmt.Errorf("unsupported image format '%s' (use png, webp, jpg, avif or pdf)", name)
+	}
+}
+
+// ContentType returns the media type of the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatWebP:
+		return "image/webp"
+	case FormatJPEG:
+		return "image/jpeg"
+	case FormatAVIF:
+		return "image/avif"
+	case FormatPDF:
+		return "application/pdf"
+	default:
+		return "image/png"
+	}
+}
+
+// Extension returns the usual file extension of the format, without a dot
+func (f Format) Extension() string {
+	if f == FormatJPEG {
+		return "jpg"
+	}
+	if f == "" {
+		return string(FormatPNG)
+	}
+	return string(f)
+}
+
+// encodeImage encodes an image in the format requested by opts. Lossy
+// formats use opts.Quality (1-100, default DefaultQuality).
+func encodeImage(img image.Image, opts Options) ([]byte, error) {
+	quality := opts.Quality
+	if quality <= 0 || quality > 100 {
+		quality = DefaultQuality
+	}
+
+	var buf bytes.Buffer
+	var err error
+	switch opts.Format {
+	case "", FormatPNG:
+		err = png.Encode(&buf, img)
+	case FormatWebP:
+		err = webp.Encode(&buf, img, webp.Options{Quality: quality, Method: webp.DefaultMethod})
+	case FormatJPEG:
+		// JPEG has no alpha channel; flatten onto white like a browser would
+		flat := image.NewRGBA(img.Bounds())
+		draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+		draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+		err = jpeg.Encode(&buf, flat, &jpeg.Options{Quality: quality})
+	case FormatAVIF:
+		err = avif.Encode(&buf, img, avif.Options{
+			Quality:           quality,
+			QualityAlpha:      quality,
+			Speed:             avif.DefaultSpeed,
+			ChromaSubsampling: image.YCbCrSubsampleRatio420,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported image format '%s'", opts.Format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", opts.Format.Extension(), err)
+	}
+	return buf.Bytes(), nil
+}
+
+// transcodePNG re-encodes PNG data in the format requested by opts
+func transcodePNG(pngData []byte, opts Options) ([]byte, error) {
+	if opts.Format == "" || opts.Format == FormatPNG {
+		return pngData, nil
+	}
+	img, err := png.Decode(bytes.NewReader(pngData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode PNG: %w", err)
+	}
+	return encodeImage(img, opts)
+}
@@ -0,0 +1,84 @@
+package converter
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Format
+		wantErr bool
+	}{
+		{"png", FormatPNG, false},
+		{"WEBP", FormatWebP, false},
+		{"jpg", FormatJPEG, false},
+		{".jpeg", FormatJPEG, false},
+		{"avif", FormatAVIF, false},
+		{"PDF", FormatPDF, false},
+		{"gif", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseFormat(%q) = %q, %v, want %q (error: %v)", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestEncodeImage(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 32))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.NRGBA{R: 200, G: 40, B: 90, A: 255}), image.Point{}, draw.Src)
+
+	tests := []struct {
+		format      Format
+		contentType string
+		extension   string
+		decodes     bool
+	}{
+		{FormatPNG, "image/png", "png", true},
+		{FormatWebP, "image/webp", "webp", true},
+		{FormatJPEG, "image/jpeg", "jpg", true},
+		{FormatAVIF, "image/avif", "avif", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			if got := tt.format.ContentType(); got != tt.contentType {
+				t.Errorf("ContentType() = %q, want %q", got, tt.contentType)
+			}
+			if got := tt.format.Extension(); got != tt.extension {
+				t.Errorf("Extension() = %q, want %q", got, tt.extension)
+			}
+
+			data, err := encodeImage(img, Options{Format: tt.format, Quality: 90})
+			if err != nil {
+				t.Fatalf("encodeImage failed: %v", err)
+			}
+			if !tt.decodes {
+				// AVIF files are ISO media files with an avif brand
+				if !bytes.Contains(data[:min(len(data), 32)], []byte("ftypavif")) {
+					t.Errorf("output is not an AVIF file")
+				}
+				return
+			}
+
+			decoded, format, err := image.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("output does not decode: %v", err)
+			}
+			if format != string(tt.format) {
+				t.Errorf("output decodes as %s", format)
+			}
+			if got := decoded.At(32, 16); !near(got, img.At(32, 16), 16) {
+				t.Errorf("pixel = %v, want %v", got, img.At(32, 16))
+			}
+		})
+	}
+}
@@ -0,0 +1,446 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"  // embedded images
+	_ "image/jpeg" // embedded images
+	"log/slog"
+	"math"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/numtide/banner-generator/internal/fonts"
+)
+
+// fallbackFont draws text whose families are not registered or lack glyphs
+var fallbackFont = sync.OnceValues(func() (*opentype.Font, error) {
+	return opentype.Parse(goregular.TTF)
+})
+
+// GoRasterizer converts SVGs without a browser. Shapes and gradients are
+// drawn by oksvg, text with the registered fonts. CSS support is limited to
+// simple selectors, custom properties and prefers-color-scheme; filters are
+// ignored, masks are only supported when they consist of text, and text is
+// never rotated or skewed. Characters without a glyph in any of the fonts,
+// such as the emoji star and fork icons of the default template, are left
+// blank. A GoRasterizer is safe for concurrent use.
+type GoRasterizer struct {
+	fonts fonts.Manager
+
+	// parsed caches fonts by family name; nil marks unavailable families
+	mu     sync.Mutex
+	parsed map[string]*opentype.Font
+}
+
+// NewGoRasterizer creates a GoRasterizer resolving font families with
+// fontManager, which may be nil
+func NewGoRasterizer(fontManager fonts.Manager) *GoRasterizer {
+	return &GoRasterizer{
+		fonts:  fontManager,
+		parsed: make(map[string]*opentype.Font),
+	}
+}
+
+// Render converts SVG data to an image
+func (g *GoRasterizer) Render(ctx context.Context, svgData []byte, opts Options) ([]byte, error) {
+	start := time.Now()
+	colorScheme := opts.ColorScheme
+	if colorScheme == "" {
+		colorScheme = ColorSchemeLight
+	}
+	scale := opts.Scale
+	if scale <= 0 {
+		scale = 1
+	}
+	if opts.Format == FormatPDF {
+		return nil, ErrPDFUnsupported
+	}
+
+	doc, err := prepareSVG(svgData, colorScheme)
+	if err != nil {
+		return nil, err
+	}
+	if doc.width <= 0 || doc.height <= 0 || doc.viewBox[2] <= 0 || doc.viewBox[3] <= 0 {
+		return nil, fmt.Errorf("failed to render SVG: document has no size")
+	}
+
+	// Like a browser, draw on a white page
+	width := int(math.Round(doc.width * scale))
+	height := int(math.Round(doc.height * scale))
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	root := affine{float64(width) / doc.viewBox[2], 0, 0, float64(height) / doc.viewBox[3], 0, 0}.
+		multiply(affine{1, 0, 0, 1, -doc.viewBox[0], -doc.viewBox[1]})
+	faces := &faceSet{rasterizer: g, faces: make(map[faceKey]font.Face)}
+	for _, l := range doc.layers {
+		switch {
+		case l.shapes != nil:
+			if err := faces.drawShapes(img, root, l); err != nil {
+				return nil, err
+			}
+		case l.text != nil:
+			faces.drawText(img, root, l.text)
+		case l.image != nil:
+			drawImage(ctx, img, root, l.image)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := encodeImage(img, opts)
+	if err != nil {
+		return nil, err
+	}
+	slog.DebugContext(ctx, "Rasterized banner", "renderer", BackendGo, "format", opts.Format.Extension(),
+		"scale", scale, "bytes", len(data), "duration", time.Since(start))
+	return data, nil
+}
+
+// Close is a no-op; a GoRasterizer holds no external resources
+func (g *GoRasterizer) Close() error {
+	return nil
+}
+
+// resolveFonts returns the available fonts of a font-family list, followed
+// by the fallback font
+func (g *GoRasterizer) resolveFonts(families string) []*opentype.Font {
+	var resolved []*opentype.Font
+	for _, name := range strings.Split(families, ",") {
+		if f := g.loadFont(strings.Trim(strings.TrimSpace(name), `'"`)); f != nil {
+			resolved = append(resolved, f)
+		}
+	}
+	if f, err := fallbackFont(); err == nil {
+		resolved = append(resolved, f)
+	}
+	return resolved
+}
+
+// loadFont returns a registered font family, parsing it on first use. Only
+// TrueType and OpenType files can be used.
+func (g *GoRasterizer) loadFont(name string) *opentype.Font {
+	if name == "" || g.fonts == nil {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if f, ok := g.parsed[name]; ok {
+		return f
+	}
+
+	var parsed *opentype.Font
+	if registered := g.fonts.GetFont(name); registered != nil {
+		for _, format := range []string{"ttf", "otf"} {
+			data, err := g.fonts.LoadFontData(registered.Family, format)
+			if err != nil {
+				continue
+			}
+			if parsed, err = opentype.Parse(data); err != nil {
+				slog.Warn("Failed to parse font", "font", name, "error", err)
+				continue
+			}
+			break
+		}
+	}
+	g.parsed[name] = parsed
+	return parsed
+}
+
+// faceKey identifies a font at a size in device pixels
+type faceKey struct {
+	font *opentype.Font
+	size float64
+}
+
+// faceSet holds the font faces of one rendering, since faces are not safe
+// for concurrent use
+type faceSet struct {
+	rasterizer *GoRasterizer
+	faces      map[faceKey]font.Face
+}
+
+// face returns a font at a size, or nil if it cannot be used
+func (s *faceSet) face(f *opentype.Font, size float64) font.Face {
+	key := faceKey{font: f, size: math.Round(size*64) / 64}
+	if face, ok := s.faces[key]; ok {
+		return face
+	}
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: key.size, DPI: 72, Hinting: font.HintingNone})
+	if err != nil {
+		face = nil
+	}
+	s.faces[key] = face
+	return face
+}
+
+// faceFor returns the first face with a glyph for r, or nil if none has one
+func (s *faceSet) faceFor(candidates []*opentype.Font, size float64, r rune) font.Face {
+	for _, f := range candidates {
+		face := s.face(f, size)
+		if face == nil {
+			continue
+		}
+		if _, ok := face.GlyphAdvance(r); ok {
+			return face
+		}
+	}
+	return nil
+}
+
+// drawShapes draws a layer of shapes, through its text mask if it has one
+func (s *faceSet) drawShapes(dst *image.RGBA, root affine, l layer) error {
+	icon, err := oksvg.ReadIconStream(bytes.NewReader(l.shapes), oksvg.IgnoreErrorMode)
+	if err != nil {
+		return fmt.Errorf("failed to parse SVG: %w", err)
+	}
+
+	bounds := dst.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	target := dst
+	if l.mask != nil {
+		target = image.NewRGBA(bounds)
+	}
+	icon.SetTarget(0, 0, float64(width), float64(height))
+	scanner := rasterx.NewScannerGV(width, height, target, bounds)
+	icon.Draw(rasterx.NewDasher(width, height, scanner), 1)
+	if l.mask == nil {
+		return nil
+	}
+
+	// Draw the mask's text and use its alpha or luminance as coverage
+	content := image.NewRGBA(bounds)
+	for _, block := range l.mask.texts {
+		s.drawText(content, root.multiply(l.maskMatrix), block)
+	}
+	mask := image.NewAlpha(bounds)
+	for i := range mask.Pix {
+		r, g, b, a := content.Pix[4*i], content.Pix[4*i+1], content.Pix[4*i+2], content.Pix[4*i+3]
+		if l.mask.alpha {
+			mask.Pix[i] = a
+		} else {
+			// Colors are premultiplied, so luminance includes alpha
+			mask.Pix[i] = uint8(0.2125*float64(r) + 0.7154*float64(g) + 0.0721*float64(b))
+		}
+	}
+	draw.DrawMask(dst, bounds, target, bounds.Min, mask, bounds.Min, draw.Over)
+	return nil
+}
+
+// placedRun is a text run at its position in user units
+type placedRun struct {
+	run  textRun
+	x, y float64
+}
+
+// drawText lays out a text block in chunks starting at each absolute x
+// position, aligning every chunk by its text-anchor
+func (s *faceSet) drawText(img *image.RGBA, root affine, block *textBlock) {
+	m := root.multiply(block.matrix)
+	scale := m.scale()
+	if scale == 0 {
+		return
+	}
+
+	var chunk []placedRun
+	var x, y float64
+	flush := func() {
+		if len(chunk) == 0 {
+			return
+		}
+		var shift float64
+		switch chunk[0].run.style.anchor {
+		case "middle":
+			shift = -(x - chunk[0].x) / 2
+		case "end":
+			shift = -(x - chunk[0].x)
+		}
+		for _, p := range chunk {
+			if p.run.style.visible {
+				ox, oy := m.apply(p.x+shift, p.y)
+				s.layoutRun(p.run, scale, img, ox, oy)
+			}
+		}
+		chunk = chunk[:0]
+	}
+
+	for _, run := range normalizeWhitespace(block.runs, block.preserve) {
+		if run.x != nil {
+			flush()
+			x = *run.x
+		}
+		if run.y != nil {
+			y = *run.y
+		}
+		x += run.dx
+		y += run.dy
+		chunk = append(chunk, placedRun{run: run, x: x, y: y})
+		x += s.layoutRun(run, scale, nil, 0, 0)
+	}
+	flush()
+}
+
+// layoutRun returns the advance of a run in user units. If dst is set, the
+// run is drawn with its origin at the device point (ox, oy). Characters no
+// font has a glyph for advance by 1em.
+func (s *faceSet) layoutRun(run textRun, scale float64, dst *image.RGBA, ox, oy float64) float64 {
+	size := run.style.size * scale
+	if size <= 0 || run.text == "" {
+		return 0
+	}
+	candidates := s.rasterizer.resolveFonts(run.style.family)
+	src := image.NewUniform(run.style.fill)
+
+	var dot float64
+	var prev rune
+	var prevFace font.Face
+	for _, r := range run.text {
+		face := s.faceFor(candidates, size, r)
+		if face == nil {
+			dot += size + run.style.letterSpacing*scale
+			prevFace = nil
+			continue
+		}
+		if face == prevFace {
+			dot += float64(face.Kern(prev, r)) / 64
+		}
+
+		advance, _ := face.GlyphAdvance(r)
+		if dst != nil {
+			origin := fixed.Point26_6{
+				X: fixed.Int26_6(math.Round((ox + dot) * 64)),
+				Y: fixed.Int26_6(math.Round(oy * 64)),
+			}
+			if dr, mask, maskp, _, ok := face.Glyph(origin, r); ok {
+				draw.DrawMask(dst, dr, src, image.Point{}, mask, maskp, draw.Over)
+			}
+		}
+		dot += float64(advance)/64 + run.style.letterSpacing*scale
+		prev, prevFace = r, face
+	}
+	return dot / scale
+}
+
+// normalizeWhitespace turns tabs and newlines into spaces and, unless
+// whitespace is preserved, collapses runs of spaces and trims the ends of
+// the text as a browser would
+func normalizeWhitespace(runs []textRun, preserve bool) []textRun {
+	normalized := make([]textRun, len(runs))
+	space := true // drops leading spaces
+	for i, run := range runs {
+		var b strings.Builder
+		for _, r := range run.text {
+			switch {
+			case r != ' ' && r != '\t' && r != '\n' && r != '\r':
+				b.WriteRune(r)
+				space = false
+			case preserve:
+				b.WriteByte(' ')
+			case !space:
+				b.WriteByte(' ')
+				space = true
+			}
+		}
+		run.text = b.String()
+		normalized[i] = run
+	}
+
+	if !preserve {
+		for i := len(normalized) - 1; i >= 0; i-- {
+			normalized[i].text = strings.TrimRight(normalized[i].text, " ")
+			if normalized[i].text != "" {
+				break
+			}
+		}
+	}
+	return normalized
+}
+
+// drawImage composites an embedded image, clipped to its clip circle
+func drawImage(ctx context.Context, dst *image.RGBA, root affine, overlay *imageOverlay) {
+	if overlay.width <= 0 || overlay.height <= 0 || overlay.opacity <= 0 {
+		return
+	}
+	src, err := decodeDataURI(overlay.href)
+	if err != nil {
+		slog.WarnContext(ctx, "Skipping embedded image", "error", err)
+		return
+	}
+
+	m := root.multiply(overlay.matrix)
+	x0, y0 := m.apply(overlay.x, overlay.y)
+	x1, y1 := m.apply(overlay.x+overlay.width, overlay.y+overlay.height)
+	bounds := image.Rect(int(math.Round(x0)), int(math.Round(y0)), int(math.Round(x1)), int(math.Round(y1)))
+	area := bounds.Intersect(dst.Bounds())
+	if area.Empty() {
+		return
+	}
+
+	scaled := image.NewRGBA(bounds)
+	xdraw.CatmullRom.Scale(scaled, bounds, src, src.Bounds(), xdraw.Src, nil)
+
+	var cx, cy, radius float64
+	if overlay.clip != nil {
+		cx, cy = m.apply(overlay.clip.cx, overlay.clip.cy)
+		radius = overlay.clip.r * m.scale()
+	}
+	mask := image.NewAlpha(area)
+	for py := area.Min.Y; py < area.Max.Y; py++ {
+		for px := area.Min.X; px < area.Max.X; px++ {
+			coverage := overlay.opacity
+			if overlay.clip != nil {
+				distance := math.Hypot(float64(px)+0.5-cx, float64(py)+0.5-cy)
+				coverage *= math.Max(0, math.Min(1, radius-distance+0.5))
+			}
+			mask.SetAlpha(px, py, color.Alpha{A: uint8(math.Round(coverage * 255))})
+		}
+	}
+	draw.DrawMask(dst, area, scaled, area.Min, mask, area.Min, draw.Over)
+}
+
+// decodeDataURI decodes an image from a data: URI
+func decodeDataURI(uri string) (image.Image, error) {
+	header, payload, ok := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+	if !ok {
+		return nil, fmt.Errorf("malformed data URI")
+	}
+
+	var data []byte
+	if strings.HasSuffix(header, ";base64") {
+		decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(payload), ""))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode data URI: %w", err)
+		}
+		data = decoded
+	} else {
+		decoded, err := url.PathUnescape(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode data URI: %w", err)
+		}
+		data = []byte(decoded)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode embedded image: %w", err)
+	}
+	return img, nil
+}
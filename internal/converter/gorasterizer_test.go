@@ -0,0 +1,318 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/numtide/banner-generator/internal/fonts"
+)
+
+// themedSVG uses the stylesheet features of the banner templates
+const themedSVG = `<svg width="200" height="100" viewBox="0 0 200 100" xmlns="http://www.w3.org/2000/svg">
+  <style>
+    :root { --bg: #ff0000; --fg: rgba(0, 0, 255, 0.5); }
+    @media (prefers-color-scheme: dark) { :root { --bg: #00ff00; } }
+    .box { fill: var(--fg); }
+  </style>
+  <rect width="200" height="100" fill="var(--bg)"/>
+  <rect class="box" x="100" width="100" height="50"/>
+  <rect x="0" y="50" width="100" height="50" fill="black" style="display: none"/>
+  <rect x="100" y="50" width="100" height="50" fill="url(#noise)"/>
+</svg>`
+
+// gradientSVG draws a vertical gradient defined after its use
+const gradientSVG = `<svg width="100" height="100" xmlns="http://www.w3.org/2000/svg">
+  <rect width="100" height="100" fill="url(#g)"/>
+  <defs>
+    <linearGradient id="g" x1="0" y1="0" x2="0" y2="100" gradientUnits="userSpaceOnUse">
+      <stop stop-color="#ffffff"/>
+      <stop offset="1" stop-color="#000000"/>
+    </linearGradient>
+  </defs>
+</svg>`
+
+// renderGo renders SVG data with a GoRasterizer without fonts
+func renderGo(t *testing.T, svg string, opts Options) image.Image {
+	t.Helper()
+	data, err := NewGoRasterizer(nil).Render(context.Background(), []byte(svg), opts)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Render did not return a PNG: %v", err)
+	}
+	return img
+}
+
+// near reports whether two colors differ by at most tolerance per channel
+func near(a, b color.Color, tolerance int) bool {
+	ca, cb := color.NRGBAModel.Convert(a).(color.NRGBA), color.NRGBAModel.Convert(b).(color.NRGBA)
+	for _, d := range []int{
+		int(ca.R) - int(cb.R), int(ca.G) - int(cb.G), int(ca.B) - int(cb.B), int(ca.A) - int(cb.A),
+	} {
+		if d < -tolerance || d > tolerance {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGoRasterizerStyles(t *testing.T) {
+	tests := []struct {
+		name   string
+		scheme ColorScheme
+		x, y   int
+		want   color.Color
+	}{
+		{"variable", ColorSchemeLight, 50, 25, color.NRGBA{R: 255, A: 255}},
+		{"dark variable", ColorSchemeDark, 50, 25, color.NRGBA{G: 255, A: 255}},
+		{"class with alpha color", ColorSchemeLight, 150, 25, color.NRGBA{R: 127, B: 128, A: 255}},
+		{"display none", ColorSchemeLight, 50, 75, color.NRGBA{R: 255, A: 255}},
+		{"unsupported paint", ColorSchemeLight, 150, 75, color.NRGBA{R: 255, A: 255}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := renderGo(t, themedSVG, Options{ColorScheme: tt.scheme})
+			if got := img.At(tt.x, tt.y); !near(got, tt.want, 2) {
+				t.Errorf("pixel (%d, %d) = %v, want %v", tt.x, tt.y, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoRasterizerGradient(t *testing.T) {
+	img := renderGo(t, gradientSVG, Options{})
+
+	top, middle, bottom := img.At(50, 1), img.At(50, 50), img.At(50, 98)
+	if !near(top, color.White, 8) || !near(bottom, color.Black, 8) || !near(middle, color.Gray{Y: 128}, 8) {
+		t.Errorf("gradient = %v, %v, %v, want white to black", top, middle, bottom)
+	}
+}
+
+func TestGoRasterizerScale(t *testing.T) {
+	img := renderGo(t, gradientSVG, Options{Scale: 2})
+	if size := img.Bounds().Size(); size != (image.Point{X: 200, Y: 200}) {
+		t.Errorf("size = %v, want 200x200", size)
+	}
+}
+
+func TestGoRasterizerText(t *testing.T) {
+	// inked returns the horizontal extent of dark pixels
+	inked := func(img image.Image) (minX, maxX int) {
+		minX, maxX = img.Bounds().Max.X, -1
+		for y := 0; y < img.Bounds().Max.Y; y++ {
+			for x := 0; x < img.Bounds().Max.X; x++ {
+				if r, _, _, _ := img.At(x, y).RGBA(); r < 0x8000 {
+					minX, maxX = min(minX, x), max(maxX, x)
+				}
+			}
+		}
+		return minX, maxX
+	}
+
+	tests := []struct {
+		name    string
+		text    string
+		wantMin int
+		wantMax int
+	}{
+		{"start", `<text x="10" y="40" font-size="32">  Hello  </text>`, 10, 150},
+		{"end", `<text x="190" y="40" font-size="32" text-anchor="end">Hello</text>`, 50, 190},
+		{"middle", `<text x="100" y="40" font-size="32" text-anchor="middle"><tspan>Hel</tspan><tspan>lo</tspan></text>`, 30, 170},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svg := `<svg width="200" height="60" xmlns="http://www.w3.org/2000/svg">` + tt.text + `</svg>`
+			minX, maxX := inked(renderGo(t, svg, Options{}))
+			if maxX < 0 {
+				t.Fatal("no text was drawn")
+			}
+			if minX < tt.wantMin || maxX > tt.wantMax {
+				t.Errorf("text spans x = %d..%d, want within %d..%d", minX, maxX, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestGoRasterizerInvalid(t *testing.T) {
+	r := NewGoRasterizer(nil)
+	for _, svg := range []string{"", "<svg>", `<svg xmlns="http://www.w3.org/2000/svg"><rect/></svg>`} {
+		if _, err := r.Render(context.Background(), []byte(svg), Options{}); err == nil {
+			t.Errorf("Render(%q) succeeded, want an error", svg)
+		}
+	}
+}
+
+func TestGoRasterizerPDF(t *testing.T) {
+	_, err := NewGoRasterizer(nil).Render(context.Background(), []byte(gradientSVG), Options{Format: FormatPDF})
+	if !errors.Is(err, ErrPDFUnsupported) {
+		t.Errorf("Render error = %v, want %v", err, ErrPDFUnsupported)
+	}
+}
+
+func TestSVGSize(t *testing.T) {
+	tests := []struct {
+		svg           string
+		width, height float64
+		wantErr       bool
+	}{
+		{`<svg width="1280" height="640" viewBox="0 0 640 320"/>`, 1280, 640, false},
+		{`<?xml version="1.0"?><svg viewBox="0 0 300 150"/>`, 300, 150, false},
+		{`<svg width="200px" viewBox="0 0 100 50"/>`, 200, 100, false},
+		{`<svg/>`, 0, 0, true},
+		{``, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		width, height, err := svgSize([]byte(tt.svg))
+		if (err != nil) != tt.wantErr || width != tt.width || height != tt.height {
+			t.Errorf("svgSize(%q) = %v, %v, %v, want %v, %v (error: %v)", tt.svg, width, height, err, tt.width, tt.height, tt.wantErr)
+		}
+	}
+}
+
+// shapesSVG uses the shapes and stylesheet features of the banner templates
+const shapesSVG = `<svg width="1280" height="640" viewBox="0 0 1280 640" xmlns="http://www.w3.org/2000/svg">
+  <style>
+    :root { --bg: #f5f0e8; --fg: #1d1d1b; }
+    @media (prefers-color-scheme: dark) { :root { --bg: #1d1d1b; --fg: #f5f0e8; } }
+  </style>
+  <defs>
+    <linearGradient id="accent" x1="0" y1="0" x2="1280" y2="0" gradientUnits="userSpaceOnUse">
+      <stop stop-color="#ff7bca"/>
+      <stop offset="1" stop-color="#ffdc0c"/>
+    </linearGradient>
+  </defs>
+  <rect width="1280" height="640" fill="var(--bg)"/>
+  <rect x="50" y="50" width="1180" height="120" rx="24" fill="url(#accent)"/>
+  <circle cx="640" cy="400" r="150" fill="none" stroke="var(--fg)" stroke-width="12"/>
+  <path d="M100 600 L300 300 L500 600 Z" fill="var(--fg)" fill-opacity="0.5"/>
+</svg>`
+
+// textSVG sets text in the bundled font, embedded for Chromium and
+// registered for the Go backend
+const textSVG = `<svg width="1280" height="640" viewBox="0 0 1280 640" xmlns="http://www.w3.org/2000/svg">
+  <style>
+    @font-face { font-family: 'GT Pressura'; src: url('data:font/ttf;base64,%s') format('truetype'); }
+    :root { --bg: #f5f0e8; --fg: #1d1d1b; }
+    @media (prefers-color-scheme: dark) { :root { --bg: #1d1d1b; --fg: #f5f0e8; } }
+  </style>
+  <rect width="1280" height="640" fill="var(--bg)"/>
+  <text x="50" y="200" font-family="GT Pressura" font-size="96" fill="var(--fg)">banner-generator</text>
+  <text x="50" y="320" font-family="GT Pressura" font-size="48" fill="var(--fg)">Banners for <tspan fill="#ff7bca">repositories</tspan></text>
+  <text x="50" y="580" font-family="GT Pressura" font-size="36" fill="var(--fg)">1.2k stars 45 forks</text>
+</svg>`
+
+// fontDir holds the bundled fonts
+const fontDir = "../../deploy/fonts"
+
+func TestBackendsMatch(t *testing.T) {
+	font, err := os.ReadFile(filepath.Join(fontDir, "gt-pressura-regular.ttf"))
+	if err != nil {
+		t.Fatalf("failed to read font: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		svg  string
+		// Antialiasing differs along edges, so a few pixels may differ.
+		// Glyphs are placed with different rounding, so text is compared
+		// blurred by radius pixels, with a looser tolerance per channel.
+		radius    int
+		tolerance int
+		maxShare  float64
+	}{
+		{"shapes", shapesSVG, 0, 24, 0.01},
+		{"text", fmt.Sprintf(textSVG, base64.StdEncoding.EncodeToString(font)), 2, 48, 0.001},
+	}
+
+	renderer := newTestRenderer(t, RendererOptions{Tabs: 1})
+	rasterizer := NewGoRasterizer(fonts.NewManager(fontDir))
+	for _, tt := range tests {
+		for _, scheme := range []ColorScheme{ColorSchemeLight, ColorSchemeDark} {
+			t.Run(tt.name+"/"+string(scheme), func(t *testing.T) {
+				opts := Options{ColorScheme: scheme}
+				want := blur(decodePNG(t, renderer, tt.svg, opts), tt.radius)
+				got := blur(decodePNG(t, rasterizer, tt.svg, opts), tt.radius)
+
+				if got.Bounds() != want.Bounds() {
+					t.Fatalf("size = %v, chromium size = %v", got.Bounds(), want.Bounds())
+				}
+				var differing int
+				bounds := got.Bounds()
+				for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+					for x := bounds.Min.X; x < bounds.Max.X; x++ {
+						if !near(got.At(x, y), want.At(x, y), tt.tolerance) {
+							differing++
+						}
+					}
+				}
+				if share := float64(differing) / float64(bounds.Dx()*bounds.Dy()); share > tt.maxShare {
+					t.Errorf("%.2f%% of pixels differ from chromium, want at most %.1f%%", share*100, tt.maxShare*100)
+				}
+			})
+		}
+	}
+}
+
+// blur averages every pixel of img with its neighbors up to radius pixels
+// away, using a summed-area table
+func blur(img image.Image, radius int) image.Image {
+	if radius == 0 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	sums := make([][4]int, (w+1)*(h+1))
+	at := func(x, y int) *[4]int { return &sums[y*(w+1)+x] }
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			for i, v := range [4]uint8{c.R, c.G, c.B, c.A} {
+				at(x+1, y+1)[i] = int(v) + at(x, y+1)[i] + at(x+1, y)[i] - at(x, y)[i]
+			}
+		}
+	}
+
+	blurred := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			x0, y0 := max(x-radius, 0), max(y-radius, 0)
+			x1, y1 := min(x+radius+1, w), min(y+radius+1, h)
+			n := (x1 - x0) * (y1 - y0)
+			var c [4]uint8
+			for i := range c {
+				c[i] = uint8((at(x1, y1)[i] - at(x0, y1)[i] - at(x1, y0)[i] + at(x0, y0)[i]) / n)
+			}
+			blurred.SetNRGBA(x, y, color.NRGBA{R: c[0], G: c[1], B: c[2], A: c[3]})
+		}
+	}
+	return blurred
+}
+
+// decodePNG renders svg with rasterizer and decodes the PNG
+func decodePNG(t *testing.T, rasterizer Rasterizer, svg string, opts Options) image.Image {
+	t.Helper()
+	data, err := rasterizer.Render(context.Background(), []byte(svg), opts)
+	if err != nil {
+		t.Fatalf("%T Render failed: %v", rasterizer, err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("%T did not return a PNG: %v", rasterizer, err)
+	}
+	return img
+}
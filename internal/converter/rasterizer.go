@@ -0,0 +1,49 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/numtide/banner-generator/internal/fonts"
+)
+
+// Rasterizer backend names accepted in configuration
+const (
+	BackendChromium = "chromium"
+	BackendGo       = "go"
+)
+
+// Rasterizer converts SVG documents to raster images
+type Rasterizer interface {
+	// Render converts SVG data to an image in opts.Format until ctx is done
+	Render(ctx context.Context, svgData []byte, opts Options) ([]byte, error)
+
+	// Close releases any resources held by the rasterizer
+	Close() error
+}
+
+// RasterizerOptions selects and configures a rasterizer backend
+type RasterizerOptions struct {
+	// Backend is "chromium" (default) or "go"
+	Backend string
+
+	// Tabs and MaxUses configure the chromium backend's Renderer
+	Tabs    int
+	MaxUses int
+
+	// Fonts resolves font-family names for the go backend. Without it, text
+	// is drawn in a built-in fallback font.
+	Fonts fonts.Manager
+}
+
+// NewRasterizer creates the rasterizer backend described by opts
+func NewRasterizer(opts RasterizerOptions) (Rasterizer, error) {
+	switch opts.Backend {
+	case "", BackendChromium:
+		return NewRenderer(RendererOptions{Tabs: opts.Tabs, MaxUses: opts.MaxUses}), nil
+	case BackendGo:
+		return NewGoRasterizer(opts.Fonts), nil
+	default:
+		return nil, fmt.Errorf("unknown renderer '%s'", opts.Backend)
+	}
+}
@@ -0,0 +1,349 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// Renderer defaults
+const (
+	DefaultTabs    = 2
+	DefaultMaxUses = 100
+)
+
+// ErrRendererClosed is returned by Render after Close
+var ErrRendererClosed = errors.New("renderer is closed")
+
+// RendererOptions configures NewRenderer
+type RendererOptions struct {
+	// Tabs is the maximum number of conversions at once (default 2)
+	Tabs int
+
+	// MaxUses is how many conversions a tab serves before it is replaced,
+	// which bounds memory growth in long-lived browsers (default 100)
+	MaxUses int
+}
+
+// Renderer converts SVGs with a warm headless browser, reusing a bounded
+// pool of tabs. The browser is started on first use and restarted when it
+// crashes. A Renderer is safe for concurrent use.
+type Renderer struct {
+	tabs    int
+	maxUses int
+
+	// slots bounds the number of tabs in use; idle holds reusable tabs
+	slots chan struct{}
+	idle  chan *tab
+
+	mu      sync.Mutex
+	browser *browserProcess
+	closed  bool
+}
+
+// browserProcess is one running browser
+type browserProcess struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// tab is a browser tab with the number of conversions it has served
+type tab struct {
+	browser *browserProcess
+	ctx     context.Context
+	cancel  context.CancelFunc
+	uses    int
+}
+
+// NewRenderer creates a Renderer. No browser is started until the first
+// call to Render.
+func NewRenderer(opts RendererOptions) *Renderer {
+	tabs := opts.Tabs
+	if tabs <= 0 {
+		tabs = DefaultTabs
+	}
+	maxUses := opts.MaxUses
+	if maxUses <= 0 {
+		maxUses = DefaultMaxUses
+	}
+
+	return &Renderer{
+		tabs:    tabs,
+		maxUses: maxUses,
+		slots:   make(chan struct{}, tabs),
+		idle:    make(chan *tab, tabs),
+	}
+}
+
+// Render converts SVG data to an image, waiting for a free tab until ctx is
+// done
+func (r *Renderer) Render(ctx context.Context, svgData []byte, opts Options) ([]byte, error) {
+	start := time.Now()
+	select {
+	case r.slots <- struct{}{}:
+		defer func() { <-r.slots }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	t, err := r.acquireTab()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := t.render(ctx, svgData, opts)
+	if err != nil {
+		// The tab may be in any state; start over with a fresh one
+		r.discardTab(t)
+		return nil, err
+	}
+	r.releaseTab(t)
+	if opts.Format != FormatPDF {
+		if data, err = transcodePNG(data, opts); err != nil {
+			return nil, err
+		}
+	}
+	slog.DebugContext(ctx, "Rasterized banner", "renderer", BackendChromium, "format", opts.Format.Extension(),
+		"scale", opts.Scale, "bytes", len(data), "duration", time.Since(start))
+	return data, nil
+}
+
+// Close closes the browser. Conversions in progress fail.
+func (r *Renderer) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	if r.browser != nil {
+		r.browser.cancel()
+		r.browser = nil
+	}
+	return nil
+}
+
+// acquireTab returns an idle tab of the running browser or opens a new one
+func (r *Renderer) acquireTab() (*tab, error) {
+	for {
+		select {
+		case t := <-r.idle:
+			if t.browser.alive() {
+				return t, nil
+			}
+			t.cancel()
+		default:
+			return r.openTab()
+		}
+	}
+}
+
+// releaseTab returns a tab to the pool, or closes it once it is used up
+func (r *Renderer) releaseTab(t *tab) {
+	t.uses++
+	if t.uses >= r.maxUses {
+		t.cancel()
+		return
+	}
+
+	select {
+	case r.idle <- t:
+	default:
+		t.cancel()
+	}
+}
+
+// discardTab closes a tab after a failed conversion, and forgets the
+// browser if it has crashed so the next conversion restarts it
+func (r *Renderer) discardTab(t *tab) {
+	t.cancel()
+	if t.browser.alive() {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.browser == t.browser {
+		slog.Warn("Browser exited, restarting on next conversion")
+		r.browser.cancel()
+		r.browser = nil
+	}
+}
+
+// openTab opens a new tab, starting the browser if needed
+func (r *Renderer) openTab() (*tab, error) {
+	browser, err := r.runningBrowser()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := chromedp.NewContext(browser.ctx)
+	if err := chromedp.Run(ctx); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to open browser tab: %w", err)
+	}
+	return &tab{browser: browser, ctx: ctx, cancel: cancel}, nil
+}
+
+// runningBrowser returns the running browser, starting one if there is none
+// or the previous one crashed
+func (r *Renderer) runningBrowser() (*browserProcess, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil, ErrRendererClosed
+	}
+	if r.browser != nil && r.browser.alive() {
+		return r.browser, nil
+	}
+	if r.browser != nil {
+		r.browser.cancel()
+		r.browser = nil
+	}
+
+	// Find Chrome/Chromium executable
+	chromePath := findChromePath()
+	if chromePath == "" {
+		return nil, fmt.Errorf("no Chrome/Chromium executable found. Install chromium or set CHROME_PATH")
+	}
+	slog.Info("Starting browser", "path", chromePath)
+
+	allocOpts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.ExecPath(chromePath),
+		chromedp.Flag("headless", true),
+		chromedp.Flag("disable-gpu", true),
+		chromedp.Flag("no-sandbox", true),
+		chromedp.Flag("disable-dev-shm-usage", true),
+	)
+
+	// The browser outlives any single conversion
+	allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(), allocOpts...)
+
+	// Enable verbose logging if DEBUG env var is set
+	var ctxOpts []chromedp.ContextOption
+	if os.Getenv("DEBUG") != "" {
+		ctxOpts = append(ctxOpts, chromedp.WithDebugf(log.Printf))
+	}
+
+	ctx, cancel := chromedp.NewContext(allocCtx, ctxOpts...)
+	if err := chromedp.Run(ctx); err != nil {
+		cancel()
+		allocCancel()
+		return nil, fmt.Errorf("failed to start browser: %w", err)
+	}
+
+	r.browser = &browserProcess{
+		ctx: ctx,
+		cancel: func() {
+			cancel()
+			allocCancel()
+		},
+	}
+	return r.browser, nil
+}
+
+// alive reports whether the browser is still running
+func (b *browserProcess) alive() bool {
+	select {
+	case <-b.ctx.Done():
+		return false
+	case <-chromedp.FromContext(b.ctx).Browser.LostConnection:
+		return false
+	default:
+		return true
+	}
+}
+
+// render converts SVG data to PNG, or to PDF if requested, in the tab
+func (t *tab) render(ctx context.Context, svgData []byte, opts Options) ([]byte, error) {
+	colorScheme := opts.ColorScheme
+	if colorScheme == "" {
+		colorScheme = ColorSchemeLight
+	}
+	scale := opts.Scale
+	if scale <= 0 {
+		scale = 1
+	}
+
+	// Write SVG to a temporary file (data URIs have size limits in Chrome)
+	tmpFile, err := os.CreateTemp("", "banner-*.svg")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpFileName := tmpFile.Name()
+	defer func() { _ = os.Remove(tmpFileName) }()
+
+	if _, err := tmpFile.Write(svgData); err != nil {
+		_ = tmpFile.Close()
+		return nil, fmt.Errorf("failed to write SVG to temp file: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	var data []byte
+	capture := chromedp.FullScreenshot(&data, 100)
+	if opts.Format == FormatPDF {
+		width, height, err := svgSize(svgData)
+		if err != nil {
+			return nil, err
+		}
+		capture = printToPDF(&data, width, height)
+	}
+
+	// Bound the tab's actions by the caller's context
+	runCtx, cancel := context.WithCancel(t.ctx)
+	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+
+	err = chromedp.Run(runCtx,
+		chromedp.EmulateViewport(1280, 640, chromedp.EmulateScale(scale)),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			return emulation.SetEmulatedMedia().
+				WithFeatures([]*emulation.MediaFeature{
+					{Name: "prefers-color-scheme", Value: string(colorScheme)},
+				}).
+				Do(ctx)
+		}),
+		chromedp.Navigate("file://"+tmpFileName),
+		capture,
+	)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to render SVG: %w", err)
+	}
+	return data, nil
+}
+
+// printToPDF prints the page to a single-page PDF of the given size in CSS
+// pixels, without margins. Text stays text, with its fonts embedded.
+func printToPDF(res *[]byte, width, height float64) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		data, _, err := page.PrintToPDF().
+			WithPaperWidth(width / 96).
+			WithPaperHeight(height / 96).
+			WithMarginTop(0).
+			WithMarginBottom(0).
+			WithMarginLeft(0).
+			WithMarginRight(0).
+			WithPrintBackground(true).
+			WithPageRanges("1").
+			Do(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to print PDF: %w", err)
+		}
+		*res = data
+		return nil
+	})
+}
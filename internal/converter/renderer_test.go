@@ -0,0 +1,109 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testSVG = `<svg width="1280" height="640" xmlns="http://www.w3.org/2000/svg"><rect width="1280" height="640" fill="red"/></svg>`
+
+// pngSignature starts every PNG file
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// newTestRenderer returns a Renderer, skipping the test without a browser
+// unless REQUIRE_CHROMIUM is set, as in CI
+func newTestRenderer(t *testing.T, opts RendererOptions) *Renderer {
+	t.Helper()
+	if findChromePath() == "" {
+		if os.Getenv("REQUIRE_CHROMIUM") != "" {
+			t.Fatal("no Chrome/Chromium executable found, but REQUIRE_CHROMIUM is set")
+		}
+		t.Skip("no Chrome/Chromium executable found")
+	}
+	r := NewRenderer(opts)
+	t.Cleanup(func() { _ = r.Close() })
+	return r
+}
+
+func TestRendererReusesAndRecyclesTabs(t *testing.T) {
+	r := newTestRenderer(t, RendererOptions{Tabs: 2, MaxUses: 2})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, err := r.Render(ctx, []byte(testSVG), Options{})
+			if err != nil {
+				t.Errorf("Render failed: %v", err)
+				return
+			}
+			if !bytes.HasPrefix(data, pngSignature) {
+				t.Error("Render did not return a PNG")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(r.idle) > 2 {
+		t.Errorf("%d idle tabs, want at most 2", len(r.idle))
+	}
+}
+
+func TestRendererRestartsBrowser(t *testing.T) {
+	r := newTestRenderer(t, RendererOptions{Tabs: 1})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if _, err := r.Render(ctx, []byte(testSVG), Options{}); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	// Simulate a crash by stopping the browser behind the renderer's back
+	r.mu.Lock()
+	crashed := r.browser
+	r.mu.Unlock()
+	crashed.cancel()
+
+	if _, err := r.Render(ctx, []byte(testSVG), Options{Scale: 2}); err != nil {
+		t.Fatalf("Render after crash failed: %v", err)
+	}
+	if r.browser == crashed {
+		t.Error("crashed browser was not replaced")
+	}
+}
+
+func TestRendererClosed(t *testing.T) {
+	r := NewRenderer(RendererOptions{})
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := r.Render(context.Background(), []byte(testSVG), Options{}); !errors.Is(err, ErrRendererClosed) {
+		t.Errorf("Render() error = %v, want %v", err, ErrRendererClosed)
+	}
+}
+
+func TestRendererPDF(t *testing.T) {
+	r := newTestRenderer(t, RendererOptions{Tabs: 1})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	data, err := r.Render(ctx, []byte(testSVG), Options{Format: FormatPDF})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		t.Error("Render did not return a PDF")
+	}
+	// 1280x640 CSS pixels are 960x480 points
+	if !bytes.Contains(data, []byte("/MediaBox [0 0 960 480]")) {
+		t.Error("PDF page does not match the banner size")
+	}
+}
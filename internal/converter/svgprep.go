@@ -0,0 +1,826 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/srwiley/oksvg"
+)
+
+// skippedElements are never drawn directly, or only through features the
+// go backend does not support
+var skippedElements = map[string]bool{
+	"style":         true,
+	"script":        true,
+	"title":         true,
+	"desc":          true,
+	"metadata":      true,
+	"mask":          true,
+	"clipPath":      true,
+	"pattern":       true,
+	"marker":        true,
+	"symbol":        true,
+	"foreignObject": true,
+}
+
+// shapeProperties are the styles passed on to oksvg as attributes
+var shapeProperties = []string{
+	"fill", "fill-opacity", "stroke", "stroke-width", "stroke-opacity",
+	"stroke-linecap", "stroke-linejoin", "stroke-miterlimit",
+	"stroke-dasharray", "stroke-dashoffset", "opacity", "stop-color",
+	"stop-opacity",
+}
+
+// inheritedProperties are the styles children inherit, as far as text and
+// images need them
+var inheritedProperties = []string{
+	"fill", "fill-opacity", "color", "font-family", "font-size",
+	"letter-spacing", "text-anchor", "white-space", "xml:space",
+}
+
+// preparedSVG is an SVG document split into layers, drawn in order
+type preparedSVG struct {
+	width   float64
+	height  float64
+	viewBox [4]float64
+	layers  []layer
+}
+
+// layer is a document of shapes for oksvg, possibly masked by text, a text
+// block or an image
+type layer struct {
+	shapes     []byte
+	mask       *textMask
+	maskMatrix affine
+	text       *textBlock
+	image      *imageOverlay
+
+	maskID string
+}
+
+// textMask is a mask consisting of text, in the user space of the element
+// it applies to
+type textMask struct {
+	alpha bool
+	texts []*textBlock
+}
+
+// openTag is a start tag written to the current shapes layer
+type openTag struct {
+	name, tag string
+}
+
+// textBlock is a text element laid out from its runs
+type textBlock struct {
+	matrix   affine
+	preserve bool
+	runs     []textRun
+}
+
+// textRun is a piece of text with the position changes preceding it
+type textRun struct {
+	text   string
+	x, y   *float64
+	dx, dy float64
+	style  textStyle
+}
+
+// textStyle is the computed style of a text run
+type textStyle struct {
+	fill          color.NRGBA
+	visible       bool
+	family        string
+	size          float64
+	letterSpacing float64
+	anchor        string
+}
+
+// imageOverlay is an embedded raster image
+type imageOverlay struct {
+	matrix              affine
+	opacity             float64
+	href                string
+	x, y, width, height float64
+	clip                *clipCircle
+}
+
+// clipCircle is a clipPath consisting of a single circle
+type clipCircle struct {
+	cx, cy, r float64
+}
+
+// elementState is what an element passes on to its children
+type elementState struct {
+	vars    map[string]string
+	props   map[string]string
+	matrix  affine
+	opacity float64
+}
+
+// svgPreparer walks an SVG document once to build a preparedSVG
+type svgPreparer struct {
+	rules       []cssRule
+	clipCircles map[string]clipCircle
+	gradients   map[string]bool
+	masks       map[string]*textMask
+
+	out     preparedSVG
+	root    bytes.Buffer
+	defs    bytes.Buffer
+	stack   []elementState
+	inDefs  int
+	skip    int
+	text    *textBlock
+	pending textRun
+
+	// body is the current shapes layer, which starts by reopening the
+	// elements still open at the end of the previous one
+	body       bytes.Buffer
+	bodyPrefix int
+	open       []openTag
+
+	// mask is the text mask being defined; masked is the depth of the
+	// element a mask applies to and maskedID the mask's id
+	mask      *textMask
+	maskDepth int
+	masked    int
+	maskedID  string
+}
+
+// prepareSVG resolves styles for the color scheme and splits the document
+func prepareSVG(svgData []byte, scheme ColorScheme) (*preparedSVG, error) {
+	p := &svgPreparer{
+		clipCircles: make(map[string]clipCircle),
+		gradients:   make(map[string]bool),
+		masks:       make(map[string]*textMask),
+	}
+	if err := p.collectDefinitions(svgData, scheme); err != nil {
+		return nil, err
+	}
+	if err := p.walk(svgData); err != nil {
+		return nil, err
+	}
+	p.flushShapes("", identity)
+
+	// Every shapes layer is a complete document with all definitions, since
+	// oksvg only resolves gradients defined before their use
+	for i, l := range p.out.layers {
+		if l.shapes == nil {
+			continue
+		}
+		var shapes bytes.Buffer
+		shapes.Write(p.root.Bytes())
+		shapes.Write(p.defs.Bytes())
+		shapes.Write(l.shapes)
+		shapes.WriteString("</svg>")
+		p.out.layers[i].shapes = shapes.Bytes()
+		if l.maskID != "" {
+			// Masks of anything but text are ignored, as oksvg does
+			p.out.layers[i].mask = p.masks[l.maskID]
+		}
+	}
+	return &p.out, nil
+}
+
+// collectDefinitions reads the stylesheets, gradients and clip circles,
+// which may appear after the elements they apply to
+func (p *svgPreparer) collectDefinitions(svgData []byte, scheme ColorScheme) error {
+	decoder := xml.NewDecoder(bytes.NewReader(svgData))
+	decoder.Strict = false
+
+	var inStyle bool
+	var clipID string
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to parse SVG: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "style":
+				inStyle = true
+			case "linearGradient", "radialGradient":
+				p.gradients[attr(t, "id")] = true
+			case "clipPath":
+				clipID = attr(t, "id")
+			case "circle":
+				if clipID != "" {
+					p.clipCircles[clipID] = clipCircle{
+						cx: parseLength(attr(t, "cx"), 0),
+						cy: parseLength(attr(t, "cy"), 0),
+						r:  parseLength(attr(t, "r"), 0),
+					}
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "style":
+				inStyle = false
+			case "clipPath":
+				clipID = ""
+			}
+		case xml.CharData:
+			if inStyle {
+				p.rules = parseStylesheet(string(t), scheme, p.rules)
+			}
+		}
+	}
+}
+
+// walk builds the oksvg document and the overlays
+func (p *svgPreparer) walk(svgData []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(svgData))
+	decoder.Strict = false
+
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to parse SVG: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			p.startElement(t)
+		case xml.EndElement:
+			p.endElement(t)
+		case xml.CharData:
+			if p.text != nil && p.skip == 0 {
+				run := p.pending
+				run.text = string(t)
+				run.style = p.textStyle()
+				p.text.runs = append(p.text.runs, run)
+				p.pending = textRun{}
+			}
+		}
+	}
+
+	if p.root.Len() == 0 {
+		return fmt.Errorf("failed to parse SVG: no svg element")
+	}
+	return nil
+}
+
+// startElement handles an opening tag
+func (p *svgPreparer) startElement(t xml.StartElement) {
+	if p.skip > 0 {
+		p.skip++
+		return
+	}
+
+	isRoot := len(p.stack) == 0
+	parent := elementState{props: map[string]string{}, matrix: identity, opacity: 1}
+	if !isRoot {
+		parent = p.stack[len(p.stack)-1]
+	}
+	own, state := p.computeStyle(t, parent, isRoot)
+
+	name := t.Name.Local
+	hidden := own["display"] == "none" || own["visibility"] == "hidden" || own["visibility"] == "collapse"
+	switch {
+	case (t.Name.Space != "" && t.Name.Space != "svg") || hidden:
+		// Elements of other namespaces (e.g., editor metadata) are not drawn
+		p.skip = 1
+		return
+	case name == "mask" && p.mask == nil && p.masked == 0:
+		// Mask content is drawn in the user space of the masked element
+		p.mask = &textMask{alpha: own["mask-type"] == "alpha"}
+		p.masks[attr(t, "id")] = p.mask
+		p.maskDepth = len(p.stack) + 1
+		state.matrix, state.opacity = identity, 1
+	case p.mask != nil && p.text == nil && name != "text":
+		// Only text is supported in masks
+		p.skip = 1
+		return
+	case skippedElements[name]:
+		p.skip = 1
+		return
+	case (name == "text" || name == "image") && (p.masked > 0 || (p.inDefs > 0 && p.mask == nil)):
+		// Text and images are only drawn as layers of their own
+		p.skip = 1
+		return
+	}
+	p.stack = append(p.stack, state)
+
+	switch {
+	case isRoot:
+		p.out.width = parseLength(attr(t, "width"), 0)
+		p.out.height = parseLength(attr(t, "height"), 0)
+		p.out.viewBox = parseViewBox(attr(t, "viewBox"), p.out.width, p.out.height)
+		if p.out.width == 0 || p.out.height == 0 {
+			p.out.width, p.out.height = p.out.viewBox[2], p.out.viewBox[3]
+		}
+		writeStartTag(&p.root, t, own)
+	case p.text != nil:
+		// tspan: positions apply to the next run of text
+		if v, ok := parseOptionalLength(attr(t, "x")); ok {
+			p.pending.x = &v
+		}
+		if v, ok := parseOptionalLength(attr(t, "y")); ok {
+			p.pending.y = &v
+		}
+		p.pending.dx += parseLength(attr(t, "dx"), 0)
+		p.pending.dy += parseLength(attr(t, "dy"), 0)
+	case t.Name.Local == "text":
+		p.text = &textBlock{matrix: state.matrix, preserve: p.preserveSpace()}
+		p.pending = textRun{}
+		if v, ok := parseOptionalLength(attr(t, "x")); ok {
+			p.pending.x = &v
+		}
+		if v, ok := parseOptionalLength(attr(t, "y")); ok {
+			p.pending.y = &v
+		}
+		p.pending.dx = parseLength(attr(t, "dx"), 0)
+		p.pending.dy = parseLength(attr(t, "dy"), 0)
+	case t.Name.Local == "image":
+		p.addImage(t, state)
+	case name == "mask":
+	case name == "defs":
+		p.inDefs++
+	case p.inDefs > 0:
+		writeStartTag(&p.defs, t, own)
+	default:
+		if id, ok := urlReference(own["mask"]); ok && p.masked == 0 {
+			p.flushShapes("", identity)
+			p.masked, p.maskedID = len(p.stack), id
+		}
+		var tag bytes.Buffer
+		writeStartTag(&tag, t, own)
+		p.open = append(p.open, openTag{name: qualifiedName(t.Name), tag: tag.String()})
+		p.body.Write(tag.Bytes())
+	}
+}
+
+// endElement handles a closing tag
+func (p *svgPreparer) endElement(t xml.EndElement) {
+	if p.skip > 0 {
+		p.skip--
+		return
+	}
+	if len(p.stack) == 0 {
+		return
+	}
+	depth := len(p.stack)
+	state := p.stack[depth-1]
+	p.stack = p.stack[:depth-1]
+
+	switch {
+	case depth == 1:
+		// The root is closed after the defs are hoisted
+	case t.Name.Local == "text" && p.mask != nil:
+		p.mask.texts = append(p.mask.texts, p.text)
+		p.text = nil
+	case t.Name.Local == "text":
+		p.addLayer(layer{text: p.text})
+		p.text = nil
+	case p.text != nil, t.Name.Local == "image":
+	case p.mask != nil:
+		if depth == p.maskDepth {
+			p.mask = nil
+		}
+	case t.Name.Local == "defs":
+		p.inDefs--
+	case p.inDefs > 0:
+		fmt.Fprintf(&p.defs, "</%s>", qualifiedName(t.Name))
+	default:
+		fmt.Fprintf(&p.body, "</%s>", qualifiedName(t.Name))
+		p.open = p.open[:len(p.open)-1]
+		if depth == p.masked {
+			p.flushShapes(p.maskedID, state.matrix)
+			p.masked = 0
+		}
+	}
+}
+
+// addLayer adds a text or image layer above the shapes so far
+func (p *svgPreparer) addLayer(l layer) {
+	p.flushShapes("", identity)
+	p.out.layers = append(p.out.layers, l)
+}
+
+// flushShapes ends the current shapes layer, closing the elements still
+// open and reopening them in the next layer so they keep their styles
+func (p *svgPreparer) flushShapes(maskID string, maskMatrix affine) {
+	if p.body.Len() == p.bodyPrefix {
+		return
+	}
+	for i := len(p.open) - 1; i >= 0; i-- {
+		fmt.Fprintf(&p.body, "</%s>", p.open[i].name)
+	}
+	p.out.layers = append(p.out.layers, layer{
+		shapes:     bytes.Clone(p.body.Bytes()),
+		maskID:     maskID,
+		maskMatrix: maskMatrix,
+	})
+
+	p.body.Reset()
+	for _, o := range p.open {
+		p.body.WriteString(o.tag)
+	}
+	p.bodyPrefix = p.body.Len()
+}
+
+// computeStyle cascades presentation attributes, stylesheet rules and the
+// style attribute, resolving variables and currentColor. It returns the
+// element's own shape styles and the state inherited by its children.
+func (p *svgPreparer) computeStyle(t xml.StartElement, parent elementState, isRoot bool) (map[string]string, elementState) {
+	declared := make(map[string]string)
+	var classes []string
+	for _, a := range t.Attr {
+		switch {
+		case a.Name.Space == "xml" && a.Name.Local == "space":
+			declared["xml:space"] = a.Value
+		case a.Name.Space != "":
+		case a.Name.Local == "class":
+			classes = strings.Fields(a.Value)
+		case a.Name.Local != "style":
+			declared[a.Name.Local] = a.Value
+		}
+	}
+	for _, decls := range matchingDeclarations(p.rules, t.Name.Local, attr(t, "id"), classes, isRoot) {
+		for k, v := range decls {
+			declared[k] = v
+		}
+	}
+	for k, v := range parseDeclarations(attr(t, "style")) {
+		declared[k] = v
+	}
+
+	state := elementState{
+		vars:    parent.vars,
+		props:   make(map[string]string, len(parent.props)),
+		matrix:  parent.matrix,
+		opacity: parent.opacity,
+	}
+	for k, v := range parent.props {
+		state.props[k] = v
+	}
+
+	// Custom properties are resolved first since everything may refer to them
+	copied := false
+	for k, v := range declared {
+		if strings.HasPrefix(k, "--") {
+			if !copied {
+				state.vars, copied = copyMap(parent.vars), true
+			}
+			state.vars[k] = v
+		}
+	}
+
+	own := make(map[string]string)
+	for k, v := range declared {
+		if !strings.HasPrefix(k, "--") {
+			own[k] = strings.TrimSpace(resolveVars(v, state.vars))
+		}
+	}
+
+	currentColor := parent.props["color"]
+	if c, ok := own["color"]; ok && !strings.EqualFold(c, "currentColor") {
+		currentColor = c
+	}
+	for _, k := range []string{"fill", "stroke", "stop-color"} {
+		if strings.EqualFold(own[k], "currentColor") {
+			own[k] = currentColor
+			if own[k] == "" {
+				own[k] = "black"
+			}
+		}
+		// oksvg paints references to anything but gradients (e.g., patterns)
+		// black; leave them out instead
+		if id, ok := urlReference(own[k]); ok && !p.gradients[id] {
+			own[k] = "none"
+		}
+	}
+
+	for _, k := range inheritedProperties {
+		if v, ok := own[k]; ok && v != "inherit" {
+			state.props[k] = v
+		}
+	}
+	if transform, ok := own["transform"]; ok {
+		state.matrix = state.matrix.multiply(parseTransform(transform))
+	}
+	if opacity, ok := own["opacity"]; ok {
+		state.opacity *= parseOpacity(opacity)
+	}
+	return own, state
+}
+
+// addImage records an embedded image as an overlay
+func (p *svgPreparer) addImage(t xml.StartElement, state elementState) {
+	href := attr(t, "href")
+	if !strings.HasPrefix(href, "data:") {
+		// External images are never fetched
+		return
+	}
+
+	img := &imageOverlay{
+		matrix:  state.matrix,
+		opacity: state.opacity,
+		href:    href,
+		x:       parseLength(attr(t, "x"), 0),
+		y:       parseLength(attr(t, "y"), 0),
+		width:   parseLength(attr(t, "width"), 0),
+		height:  parseLength(attr(t, "height"), 0),
+	}
+	if id, ok := urlReference(attr(t, "clip-path")); ok {
+		if circle, ok := p.clipCircles[id]; ok {
+			img.clip = &circle
+		}
+	}
+	p.addLayer(layer{image: img})
+}
+
+// textStyle returns the style of text in the current element
+func (p *svgPreparer) textStyle() textStyle {
+	state := p.stack[len(p.stack)-1]
+	size := parseLength(state.props["font-size"], 16)
+
+	style := textStyle{
+		family:        state.props["font-family"],
+		size:          size,
+		letterSpacing: parseFontRelative(state.props["letter-spacing"], size),
+		anchor:        state.props["text-anchor"],
+	}
+
+	fill := state.props["fill"]
+	if fill == "" {
+		fill = "black"
+	}
+	if c, ok := parseColor(fill); ok {
+		c.A = uint8(math.Round(float64(c.A) * parseOpacity(state.props["fill-opacity"]) * state.opacity))
+		style.fill, style.visible = c, c.A > 0
+	}
+	return style
+}
+
+// preserveSpace reports whether whitespace of the current text is kept
+func (p *svgPreparer) preserveSpace() bool {
+	props := p.stack[len(p.stack)-1].props
+	return props["xml:space"] == "preserve" || strings.HasPrefix(props["white-space"], "pre")
+}
+
+// writeStartTag writes an opening tag with the resolved shape styles in
+// place of presentation attributes, style and class
+func writeStartTag(w *bytes.Buffer, t xml.StartElement, own map[string]string) {
+	fmt.Fprintf(w, "<%s", qualifiedName(t.Name))
+	for _, a := range t.Attr {
+		if a.Name.Space == "" && (a.Name.Local == "style" || a.Name.Local == "class" || isShapeProperty(a.Name.Local)) {
+			continue
+		}
+		writeAttr(w, qualifiedName(a.Name), a.Value)
+	}
+
+	styles := make(map[string]string)
+	for _, k := range shapeProperties {
+		if v, ok := own[k]; ok {
+			styles[k] = strings.TrimSuffix(v, "px")
+		}
+	}
+
+	// Colors with alpha are split into a color and an opacity
+	for colorKey, opacityKey := range map[string]string{"fill": "fill-opacity", "stroke": "stroke-opacity", "stop-color": "stop-opacity"} {
+		v, ok := styles[colorKey]
+		if !ok {
+			continue
+		}
+		value, alpha, ok := normalizeColor(v)
+		if !ok {
+			delete(styles, colorKey)
+			continue
+		}
+		styles[colorKey] = value
+		if alpha < 1 {
+			styles[opacityKey] = strconv.FormatFloat(alpha*parseOpacity(styles[opacityKey]), 'f', -1, 64)
+		}
+	}
+
+	for _, k := range shapeProperties {
+		if v, ok := styles[k]; ok {
+			writeAttr(w, k, v)
+		}
+	}
+	w.WriteString(">")
+}
+
+// writeAttr writes an escaped attribute
+func writeAttr(w *bytes.Buffer, name, value string) {
+	fmt.Fprintf(w, ` %s="`, name)
+	_ = xml.EscapeText(w, []byte(value))
+	w.WriteString(`"`)
+}
+
+// isShapeProperty reports whether name is passed on to oksvg after cascading
+func isShapeProperty(name string) bool {
+	for _, k := range shapeProperties {
+		if k == name {
+			return true
+		}
+	}
+	return false
+}
+
+// qualifiedName returns a raw token name with its prefix
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// attr returns the value of an unprefixed attribute, or of an href in any
+// namespace (xlink:href)
+func attr(t xml.StartElement, name string) string {
+	for _, a := range t.Attr {
+		if a.Name.Local == name && (a.Name.Space == "" || name == "href") {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// urlReference returns the fragment of a url(#id) reference
+func urlReference(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "url(") || !strings.HasSuffix(value, ")") {
+		return "", false
+	}
+	ref := strings.Trim(value[4:len(value)-1], `'" `)
+	return strings.CutPrefix(ref, "#")
+}
+
+// normalizeColor converts a paint value to a form oksvg parses, returning
+// the color's alpha separately. Paint servers are passed through.
+func normalizeColor(value string) (string, float64, bool) {
+	switch v := strings.ToLower(strings.TrimSpace(value)); {
+	case v == "none", strings.HasPrefix(v, "url("):
+		return value, 1, true
+	case v == "transparent":
+		return "none", 1, true
+	}
+
+	c, ok := parseColor(value)
+	if !ok {
+		return "", 1, false
+	}
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B), float64(c.A) / 255, true
+}
+
+// parseColor parses a CSS color, including the rgba() and alpha hex forms
+// oksvg does not understand
+func parseColor(value string) (color.NRGBA, bool) {
+	v := strings.ToLower(strings.TrimSpace(value))
+	switch {
+	case v == "" || v == "none" || v == "transparent":
+		return color.NRGBA{}, false
+	case strings.HasPrefix(v, "url("):
+		// Gradients are not supported for text; approximate with black
+		return color.NRGBA{A: 255}, true
+	case strings.HasPrefix(v, "rgba(") && strings.HasSuffix(v, ")"):
+		parts := strings.Split(v[5:len(v)-1], ",")
+		if len(parts) != 4 {
+			return color.NRGBA{}, false
+		}
+		c, ok := parseColor("rgb(" + strings.Join(parts[:3], ",") + ")")
+		c.A = uint8(math.Round(parseOpacity(parts[3]) * 255))
+		return c, ok
+	case strings.HasPrefix(v, "#") && (len(v) == 5 || len(v) == 9):
+		alpha := v[len(v)-1:]
+		if len(v) == 9 {
+			alpha = v[7:]
+		} else {
+			alpha += alpha
+		}
+		a, err := strconv.ParseUint(alpha, 16, 8)
+		if err != nil {
+			return color.NRGBA{}, false
+		}
+		c, ok := parseColor(v[:len(v)-len(v[1:])/4])
+		c.A = uint8(a)
+		return c, ok
+	}
+
+	parsed, err := oksvg.ParseSVGColor(v)
+	if err != nil || parsed == nil {
+		return color.NRGBA{}, false
+	}
+	r, g, b, a := parsed.RGBA()
+	if a == 0 {
+		return color.NRGBA{}, true
+	}
+	return color.NRGBA{R: uint8(r * 0xff / a), G: uint8(g * 0xff / a), B: uint8(b * 0xff / a), A: uint8(a >> 8)}, true
+}
+
+// parseLength parses a length in user units, ignoring a px suffix
+func parseLength(value string, fallback float64) float64 {
+	if v, ok := parseOptionalLength(value); ok {
+		return v
+	}
+	return fallback
+}
+
+// parseOptionalLength parses the first length of a list, if there is one
+func parseOptionalLength(value string) (float64, bool) {
+	fields := strings.FieldsFunc(value, func(r rune) bool { return r == ' ' || r == ',' })
+	if len(fields) == 0 {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(strings.TrimSuffix(fields[0], "px"), 64)
+	return v, err == nil
+}
+
+// parseFontRelative parses a length that may be given in em
+func parseFontRelative(value string, fontSize float64) float64 {
+	if em, ok := strings.CutSuffix(strings.TrimSpace(value), "em"); ok {
+		v, _ := strconv.ParseFloat(em, 64)
+		return v * fontSize
+	}
+	return parseLength(value, 0)
+}
+
+// parseOpacity parses an opacity as a number or percentage, defaulting to 1
+func parseOpacity(value string) float64 {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 1
+	}
+	scale := 1.0
+	if v, ok := strings.CutSuffix(value, "%"); ok {
+		value, scale = v, 0.01
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 1
+	}
+	return math.Max(0, math.Min(1, v*scale))
+}
+
+// parseViewBox parses a viewBox, defaulting to the document size
+func parseViewBox(value string, width, height float64) [4]float64 {
+	fields := strings.FieldsFunc(value, func(r rune) bool { return r == ' ' || r == ',' })
+	if len(fields) != 4 {
+		return [4]float64{0, 0, width, height}
+	}
+	var box [4]float64
+	for i, f := range fields {
+		box[i], _ = strconv.ParseFloat(f, 64)
+	}
+	return box
+}
+
+// svgSize returns the size of an SVG document in CSS pixels, falling back to
+// the viewBox when width or height is missing
+func svgSize(svgData []byte) (float64, float64, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(svgData))
+	decoder.Strict = false
+
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			return 0, 0, fmt.Errorf("failed to parse SVG: no svg element")
+		} else if err != nil {
+			return 0, 0, fmt.Errorf("failed to parse SVG: %w", err)
+		}
+
+		t, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		width, height := parseLength(attr(t, "width"), 0), parseLength(attr(t, "height"), 0)
+		if box := parseViewBox(attr(t, "viewBox"), 0, 0); box[2] > 0 && box[3] > 0 {
+			// A missing dimension follows the viewBox's aspect ratio
+			switch {
+			case width == 0 && height == 0:
+				width, height = box[2], box[3]
+			case width == 0:
+				width = height * box[2] / box[3]
+			case height == 0:
+				height = width * box[3] / box[2]
+			}
+		}
+		if width <= 0 || height <= 0 {
+			return 0, 0, fmt.Errorf("failed to parse SVG: document has no size")
+		}
+		return width, height, nil
+	}
+}
+
+// copyMap returns a shallow copy of m
+func copyMap(m map[string]string) map[string]string {
+	c := make(map[string]string, len(m)+1)
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
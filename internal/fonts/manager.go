@@ -3,23 +3,45 @@ package fonts
 import (
 	"encoding/base64"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Manager handles font operations for banner generation
 type Manager interface {
 	GetFont(family string) *Font
 	GetFontData(fontPath string) (string, error)
+	LoadFontData(family, format string) ([]byte, error)
 	ServeHTTP(w http.ResponseWriter, r *http.Request)
+
+	// Refresh reads a font file again if it changed on disk
+	Refresh(fontPath string) error
+
+	// Reloads returns how often font files were read again after changing
+	Reloads() uint64
 }
 
-// DefaultManager implements Manager using a Registry
+// DefaultManager implements Manager using a Registry. Font files are kept
+// in memory and read again when they change on disk.
 type DefaultManager struct {
 	registry *Registry
 	baseDir  string
+
+	mu      sync.Mutex
+	files   map[string]*fontFile
+	reloads uint64
+}
+
+// fontFile is a font file read from disk
+type fontFile struct {
+	data    []byte
+	modTime time.Time
+	size    int64
 }
 
 // NewManager creates a new font manager
@@ -28,6 +50,7 @@ func NewManager(fontDir string) Manager {
 	return &DefaultManager{
 		registry: registry,
 		baseDir:  fontDir,
+		files:    make(map[string]*fontFile),
 	}
 }
 
@@ -38,12 +61,7 @@ func (m *DefaultManager) GetFont(family string) *Font {
 
 // GetFontData returns base64-encoded font data
 func (m *DefaultManager) GetFontData(fontPath string) (string, error) {
-	fullPath := fontPath
-	if !filepath.IsAbs(fontPath) {
-		fullPath = filepath.Join(m.baseDir, fontPath)
-	}
-
-	data, err := os.ReadFile(fullPath)
+	data, err := m.readFile(m.fullPath(fontPath))
 	if err != nil {
 		return "", fmt.Errorf("failed to read font file: %w", err)
 	}
@@ -64,6 +82,64 @@ func (m *DefaultManager) GetFontData(fontPath string) (string, error) {
 	return fmt.Sprintf("data:%s;base64,%s", mimeType, encoded), nil
 }
 
+// LoadFontData returns the raw font file of a family in the given format
+func (m *DefaultManager) LoadFontData(family, format string) ([]byte, error) {
+	fontPath, err := m.registry.GetFontPath(family, format)
+	if err != nil {
+		return nil, err
+	}
+	return m.readFile(fontPath)
+}
+
+// Refresh reads a font file again if it changed on disk, so the next
+// GetFontData call does not have to
+func (m *DefaultManager) Refresh(fontPath string) error {
+	_, err := m.readFile(m.fullPath(fontPath))
+	return err
+}
+
+// fullPath resolves fontPath against the font directory
+func (m *DefaultManager) fullPath(fontPath string) string {
+	if filepath.IsAbs(fontPath) {
+		return fontPath
+	}
+	return filepath.Join(m.baseDir, fontPath)
+}
+
+// Reloads returns how often font files were read again after changing
+func (m *DefaultManager) Reloads() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.reloads
+}
+
+// readFile returns the contents of a font file, reading it again if its
+// size or modification time changed. The data must not be modified.
+func (m *DefaultManager) readFile(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cached := m.files[path]
+	if cached != nil && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.data, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil {
+		slog.Info("Reloaded font", "font", path)
+		m.reloads++
+	}
+	m.files[path] = &fontFile{data: data, modTime: info.ModTime(), size: info.Size()}
+	return data, nil
+}
+
 // ServeHTTP implements http.Handler for serving font files
 func (m *DefaultManager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	m.registry.ServeHTTP(w, r)
@@ -0,0 +1,44 @@
+package fonts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGetFontDataReloads(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "font.ttf")
+	if err := os.WriteFile(path, []byte("first"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(dir)
+
+	for range 2 {
+		if _, err := m.GetFontData("font.ttf"); err != nil {
+			t.Fatalf("GetFontData failed: %v", err)
+		}
+	}
+	if got := m.Reloads(); got != 0 {
+		t.Errorf("got %d reloads of an unchanged font, want 0", got)
+	}
+
+	if err := os.WriteFile(path, []byte("second"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	data, err := m.GetFontData("font.ttf")
+	if err != nil {
+		t.Fatalf("GetFontData failed: %v", err)
+	}
+	if want := "data:font/ttf;base64,c2Vjb25k"; data != want {
+		t.Errorf("got %q, want %q", data, want)
+	}
+	if got := m.Reloads(); got != 1 {
+		t.Errorf("got %d reloads, want 1", got)
+	}
+}
@@ -0,0 +1,121 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/numtide/banner-generator/internal/cache"
+	"github.com/numtide/banner-generator/internal/logging"
+	"github.com/numtide/banner-generator/internal/singleflight"
+)
+
+// cachedProvider caches the repositories fetched by a provider
+type cachedProvider struct {
+	Provider
+	cache     cache.Cache
+	duration  time.Duration
+	retention time.Duration
+	fetches   singleflight.Group[*Repository]
+}
+
+// cacheEntry is the serialized form of a cached repository
+type cacheEntry struct {
+	Data      *Repository `json:"data"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// Cached wraps a provider so fetched repositories are kept in store for
+// duration, and concurrent fetches for the same repository are coalesced
+func Cached(p Provider, store cache.Cache, duration, retention time.Duration) Provider {
+	return &cachedProvider{
+		Provider:  p,
+		cache:     store,
+		duration:  duration,
+		retention: retention,
+	}
+}
+
+// GetRepositoryData returns cached repository metadata, fetching it if stale
+func (c *cachedProvider) GetRepositoryData(ctx context.Context, owner, repo string) (*Repository, error) {
+	// Owner and repository names are case-insensitive
+	cacheKey := strings.ToLower(fmt.Sprintf("repo:%s/%s/%s", c.Name(), owner, repo))
+
+	if entry := c.loadEntry(ctx, cacheKey); entry != nil && time.Since(entry.Timestamp) < c.duration {
+		logging.Add(ctx, "cache", "hit")
+		return present(entry.Data, owner, repo), nil
+	}
+
+	data, shared, err := c.fetches.Do(ctx, cacheKey, func(ctx context.Context) (*Repository, error) {
+		start := time.Now()
+		data, err := c.Provider.GetRepositoryData(ctx, owner, repo)
+		if err != nil {
+			return nil, err
+		}
+		logging.Add(ctx, "cache", "miss")
+		slog.DebugContext(ctx, "Fetched repository", "forge", c.Name(), "duration", time.Since(start))
+		now := time.Now()
+		if data.FetchedAt.IsZero() {
+			data.FetchedAt = now
+		}
+		c.storeEntry(ctx, cacheKey, &cacheEntry{Data: data, Timestamp: now})
+		return data, nil
+	})
+	if shared {
+		logging.Add(ctx, "cache", "coalesced")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return present(data, owner, repo), nil
+}
+
+// present returns a copy of data with the owner and name spelled as
+// requested, since the entry may have been fetched under another
+// capitalization
+func present(data *Repository, owner, repo string) *Repository {
+	presented := *data
+	presented.Owner = owner
+	presented.Name = repo
+	return &presented
+}
+
+// loadEntry returns the cached entry for key, or nil if there is none.
+// Cache failures are logged and treated as a miss.
+func (c *cachedProvider) loadEntry(ctx context.Context, key string) *cacheEntry {
+	raw, ok, err := c.cache.Get(ctx, key)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to read cache entry", "key", key, "error", err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(raw, &entry); err != nil || entry.Data == nil {
+		slog.WarnContext(ctx, "Discarding invalid cache entry", "key", key, "error", err)
+		return nil
+	}
+	// Entries cached before visibility was recorded would hide public
+	// repositories until they expire
+	if entry.Data.Visibility == "" {
+		return nil
+	}
+	return &entry
+}
+
+// storeEntry saves an entry in the cache. Failures are logged but not fatal.
+func (c *cachedProvider) storeEntry(ctx context.Context, key string, entry *cacheEntry) {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to encode cache entry", "key", key, "error", err)
+		return
+	}
+	if err := c.cache.Set(ctx, key, raw, c.retention); err != nil {
+		slog.WarnContext(ctx, "Failed to write cache entry", "key", key, "error", err)
+	}
+}
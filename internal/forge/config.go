@@ -0,0 +1,36 @@
+package forge
+
+import (
+	"fmt"
+
+	"github.com/numtide/banner-generator/internal/config"
+)
+
+// NewFromConfig creates the provider for a forge configured under [forges.<name>]
+func NewFromConfig(name string, cfg config.ForgeConfig) (Provider, error) {
+	if name == DefaultForge {
+		return nil, fmt.Errorf("GitHub is configured in the [github] section, not [forges.%s]", name)
+	}
+
+	kind := cfg.Type
+	if kind == "" {
+		kind = name
+	}
+
+	switch kind {
+	case "gitlab":
+		return NewGitLab(name, cfg.BaseURL, cfg.Token), nil
+	case "gitea", "forgejo":
+		return NewGitea(name, cfg.BaseURL, cfg.Token)
+	case "codeberg":
+		baseURL := cfg.BaseURL
+		if baseURL == "" {
+			baseURL = DefaultCodebergURL
+		}
+		return NewGitea(name, baseURL, cfg.Token)
+	case "sourcehut", "srht":
+		return NewSourceHut(name, cfg.BaseURL, cfg.Token)
+	default:
+		return nil, fmt.Errorf("forge '%s' has unknown type '%s'", name, kind)
+	}
+}
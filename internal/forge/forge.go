@@ -0,0 +1,177 @@
+package forge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultForge is the forge used when none is specified
+const DefaultForge = "github"
+
+// ErrRateLimited is returned when a forge's API quota is exhausted and no
+// cached data is available
+var ErrRateLimited = errors.New("API rate limit exhausted")
+
+// ErrNotFound is returned when a repository does not exist or is not visible
+var ErrNotFound = errors.New("repository not found")
+
+// Repository visibilities
+const (
+	VisibilityPublic  = "public"
+	VisibilityPrivate = "private"
+)
+
+// Repository contains forge-neutral repository information
+type Repository struct {
+	// ID is the forge's numeric repository ID, stable across renames (optional)
+	ID int64 `json:"id,omitempty"`
+
+	// FullName is the canonical owner/repo path as reported by the forge (optional)
+	FullName string `json:"full_name,omitempty"`
+
+	Name            string `json:"name"`
+	Description     string `json:"description"`
+	Owner           string `json:"owner"`
+	Language        string `json:"language"`
+	StargazersCount int    `json:"stargazers_count"`
+	ForksCount      int    `json:"forks_count"`
+
+	// Visibility is VisibilityPublic or VisibilityPrivate. Anything else
+	// counts as private; cached data without a visibility is fetched again.
+	Visibility string `json:"visibility,omitempty"`
+
+	// Contributors are the top contributors, most active first (optional)
+	Contributors []Contributor `json:"contributors,omitempty"`
+
+	// ContributorCount is the total number of contributors (optional)
+	ContributorCount int `json:"contributor_count,omitempty"`
+
+	// Languages is the language breakdown, largest first (optional)
+	Languages []LanguageShare `json:"languages,omitempty"`
+
+	// History holds recorded star and fork counts, oldest first (optional)
+	History []HistorySample `json:"history,omitempty"`
+
+	// FetchedAt is when the data was last fetched from the forge with
+	// changes (optional)
+	FetchedAt time.Time `json:"fetched_at,omitempty"`
+}
+
+// HistorySample is a star and fork count recorded at a point in time
+type HistorySample struct {
+	Time  time.Time `json:"time"`
+	Stars int       `json:"stars"`
+	Forks int       `json:"forks"`
+}
+
+// LanguageShare is a language's share of a repository's code
+type LanguageShare struct {
+	Name    string  `json:"name"`
+	Percent float64 `json:"percent"`
+}
+
+// LanguageShares converts per-language sizes (bytes or percentages) into
+// percentages, largest first
+func LanguageShares(sizes map[string]float64) []LanguageShare {
+	var total float64
+	for _, size := range sizes {
+		total += size
+	}
+	if total <= 0 {
+		return nil
+	}
+
+	shares := make([]LanguageShare, 0, len(sizes))
+	for name, size := range sizes {
+		shares = append(shares, LanguageShare{Name: name, Percent: size / total * 100})
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		// Break ties by name so the result is stable
+		if shares[i].Percent != shares[j].Percent {
+			return shares[i].Percent > shares[j].Percent
+		}
+		return shares[i].Name < shares[j].Name
+	})
+	return shares
+}
+
+// Contributor is a repository contributor shown in the contributors slot
+type Contributor struct {
+	Login string `json:"login"`
+
+	// Avatar is the downsampled avatar as a data URI (empty if unavailable)
+	Avatar string `json:"avatar,omitempty"`
+}
+
+// IsPrivate reports whether the repository must not be shown publicly
+func (r *Repository) IsPrivate() bool {
+	return r.Visibility != VisibilityPublic
+}
+
+// visibility maps a forge's visibility flag to a Repository visibility
+func visibility(public bool) string {
+	if public {
+		return VisibilityPublic
+	}
+	return VisibilityPrivate
+}
+
+// Provider fetches repository metadata from a forge
+type Provider interface {
+	// Name identifies the forge in routes and cache keys (e.g., "gitlab")
+	Name() string
+
+	// GetRepositoryData fetches repository metadata
+	GetRepositoryData(ctx context.Context, owner, repo string) (*Repository, error)
+
+	// RepositoryURL returns the web URL of a repository
+	RepositoryURL(owner, repo string) string
+}
+
+// Registry looks up providers by forge name
+type Registry struct {
+	providers map[string]Provider
+}
+
+// NewRegistry creates a registry containing the given providers
+func NewRegistry(providers ...Provider) *Registry {
+	r := &Registry{providers: make(map[string]Provider)}
+	for _, p := range providers {
+		r.Register(p)
+	}
+	return r
+}
+
+// Register adds a provider, replacing any provider with the same name
+func (r *Registry) Register(p Provider) {
+	r.providers[strings.ToLower(p.Name())] = p
+}
+
+// Get returns the provider for a forge name
+func (r *Registry) Get(name string) (Provider, error) {
+	p, ok := r.providers[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown forge '%s'", name)
+	}
+	return p, nil
+}
+
+// ParseRepositoryPath splits "forge:owner/repo" into its parts. The forge
+// prefix is optional and defaults to GitHub; the owner may contain slashes
+// (e.g., GitLab subgroups).
+func ParseRepositoryPath(path string) (forgeName, owner, repo string, err error) {
+	forgeName = DefaultForge
+	if prefix, rest, ok := strings.Cut(path, ":"); ok {
+		forgeName, path = prefix, rest
+	}
+
+	i := strings.LastIndex(path, "/")
+	if i <= 0 || i == len(path)-1 {
+		return "", "", "", fmt.Errorf("invalid repository format, expected [forge:]owner/repo")
+	}
+	return forgeName, path[:i], path[i+1:], nil
+}
@@ -0,0 +1,100 @@
+package forge
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/numtide/banner-generator/internal/cache"
+)
+
+func TestParseRepositoryPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		forge   string
+		owner   string
+		repo    string
+		wantErr bool
+	}{
+		{"numtide/treefmt", "github", "numtide", "treefmt", false},
+		{"gitlab:group/project", "gitlab", "group", "project", false},
+		{"gitlab:group/subgroup/project", "gitlab", "group/subgroup", "project", false},
+		{"codeberg:forgejo/forgejo", "codeberg", "forgejo", "forgejo", false},
+		{"treefmt", "", "", "", true},
+		{"gitlab:group/", "", "", "", true},
+		{"/repo", "", "", "", true},
+	}
+
+	for _, tt := range tests {
+		forgeName, owner, repo, err := ParseRepositoryPath(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRepositoryPath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if forgeName != tt.forge || owner != tt.owner || repo != tt.repo {
+			t.Errorf("ParseRepositoryPath(%q) = %q, %q, %q", tt.path, forgeName, owner, repo)
+		}
+	}
+}
+
+// countingProvider returns a fixed repository and counts fetches
+type countingProvider struct {
+	fetches atomic.Int32
+}
+
+func (p *countingProvider) Name() string { return "test" }
+
+func (p *countingProvider) GetRepositoryData(ctx context.Context, owner, repo string) (*Repository, error) {
+	p.fetches.Add(1)
+	return &Repository{Name: repo, Owner: owner, Visibility: VisibilityPublic}, nil
+}
+
+func (p *countingProvider) RepositoryURL(owner, repo string) string {
+	return "https://example.com/" + owner + "/" + repo
+}
+
+func TestCachedProvider(t *testing.T) {
+	inner := &countingProvider{}
+	p := Cached(inner, cache.NewMemory(), time.Hour, time.Hour)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		data, err := p.GetRepositoryData(ctx, "owner", "repo")
+		if err != nil {
+			t.Fatalf("GetRepositoryData failed: %v", err)
+		}
+		if data.Name != "repo" {
+			t.Errorf("got name %q", data.Name)
+		}
+	}
+	if _, err := p.GetRepositoryData(ctx, "owner", "other"); err != nil {
+		t.Fatalf("GetRepositoryData failed: %v", err)
+	}
+
+	// Other capitalizations share the entry but keep their spelling
+	data, err := p.GetRepositoryData(ctx, "Owner", "Repo")
+	if err != nil {
+		t.Fatalf("GetRepositoryData failed: %v", err)
+	}
+	if data.Owner != "Owner" || data.Name != "Repo" {
+		t.Errorf("got %s/%s, want Owner/Repo", data.Owner, data.Name)
+	}
+
+	if got := inner.fetches.Load(); got != 2 {
+		t.Errorf("fetched %d times, want 2", got)
+	}
+	if p.Name() != "test" {
+		t.Errorf("cached provider renamed to %q", p.Name())
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry(&countingProvider{})
+	if _, err := r.Get("TEST"); err != nil {
+		t.Errorf("lookup should be case-insensitive: %v", err)
+	}
+	if _, err := r.Get("gitlab"); err == nil {
+		t.Error("expected error for unknown forge")
+	}
+}
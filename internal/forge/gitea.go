@@ -0,0 +1,77 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultCodebergURL is the public Forgejo instance run by Codeberg
+const DefaultCodebergURL = "https://codeberg.org"
+
+// Gitea fetches repositories from a Gitea or Forgejo instance (e.g., Codeberg)
+type Gitea struct {
+	name    string
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// NewGitea creates a Gitea/Forgejo provider for the instance at baseURL
+func NewGitea(name, baseURL, token string) (*Gitea, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("forge '%s' requires a base_url", name)
+	}
+	return &Gitea{
+		name:    name,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		client:  defaultHTTPClient,
+	}, nil
+}
+
+// Name identifies the forge
+func (g *Gitea) Name() string {
+	return g.name
+}
+
+// GetRepositoryData fetches repository metadata
+func (g *Gitea) GetRepositoryData(ctx context.Context, owner, repo string) (*Repository, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/v1/repos/%s/%s", g.baseURL, url.PathEscape(owner), url.PathEscape(repo)), nil)
+	if err != nil {
+		return nil, err
+	}
+	if g.token != "" {
+		req.Header.Set("Authorization", "token "+g.token)
+	}
+
+	var repository struct {
+		Description string `json:"description"`
+		Language    string `json:"language"`
+		StarsCount  int    `json:"stars_count"`
+		ForksCount  int    `json:"forks_count"`
+		Private     bool   `json:"private"`
+		Internal    bool   `json:"internal"`
+	}
+	if err := doJSON(ctx, g.client, req, &repository); err != nil {
+		return nil, fmt.Errorf("failed to fetch repository: %w", err)
+	}
+
+	// Use the original repo name as provided by the user to preserve capitalization
+	return &Repository{
+		Name:            repo,
+		Description:     repository.Description,
+		Owner:           owner,
+		Language:        repository.Language,
+		StargazersCount: repository.StarsCount,
+		ForksCount:      repository.ForksCount,
+		Visibility:      visibility(!repository.Private && !repository.Internal),
+	}, nil
+}
+
+// RepositoryURL returns the web URL of a repository
+func (g *Gitea) RepositoryURL(owner, repo string) string {
+	return fmt.Sprintf("%s/%s/%s", g.baseURL, owner, repo)
+}
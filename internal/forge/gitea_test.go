@@ -0,0 +1,64 @@
+package forge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestGiteaGetRepositoryData(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/repos/forgejo/forgejo":
+			if r.Header.Get("Authorization") != "token secret" {
+				t.Errorf("missing token on %s", r.URL.Path)
+			}
+			fmt.Fprint(w, `{"description":"Beyond coding. We forge.","language":"Go","stars_count":3000,"forks_count":500}`)
+		case "/api/v1/repos/forgejo/busy":
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	g, err := NewGitea("codeberg", srv.URL+"/", "secret")
+	if err != nil {
+		t.Fatalf("NewGitea failed: %v", err)
+	}
+
+	data, err := g.GetRepositoryData(context.Background(), "forgejo", "forgejo")
+	if err != nil {
+		t.Fatalf("GetRepositoryData failed: %v", err)
+	}
+
+	expected := Repository{
+		Name:            "forgejo",
+		Description:     "Beyond coding. We forge.",
+		Owner:           "forgejo",
+		Language:        "Go",
+		StargazersCount: 3000,
+		ForksCount:      500,
+		Visibility:      VisibilityPublic,
+	}
+	if !reflect.DeepEqual(*data, expected) {
+		t.Errorf("got %+v, want %+v", *data, expected)
+	}
+
+	if _, err := g.GetRepositoryData(context.Background(), "forgejo", "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := g.GetRepositoryData(context.Background(), "forgejo", "busy"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected ErrRateLimited, got %v", err)
+	}
+}
+
+func TestNewGiteaRequiresBaseURL(t *testing.T) {
+	if _, err := NewGitea("gitea", "", ""); err == nil {
+		t.Error("expected error without base URL")
+	}
+}
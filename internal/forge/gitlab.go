@@ -0,0 +1,105 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultGitLabURL is the public GitLab instance
+const DefaultGitLabURL = "https://gitlab.com"
+
+// GitLab fetches repositories from a GitLab instance
+type GitLab struct {
+	name    string
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// NewGitLab creates a GitLab provider. An empty baseURL means gitlab.com.
+func NewGitLab(name, baseURL, token string) *GitLab {
+	if baseURL == "" {
+		baseURL = DefaultGitLabURL
+	}
+	return &GitLab{
+		name:    name,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		client:  defaultHTTPClient,
+	}
+}
+
+// Name identifies the forge
+func (g *GitLab) Name() string {
+	return g.name
+}
+
+// GetRepositoryData fetches project metadata. The owner may be a nested
+// group path (e.g., "group/subgroup").
+func (g *GitLab) GetRepositoryData(ctx context.Context, owner, repo string) (*Repository, error) {
+	projectPath := url.PathEscape(owner + "/" + repo)
+
+	var project struct {
+		Description string `json:"description"`
+		StarCount   int    `json:"star_count"`
+		ForksCount  int    `json:"forks_count"`
+		Visibility  string `json:"visibility"`
+	}
+	if err := g.get(ctx, "projects/"+projectPath, &project); err != nil {
+		return nil, fmt.Errorf("failed to fetch project: %w", err)
+	}
+
+	// Languages are reported as percentages; the largest is the primary one
+	var languages map[string]float64
+	if err := g.get(ctx, "projects/"+projectPath+"/languages", &languages); err != nil {
+		// Languages are decoration; show the banner without them
+		slog.WarnContext(ctx, "Failed to fetch languages", "forge", g.name, "error", err)
+		languages = nil
+	}
+
+	// Use the original repo name as provided by the user to preserve capitalization
+	return &Repository{
+		Name:            repo,
+		Description:     project.Description,
+		Owner:           owner,
+		Language:        primaryLanguage(languages),
+		Languages:       LanguageShares(languages),
+		StargazersCount: project.StarCount,
+		ForksCount:      project.ForksCount,
+		Visibility:      visibility(project.Visibility == "public"),
+	}, nil
+}
+
+// RepositoryURL returns the web URL of a project
+func (g *GitLab) RepositoryURL(owner, repo string) string {
+	return fmt.Sprintf("%s/%s/%s", g.baseURL, owner, repo)
+}
+
+// get calls a GitLab REST API v4 endpoint
+func (g *GitLab) get(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, g.baseURL+"/api/v4/"+path, nil)
+	if err != nil {
+		return err
+	}
+	if g.token != "" {
+		req.Header.Set("PRIVATE-TOKEN", g.token)
+	}
+	return doJSON(ctx, g.client, req, out)
+}
+
+// primaryLanguage returns the language with the largest share
+func primaryLanguage(languages map[string]float64) string {
+	var primary string
+	var largest float64
+	for language, share := range languages {
+		// Break ties by name so the result is stable
+		if share > largest || (share == largest && language < primary) {
+			primary, largest = language, share
+		}
+	}
+	return primary
+}
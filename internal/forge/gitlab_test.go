@@ -0,0 +1,92 @@
+package forge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestGitLabGetRepositoryData(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "glpat-test" {
+			t.Errorf("missing token on %s", r.URL.Path)
+		}
+		switch r.URL.EscapedPath() {
+		case "/api/v4/projects/numtide%2Fnix%2Fblueprint":
+			fmt.Fprint(w, `{"description":"Nix made easy","star_count":42,"forks_count":7,"visibility":"public"}`)
+		case "/api/v4/projects/numtide%2Fnix%2Fblueprint/languages":
+			fmt.Fprint(w, `{"Shell":12.5,"Nix":80.1,"Go":7.4}`)
+		case "/api/v4/projects/numtide%2Fnolang":
+			fmt.Fprint(w, `{"description":"No languages","visibility":"public"}`)
+		case "/api/v4/projects/numtide%2Fnolang/languages":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	g := NewGitLab("gitlab", srv.URL, "glpat-test")
+	data, err := g.GetRepositoryData(context.Background(), "numtide/nix", "blueprint")
+	if err != nil {
+		t.Fatalf("GetRepositoryData failed: %v", err)
+	}
+
+	// GitLab reports the breakdown as percentages
+	if len(data.Languages) != 3 || data.Languages[0].Name != "Nix" || math.Abs(data.Languages[0].Percent-80.1) > 0.01 {
+		t.Errorf("unexpected language breakdown: %+v", data.Languages)
+	}
+	data.Languages = nil
+
+	expected := Repository{
+		Name:            "blueprint",
+		Description:     "Nix made easy",
+		Owner:           "numtide/nix",
+		Language:        "Nix",
+		StargazersCount: 42,
+		ForksCount:      7,
+		Visibility:      VisibilityPublic,
+	}
+	if !reflect.DeepEqual(*data, expected) {
+		t.Errorf("got %+v, want %+v", *data, expected)
+	}
+
+	if got := g.RepositoryURL("numtide/nix", "blueprint"); got != srv.URL+"/numtide/nix/blueprint" {
+		t.Errorf("got repository URL %q", got)
+	}
+
+	// A failing languages call still produces the banner
+	data, err = g.GetRepositoryData(context.Background(), "numtide", "nolang")
+	if err != nil {
+		t.Fatalf("GetRepositoryData without languages failed: %v", err)
+	}
+	if data.Description != "No languages" || data.Language != "" || data.Languages != nil {
+		t.Errorf("unexpected repository without languages: %+v", data)
+	}
+
+	if _, err := g.GetRepositoryData(context.Background(), "numtide", "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestPrimaryLanguage(t *testing.T) {
+	tests := []struct {
+		languages map[string]float64
+		expected  string
+	}{
+		{nil, ""},
+		{map[string]float64{"Go": 60, "Nix": 40}, "Go"},
+		{map[string]float64{"Rust": 50, "C": 50}, "C"},
+	}
+
+	for _, tt := range tests {
+		if got := primaryLanguage(tt.languages); got != tt.expected {
+			t.Errorf("primaryLanguage(%v) = %q, want %q", tt.languages, got, tt.expected)
+		}
+	}
+}
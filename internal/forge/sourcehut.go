@@ -0,0 +1,115 @@
+package forge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DefaultSourceHutURL is the public git.sr.ht instance
+const DefaultSourceHutURL = "https://git.sr.ht"
+
+// sourceHutQuery fetches a repository through the git.sr.ht GraphQL API
+const sourceHutQuery = `query($owner: String!, $repo: String!) {
+  user(username: $owner) {
+    repository(name: $repo) {
+      name
+      description
+      visibility
+    }
+  }
+}`
+
+// SourceHut fetches repositories from a git.sr.ht instance. Its API does
+// not report stars, forks or languages, so only the description is shown.
+type SourceHut struct {
+	name    string
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// NewSourceHut creates a SourceHut provider. The GraphQL API requires a
+// personal access token. An empty baseURL means git.sr.ht.
+func NewSourceHut(name, baseURL, token string) (*SourceHut, error) {
+	if token == "" {
+		return nil, fmt.Errorf("forge '%s' requires a token", name)
+	}
+	if baseURL == "" {
+		baseURL = DefaultSourceHutURL
+	}
+	return &SourceHut{
+		name:    name,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		client:  defaultHTTPClient,
+	}, nil
+}
+
+// Name identifies the forge
+func (s *SourceHut) Name() string {
+	return s.name
+}
+
+// GetRepositoryData fetches repository metadata. The owner may be given
+// with or without its "~" prefix.
+func (s *SourceHut) GetRepositoryData(ctx context.Context, owner, repo string) (*Repository, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"query": sourceHutQuery,
+		"variables": map[string]string{
+			"owner": strings.TrimPrefix(owner, "~"),
+			"repo":  repo,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.baseURL+"/query", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.token)
+
+	var response struct {
+		Data struct {
+			User *struct {
+				Repository *struct {
+					Description string `json:"description"`
+					Visibility  string `json:"visibility"`
+				} `json:"repository"`
+			} `json:"user"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := doJSON(ctx, s.client, req, &response); err != nil {
+		return nil, fmt.Errorf("failed to fetch repository: %w", err)
+	}
+	if len(response.Errors) > 0 {
+		return nil, fmt.Errorf("failed to fetch repository: %s", response.Errors[0].Message)
+	}
+	if response.Data.User == nil || response.Data.User.Repository == nil {
+		return nil, fmt.Errorf("failed to fetch repository: %w", ErrNotFound)
+	}
+
+	// Use the original repo name as provided by the user to preserve
+	// capitalization. Unlisted repositories are not treated as public.
+	repository := response.Data.User.Repository
+	return &Repository{
+		Name:        repo,
+		Description: repository.Description,
+		Owner:       owner,
+		Visibility:  visibility(repository.Visibility == "PUBLIC"),
+	}, nil
+}
+
+// RepositoryURL returns the web URL of a repository
+func (s *SourceHut) RepositoryURL(owner, repo string) string {
+	return fmt.Sprintf("%s/~%s/%s", s.baseURL, strings.TrimPrefix(owner, "~"), repo)
+}
@@ -0,0 +1,72 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestSourceHutGetRepositoryData(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/query" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer srht-token" {
+			t.Errorf("missing token")
+		}
+
+		var request struct {
+			Variables map[string]string `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+
+		if request.Variables["owner"] == "sircmpwn" && request.Variables["repo"] == "hare" {
+			fmt.Fprint(w, `{"data":{"user":{"repository":{"name":"hare","description":"The Hare programming language","visibility":"PUBLIC"}}}}`)
+			return
+		}
+		fmt.Fprint(w, `{"data":{"user":{"repository":null}}}`)
+	}))
+	defer srv.Close()
+
+	s, err := NewSourceHut("sourcehut", srv.URL, "srht-token")
+	if err != nil {
+		t.Fatalf("NewSourceHut failed: %v", err)
+	}
+
+	data, err := s.GetRepositoryData(context.Background(), "~sircmpwn", "hare")
+	if err != nil {
+		t.Fatalf("GetRepositoryData failed: %v", err)
+	}
+
+	expected := Repository{
+		Name:        "hare",
+		Description: "The Hare programming language",
+		Owner:       "~sircmpwn",
+		Visibility:  VisibilityPublic,
+	}
+	if !reflect.DeepEqual(*data, expected) {
+		t.Errorf("got %+v, want %+v", *data, expected)
+	}
+
+	if got := s.RepositoryURL("sircmpwn", "hare"); got != srv.URL+"/~sircmpwn/hare" {
+		t.Errorf("got repository URL %q", got)
+	}
+
+	if _, err := s.GetRepositoryData(context.Background(), "sircmpwn", "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestNewSourceHutRequiresToken(t *testing.T) {
+	if _, err := NewSourceHut("sourcehut", "", ""); err == nil {
+		t.Error("expected error without token")
+	}
+}
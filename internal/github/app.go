@@ -0,0 +1,296 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v56/github"
+	"github.com/numtide/banner-generator/internal/singleflight"
+)
+
+const (
+	// appJWTLifetime is how long app JWTs are valid (GitHub allows at most 10 minutes)
+	appJWTLifetime = 9 * time.Minute
+
+	// tokenRefreshMargin is how long before expiry installation tokens are renewed
+	tokenRefreshMargin = 5 * time.Minute
+
+	// missingInstallationTTL is how long to remember that an owner has no installation
+	missingInstallationTTL = 10 * time.Minute
+)
+
+// AppCredentials identifies a GitHub App used for authentication
+type AppCredentials struct {
+	// AppID is the numeric GitHub App ID
+	AppID int64
+
+	// InstallationID pins a single installation (optional)
+	InstallationID int64
+
+	// Owner is the account whose installation is used when the requested
+	// owner has none (optional)
+	Owner string
+
+	key *rsa.PrivateKey
+}
+
+// NewAppCredentials creates app credentials from a PEM-encoded private key
+func NewAppCredentials(appID int64, privateKey []byte, installationID int64, owner string) (*AppCredentials, error) {
+	if appID == 0 {
+		return nil, fmt.Errorf("GitHub App ID is required")
+	}
+
+	key, err := parsePrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AppCredentials{
+		AppID:          appID,
+		InstallationID: installationID,
+		Owner:          owner,
+		key:            key,
+	}, nil
+}
+
+// parsePrivateKey decodes a PKCS#1 or PKCS#8 RSA private key
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("GitHub App private key is not PEM-encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub App private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("GitHub App private key is not an RSA key")
+	}
+	return key, nil
+}
+
+// jwt mints a JSON Web Token authenticating as the app
+func (a *AppCredentials) jwt(now time.Time) (string, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+
+	claims, err := json.Marshal(map[string]int64{
+		// Backdate to allow for clock drift
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": a.AppID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign app JWT: %w", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// jwtTransport authenticates requests as the app itself
+type jwtTransport struct {
+	creds *AppCredentials
+	base  http.RoundTripper
+}
+
+func (t *jwtTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.creds.jwt(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req)
+}
+
+type installationToken struct {
+	token   string
+	expires time.Time
+}
+
+type installationLookup struct {
+	id      int64
+	checked time.Time
+}
+
+// appTransport authenticates requests with an installation token for the
+// owner of the requested repository
+type appTransport struct {
+	creds *AppCredentials
+	base  http.RoundTripper
+
+	// apps is authenticated as the app and used to manage installations
+	apps *github.Client
+
+	mu            sync.Mutex
+	installations map[string]installationLookup
+	tokens        map[int64]installationToken
+	refreshes     singleflight.Group[installationToken]
+}
+
+// newAppTransport creates a transport authenticating as installations of
+// the app on the API at baseURL
+func newAppTransport(creds *AppCredentials, base http.RoundTripper, baseURL, uploadURL string) (*appTransport, error) {
+	apps, err := newGitHubClient(&http.Client{Transport: &jwtTransport{creds: creds, base: base}}, baseURL, uploadURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &appTransport{
+		creds:         creds,
+		base:          base,
+		apps:          apps,
+		installations: make(map[string]installationLookup),
+		tokens:        make(map[int64]installationToken),
+	}, nil
+}
+
+func (t *appTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	installationID, err := t.installationFor(ctx, ownerFromPath(req.URL.Path))
+	if err != nil {
+		return nil, err
+	}
+	if installationID == 0 {
+		// No installation can see this owner; public data is still
+		// reachable anonymously
+		return t.base.RoundTrip(req)
+	}
+
+	token, err := t.token(ctx, installationID)
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(ctx)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req)
+}
+
+// installationFor returns the installation to use for owner, or 0 if none
+func (t *appTransport) installationFor(ctx context.Context, owner string) (int64, error) {
+	if t.creds.InstallationID != 0 {
+		return t.creds.InstallationID, nil
+	}
+
+	if owner != "" {
+		id, err := t.lookupInstallation(ctx, owner)
+		if err != nil || id != 0 {
+			return id, err
+		}
+	}
+
+	if t.creds.Owner != "" && !strings.EqualFold(owner, t.creds.Owner) {
+		return t.lookupInstallation(ctx, t.creds.Owner)
+	}
+
+	return 0, nil
+}
+
+// lookupInstallation finds the app installation on an organization or user account
+func (t *appTransport) lookupInstallation(ctx context.Context, owner string) (int64, error) {
+	key := strings.ToLower(owner)
+
+	t.mu.Lock()
+	lookup, ok := t.installations[key]
+	t.mu.Unlock()
+	if ok && (lookup.id != 0 || time.Since(lookup.checked) < missingInstallationTTL) {
+		return lookup.id, nil
+	}
+
+	installation, _, err := t.apps.Apps.FindOrganizationInstallation(ctx, owner)
+	if isNotFound(err) {
+		installation, _, err = t.apps.Apps.FindUserInstallation(ctx, owner)
+	}
+	if isNotFound(err) {
+		slog.WarnContext(ctx, "GitHub App is not installed", "app_id", t.creds.AppID, "owner", owner)
+		installation, err = &github.Installation{}, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to find GitHub App installation for %s: %w", owner, err)
+	}
+
+	t.mu.Lock()
+	t.installations[key] = installationLookup{id: installation.GetID(), checked: time.Now()}
+	t.mu.Unlock()
+
+	return installation.GetID(), nil
+}
+
+// token returns a valid installation token, renewing it shortly before expiry
+func (t *appTransport) token(ctx context.Context, installationID int64) (string, error) {
+	t.mu.Lock()
+	cached, ok := t.tokens[installationID]
+	t.mu.Unlock()
+	if ok && time.Until(cached.expires) > tokenRefreshMargin {
+		return cached.token, nil
+	}
+
+	key := fmt.Sprintf("%d", installationID)
+	fresh, _, err := t.refreshes.Do(ctx, key, func(ctx context.Context) (installationToken, error) {
+		token, _, err := t.apps.Apps.CreateInstallationToken(ctx, installationID, nil)
+		if err != nil {
+			return installationToken{}, fmt.Errorf("failed to create installation token: %w", err)
+		}
+
+		fresh := installationToken{
+			token:   token.GetToken(),
+			expires: token.GetExpiresAt().Time,
+		}
+
+		t.mu.Lock()
+		t.tokens[installationID] = fresh
+		t.mu.Unlock()
+
+		return fresh, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return fresh.token, nil
+}
+
+// ownerFromPath extracts the owner from an API path like /repos/{owner}/{repo}
+func ownerFromPath(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i, part := range parts {
+		if part == "repos" && i+1 < len(parts) {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+// isNotFound reports whether err is a 404 response from the API
+func isNotFound(err error) bool {
+	var errResp *github.ErrorResponse
+	return errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotFound
+}
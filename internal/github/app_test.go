@@ -0,0 +1,218 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/numtide/banner-generator/internal/config"
+)
+
+// fakeAppServer is a local stand-in for the GitHub API with a single app
+// installed on the "numtide" organization
+type fakeAppServer struct {
+	t         *testing.T
+	key       *rsa.PublicKey
+	tokenTTL  time.Duration
+	minted    atomic.Int32
+	lookups   atomic.Int32
+	lastToken atomic.Value
+}
+
+func (f *fakeAppServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case r.URL.Path == "/orgs/numtide/installation":
+		f.lookups.Add(1)
+		if !f.validJWT(r) {
+			http.Error(w, `{"message":"bad JWT"}`, http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"id":7}`)
+
+	case strings.HasSuffix(r.URL.Path, "/installation"):
+		http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+
+	case r.URL.Path == "/app/installations/7/access_tokens" && r.Method == http.MethodPost:
+		if !f.validJWT(r) {
+			http.Error(w, `{"message":"bad JWT"}`, http.StatusUnauthorized)
+			return
+		}
+		n := f.minted.Add(1)
+		token := fmt.Sprintf("ghs_token%d", n)
+		f.lastToken.Store(token)
+		fmt.Fprintf(w, `{"token":%q,"expires_at":%q}`, token, time.Now().Add(f.tokenTTL).Format(time.RFC3339))
+
+	case strings.HasPrefix(r.URL.Path, "/repos/numtide/"):
+		if r.Header.Get("Authorization") != "Bearer "+f.lastToken.Load().(string) {
+			http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"description":"private insight","stargazers_count":1}`)
+
+	case strings.HasPrefix(r.URL.Path, "/repos/"):
+		if r.Header.Get("Authorization") != "" {
+			f.t.Errorf("unexpected credentials for %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"description":"public"}`)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// validJWT checks the app JWT signature and claims
+func (f *fakeAppServer) validJWT(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return false
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(f.key, crypto.SHA256, digest[:], signature); err != nil {
+		return false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	var claims struct {
+		Iss int64 `json:"iss"`
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return false
+	}
+	return claims.Iss == 42 && time.Unix(claims.Exp, 0).After(time.Now())
+}
+
+// newAppTestClient creates a client authenticating as app 42 against a fake server
+func newAppTestClient(t *testing.T, tokenTTL time.Duration) (*Client, *fakeAppServer) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	creds, err := NewAppCredentials(42, keyPEM, 0, "")
+	if err != nil {
+		t.Fatalf("NewAppCredentials failed: %v", err)
+	}
+
+	fake := &fakeAppServer{t: t, key: &key.PublicKey, tokenTTL: tokenTTL}
+	fake.lastToken.Store("")
+
+	c := newTestClientWithOptions(t, fake, Options{App: creds, CacheDuration: time.Hour})
+	return c, fake
+}
+
+func TestAppAuthenticationUsesInstallationToken(t *testing.T) {
+	c, fake := newAppTestClient(t, time.Hour)
+	ctx := context.Background()
+
+	for _, repo := range []string{"treefmt", "blueprint"} {
+		data, err := c.GetRepositoryData(ctx, "numtide", repo)
+		if err != nil {
+			t.Fatalf("fetching %s failed: %v", repo, err)
+		}
+		if data.Description != "private insight" {
+			t.Errorf("got description %q for %s", data.Description, repo)
+		}
+	}
+
+	if got := fake.minted.Load(); got != 1 {
+		t.Errorf("minted %d installation tokens, want 1", got)
+	}
+	if got := fake.lookups.Load(); got != 1 {
+		t.Errorf("looked up installation %d times, want 1", got)
+	}
+}
+
+func TestAppAuthenticationRefreshesExpiringToken(t *testing.T) {
+	// Tokens expiring within the refresh margin are renewed on every use
+	c, fake := newAppTestClient(t, time.Minute)
+	ctx := context.Background()
+
+	for _, repo := range []string{"treefmt", "blueprint"} {
+		if _, err := c.GetRepositoryData(ctx, "numtide", repo); err != nil {
+			t.Fatalf("fetching %s failed: %v", repo, err)
+		}
+	}
+
+	if got := fake.minted.Load(); got != 2 {
+		t.Errorf("minted %d installation tokens, want 2", got)
+	}
+}
+
+func TestAppAuthenticationWithoutInstallation(t *testing.T) {
+	c, fake := newAppTestClient(t, time.Hour)
+
+	data, err := c.GetRepositoryData(context.Background(), "someone-else", "repo")
+	if err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if data.Description != "public" {
+		t.Errorf("got description %q, want %q", data.Description, "public")
+	}
+	if got := fake.minted.Load(); got != 0 {
+		t.Errorf("minted %d installation tokens, want 0", got)
+	}
+}
+
+func TestOptionsFromConfigRejectsAppWithTokenPool(t *testing.T) {
+	app := config.GitHubAppConfig{AppID: 1, PrivateKey: "unused"}
+	tests := []struct {
+		name string
+		cfg  config.GitHubConfig
+	}{
+		{"tokens", config.GitHubConfig{Tokens: []string{"ghp_a", "ghp_b"}, App: app}},
+		{"token files", config.GitHubConfig{TokenFiles: []string{"github.token"}, App: app}},
+	}
+
+	for _, tt := range tests {
+		if _, err := OptionsFromConfig(tt.cfg); err == nil || !strings.Contains(err.Error(), "cannot be combined") {
+			t.Errorf("%s: got %v, want an error for GitHub App authentication with a token pool", tt.name, err)
+		}
+	}
+}
+
+func TestOwnerFromPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/repos/numtide/treefmt", "numtide"},
+		{"/api/v3/repos/numtide/treefmt", "numtide"},
+		{"/rate_limit", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ownerFromPath(tt.path); got != tt.expected {
+			t.Errorf("ownerFromPath(%q) = %q, want %q", tt.path, got, tt.expected)
+		}
+	}
+}
@@ -2,97 +2,456 @@ package github
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"sync"
+	"log/slog"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/go-github/v56/github"
-	"golang.org/x/oauth2"
+	"github.com/numtide/banner-generator/internal/cache"
+	"github.com/numtide/banner-generator/internal/forge"
+	"github.com/numtide/banner-generator/internal/logging"
+	"github.com/numtide/banner-generator/internal/singleflight"
 )
 
+// DefaultCacheRetention is how long cached responses are kept after they
+// become stale, so they can be revalidated with their ETag
+const DefaultCacheRetention = 7 * 24 * time.Hour
+
 // Client wraps the GitHub API client
 type Client struct {
-	client        *github.Client
-	cache         map[string]*cacheEntry
-	cacheMu       sync.RWMutex
-	cacheDuration time.Duration
+	// hosts lists the configured API endpoints; the first one is the default
+	hosts          []*host
+	cache          cache.Cache
+	cacheDuration  time.Duration
+	cacheRetention time.Duration
+	fetches        singleflight.Group[*Repository]
+	lowQuota       float64
+	canonicalNames bool
+	contributors   int
+	languages      bool
 }
 
+// Options configures a Client
+type Options struct {
+	// BaseURL is the API URL of a GitHub Enterprise Server
+	// (defaults to api.github.com)
+	BaseURL string
+
+	// UploadURL is the uploads API URL (defaults to BaseURL)
+	UploadURL string
+
+	// Token is the GitHub API token (optional)
+	Token string
+
+	// Tokens are additional API tokens. When more than one token is
+	// configured, requests are spread across them and exhausted tokens are
+	// skipped until their rate limit resets.
+	Tokens []string
+
+	// App authenticates as a GitHub App installation instead of using
+	// Token (optional)
+	App *AppCredentials
+
+	// Hosts are additional GitHub hosts serving specific owners
+	Hosts []HostOptions
+
+	// CacheDuration is how long fetched data is considered fresh
+	CacheDuration time.Duration
+
+	// Cache stores fetched data (defaults to an in-memory cache)
+	Cache cache.Cache
+
+	// CacheRetention is how long stale data is kept for revalidation
+	// (defaults to DefaultCacheRetention)
+	CacheRetention time.Duration
+
+	// LowQuotaPercent is the share of the rate limit, in percent, below
+	// which stale cached data is served instead of calling the API
+	// (defaults to DefaultLowQuotaPercent)
+	LowQuotaPercent float64
+
+	// CanonicalNames reports the owner and name as GitHub spells them,
+	// instead of as requested (e.g., after a rename or transfer)
+	CanonicalNames bool
+
+	// Contributors is how many top contributors to fetch, with their
+	// avatars, for the contributors slot (0 disables fetching them)
+	Contributors int
+
+	// Languages fetches the language breakdown for the language-bar slot
+	Languages bool
+
+	// Observe is called after every API request, e.g. for metrics (optional)
+	Observe RequestObserver
+}
+
+// cacheEntry is the serialized form of a cached repository
 type cacheEntry struct {
-	data      *Repository
-	timestamp time.Time
+	Data      *Repository `json:"data"`
+	Timestamp time.Time   `json:"timestamp"`
+	ETag      string      `json:"etag,omitempty"`
 }
 
-// NewClient creates a new GitHub client
+// NewClient creates a new GitHub client for api.github.com with an in-memory cache
 func NewClient(token string, cacheDuration time.Duration) *Client {
-	ctx := context.Background()
-	var tc *oauth2.TokenSource
+	// Without custom URLs, creating the client cannot fail
+	client, _ := NewClientWithOptions(Options{
+		Token:         token,
+		CacheDuration: cacheDuration,
+	})
+	return client
+}
+
+// NewClientWithOptions creates a new GitHub client from options
+func NewClientWithOptions(opts Options) (*Client, error) {
+	tokens := opts.Tokens
+	if opts.Token != "" {
+		tokens = append([]string{opts.Token}, tokens...)
+	}
 
-	if token != "" {
-		ts := oauth2.StaticTokenSource(
-			&oauth2.Token{AccessToken: token},
-		)
-		tc = &ts
+	defaultHost, err := newHost(opts.BaseURL, opts.UploadURL, tokens, opts.App, opts.Observe)
+	if err != nil {
+		return nil, err
+	}
+	hosts := []*host{defaultHost}
+
+	for _, hostOpts := range opts.Hosts {
+		h, err := newHost(hostOpts.BaseURL, hostOpts.UploadURL, hostOpts.Tokens, nil, opts.Observe)
+		if err != nil {
+			return nil, err
+		}
+		h.owners = hostOpts.Owners
+		hosts = append(hosts, h)
 	}
 
-	var ghClient *github.Client
-	if tc != nil {
-		httpClient := oauth2.NewClient(ctx, *tc)
-		ghClient = github.NewClient(httpClient)
-	} else {
-		ghClient = github.NewClient(nil)
+	store := opts.Cache
+	if store == nil {
+		store = cache.NewMemory()
+	}
+
+	retention := opts.CacheRetention
+	if retention <= 0 {
+		retention = DefaultCacheRetention
+	}
+
+	lowQuota := opts.LowQuotaPercent
+	if lowQuota <= 0 {
+		lowQuota = DefaultLowQuotaPercent
 	}
 
 	return &Client{
-		client:        ghClient,
-		cache:         make(map[string]*cacheEntry),
-		cacheDuration: cacheDuration,
+		hosts:          hosts,
+		cache:          store,
+		cacheDuration:  opts.CacheDuration,
+		cacheRetention: retention,
+		lowQuota:       lowQuota,
+		canonicalNames: opts.CanonicalNames,
+		contributors:   opts.Contributors,
+		languages:      opts.Languages,
+	}, nil
+}
+
+// hostFor returns the host serving owner
+func (c *Client) hostFor(owner string) *host {
+	for _, h := range c.hosts[1:] {
+		if h.serves(owner) {
+			return h
+		}
 	}
+	return c.hosts[0]
 }
 
-// GetRepositoryData fetches repository metadata from GitHub
+// Name identifies GitHub as a forge
+func (c *Client) Name() string {
+	return forge.DefaultForge
+}
+
+// GetRepositoryData fetches repository metadata from GitHub. Repositories
+// are cached by their numeric ID, so differently capitalized, renamed and
+// transferred names share one entry.
 func (c *Client) GetRepositoryData(ctx context.Context, owner, repo string) (*Repository, error) {
+	h := c.hostFor(owner)
+
 	// Check cache first
-	cacheKey := fmt.Sprintf("%s/%s", owner, repo)
+	alias := aliasKey(h, owner, repo)
+
+	entry := c.lookupEntry(ctx, alias)
+	if entry != nil && time.Since(entry.Timestamp) < c.cacheDuration {
+		logging.Add(ctx, "cache", "hit")
+		return c.present(entry.Data, owner, repo), nil
+	}
+
+	// Avoid spending the remaining quota when we have something to show
+	rate := h.rateLimit()
+	if rate.Low(time.Now(), c.lowQuota) && entry != nil {
+		logging.Add(ctx, "cache", "stale")
+		return c.present(entry.Data, owner, repo), nil
+	}
+	if rate.Exhausted(time.Now()) {
+		return nil, fmt.Errorf("%w until %s", ErrRateLimited, rate.Reset.Format(time.RFC3339))
+	}
+
+	// Coalesce concurrent fetches for the same repository
+	data, shared, err := c.fetches.Do(ctx, alias, func(ctx context.Context) (*Repository, error) {
+		return c.fetchRepositoryData(ctx, h, alias, owner, repo, entry)
+	})
+	if shared {
+		logging.Add(ctx, "cache", "coalesced")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.present(data, owner, repo), nil
+}
 
-	c.cacheMu.RLock()
-	entry, ok := c.cache[cacheKey]
-	c.cacheMu.RUnlock()
+// present returns the repository as reported to callers. Unless canonical
+// names are enabled, the owner and name from the request are kept to
+// preserve their capitalization.
+func (c *Client) present(data *Repository, owner, repo string) *Repository {
+	if c.canonicalNames && data.FullName != "" {
+		return data
+	}
+	presented := *data
+	presented.Owner = owner
+	presented.Name = repo
+	return &presented
+}
 
-	if ok && time.Since(entry.timestamp) < c.cacheDuration {
-		return entry.data, nil
+// fetchRepositoryData fetches repository metadata from the API and updates the cache.
+// If a stale entry is available, it is revalidated using its ETag. The cache
+// status is added to the log fields of the request ctx belongs to.
+func (c *Client) fetchRepositoryData(ctx context.Context, h *host, alias, owner, repo string, stale *cacheEntry) (*Repository, error) {
+	req, err := h.client.NewRequest("GET", fmt.Sprintf("repos/%s/%s", owner, repo), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if stale != nil && stale.ETag != "" {
+		req.Header.Set("If-None-Match", stale.ETag)
 	}
 
-	// Fetch repository information
-	repository, _, err := c.client.Repositories.Get(ctx, owner, repo)
+	// Fetch repository information. Renamed and transferred repositories
+	// are redirected to their new location.
+	repository := new(github.Repository)
+	start := time.Now()
+	resp, err := h.client.Do(ctx, req, repository)
+	if resp != nil {
+		h.rate.update(resp.Rate)
+		slog.DebugContext(ctx, "Fetched repository from GitHub", "host", h.name, "status", resp.StatusCode, "duration", time.Since(start))
+	}
+	if reset, ok := rateLimitReset(err); ok {
+		h.rate.exhaust(reset)
+		if stale != nil {
+			logging.Add(ctx, "cache", "stale")
+			return stale.Data, nil
+		}
+		return nil, fmt.Errorf("%w until %s", ErrRateLimited, reset.Format(time.RFC3339))
+	}
+	if resp != nil && resp.StatusCode == http.StatusNotModified && stale != nil {
+		// Unchanged since the last fetch; conditional requests do not
+		// count against the rate limit
+		c.storeEntry(ctx, h, alias, &cacheEntry{
+			Data:      stale.Data,
+			Timestamp: time.Now(),
+			ETag:      stale.ETag,
+		})
+		logging.Add(ctx, "cache", "revalidated")
+		return stale.Data, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch repository: %w", err)
 	}
+	logging.Add(ctx, "cache", "miss")
 
-	// Use the original repo name as provided by the user to preserve capitalization
 	data := &Repository{
-		Name:            repo,
+		ID:              repository.GetID(),
+		FullName:        repository.GetFullName(),
+		Name:            repository.GetName(),
 		Description:     repository.GetDescription(),
-		Owner:           owner,
+		Owner:           repository.GetOwner().GetLogin(),
 		Language:        repository.GetLanguage(),
 		StargazersCount: repository.GetStargazersCount(),
 		ForksCount:      repository.GetForksCount(),
+		Visibility:      repositoryVisibility(repository),
+		FetchedAt:       time.Now(),
+	}
+	if data.Name == "" {
+		data.Name = repo
+	}
+	if data.Owner == "" {
+		data.Owner = owner
 	}
 
-	// Update cache
-	c.cacheMu.Lock()
-	c.cache[cacheKey] = &cacheEntry{
-		data:      data,
-		timestamp: time.Now(),
+	if c.languages {
+		languages, resp, err := h.client.Repositories.ListLanguages(ctx, owner, repo)
+		if resp != nil {
+			h.rate.update(resp.Rate)
+		}
+		if err != nil {
+			// The primary language is still shown without the breakdown
+			slog.WarnContext(ctx, "Failed to fetch languages", "error", err)
+		} else {
+			sizes := make(map[string]float64, len(languages))
+			for name, size := range languages {
+				sizes[name] = float64(size)
+			}
+			data.Languages = forge.LanguageShares(sizes)
+		}
+	}
+
+	if c.contributors > 0 {
+		contributors, total, err := c.fetchContributors(ctx, h, owner, repo)
+		if err != nil {
+			// Contributors are decoration; show the banner without them
+			slog.WarnContext(ctx, "Failed to fetch contributors", "error", err)
+		} else {
+			data.Contributors = contributors
+			data.ContributorCount = total
+		}
 	}
-	c.cacheMu.Unlock()
+
+	// Update cache
+	c.storeEntry(ctx, h, alias, &cacheEntry{
+		Data:      data,
+		Timestamp: time.Now(),
+		ETag:      resp.Header.Get("ETag"),
+	})
 
 	return data, nil
 }
 
+// repositoryVisibility maps GitHub's visibility to a Repository visibility.
+// Internal repositories of enterprises count as private.
+func repositoryVisibility(repository *github.Repository) string {
+	if repository.GetPrivate() || (repository.Visibility != nil && repository.GetVisibility() != "public") {
+		return forge.VisibilityPrivate
+	}
+	return forge.VisibilityPublic
+}
+
+// aliasKey is the cache key mapping a requested owner/repo to the key of
+// the repository's entry
+func aliasKey(h *host, owner, repo string) string {
+	return strings.ToLower(fmt.Sprintf("alias:%s/%s/%s", h.name, owner, repo))
+}
+
+// entryKey is the cache key of a repository, based on its numeric ID when known
+func entryKey(h *host, data *Repository) string {
+	if data.ID != 0 {
+		return fmt.Sprintf("repo:%s/%d", h.name, data.ID)
+	}
+	return strings.ToLower(fmt.Sprintf("repo:%s/%s/%s", h.name, data.Owner, data.Name))
+}
+
+// lookupEntry resolves an alias and returns the cached entry it points to,
+// or nil if there is none
+func (c *Client) lookupEntry(ctx context.Context, alias string) *cacheEntry {
+	key, ok, err := c.cache.Get(ctx, alias)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to read cache entry", "key", alias, "error", err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	return c.loadEntry(ctx, string(key))
+}
+
+// loadEntry returns the cached entry for key, or nil if there is none.
+// Cache failures are logged and treated as a miss.
+func (c *Client) loadEntry(ctx context.Context, key string) *cacheEntry {
+	raw, ok, err := c.cache.Get(ctx, key)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to read cache entry", "key", key, "error", err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(raw, &entry); err != nil || entry.Data == nil {
+		slog.WarnContext(ctx, "Discarding invalid cache entry", "key", key, "error", err)
+		return nil
+	}
+	// Entries cached before visibility was recorded would hide public
+	// repositories until they expire
+	if entry.Data.Visibility == "" {
+		return nil
+	}
+	return &entry
+}
+
+// storeEntry saves an entry in the cache under its repository key, and
+// points the requested and canonical aliases at it. Failures are logged but
+// not fatal.
+func (c *Client) storeEntry(ctx context.Context, h *host, alias string, entry *cacheEntry) {
+	key := entryKey(h, entry.Data)
+
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to encode cache entry", "key", key, "error", err)
+		return
+	}
+	if err := c.cache.Set(ctx, key, raw, c.cacheRetention); err != nil {
+		slog.WarnContext(ctx, "Failed to write cache entry", "key", key, "error", err)
+		return
+	}
+
+	aliases := []string{alias}
+	if owner, repo, ok := strings.Cut(entry.Data.FullName, "/"); ok {
+		if canonical := aliasKey(h, owner, repo); canonical != alias {
+			aliases = append(aliases, canonical)
+		}
+	}
+	for _, a := range aliases {
+		if err := c.cache.Set(ctx, a, []byte(key), c.cacheRetention); err != nil {
+			slog.WarnContext(ctx, "Failed to write cache entry", "key", a, "error", err)
+		}
+	}
+}
+
+// RateLimit returns the last known quota of the default host. With several
+// tokens, this is their combined quota.
+func (c *Client) RateLimit() RateLimitState {
+	return c.hosts[0].rateLimit()
+}
+
+// RateLimitFor returns the last known quota of the host serving owner
+func (c *Client) RateLimitFor(owner string) RateLimitState {
+	return c.hostFor(owner).rateLimit()
+}
+
+// TokenStatus returns the health of each pooled token of the default host,
+// or nil if it does not use a token pool
+func (c *Client) TokenStatus() []TokenState {
+	return c.hosts[0].state().Tokens
+}
+
+// HostStatus returns the quota of every configured host
+func (c *Client) HostStatus() []HostState {
+	states := make([]HostState, 0, len(c.hosts))
+	for _, h := range c.hosts {
+		states = append(states, h.state())
+	}
+	return states
+}
+
+// RepositoryURL returns the web URL of a repository on the host serving its owner
+func (c *Client) RepositoryURL(owner, repo string) string {
+	return c.hostFor(owner).repositoryURL(owner, repo)
+}
+
+// Close releases the resources held by the cache
+func (c *Client) Close() error {
+	return c.cache.Close()
+}
+
 // ValidateRepository checks if a repository exists and is accessible
 func (c *Client) ValidateRepository(ctx context.Context, owner, repo string) error {
-	_, _, err := c.client.Repositories.Get(ctx, owner, repo)
+	_, _, err := c.hostFor(owner).client.Repositories.Get(ctx, owner, repo)
 	if err != nil {
 		return fmt.Errorf("repository validation failed: %w", err)
 	}
@@ -0,0 +1,440 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v56/github"
+	"github.com/numtide/banner-generator/internal/forge"
+)
+
+// newTestClient creates a client talking to a local fake GitHub API
+func newTestClient(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+	return newTestClientWithOptions(t, handler, Options{CacheDuration: time.Hour})
+}
+
+// newTestClientWithOptions creates a client with opts talking to a local
+// fake GitHub API, served like a GitHub Enterprise Server under /api/v3
+func newTestClientWithOptions(t *testing.T, handler http.Handler, opts Options) *Client {
+	t.Helper()
+	srv := httptest.NewServer(http.StripPrefix("/api/v3", handler))
+	t.Cleanup(srv.Close)
+
+	opts.BaseURL = srv.URL + "/"
+	c, err := NewClientWithOptions(opts)
+	if err != nil {
+		t.Fatalf("NewClientWithOptions failed: %v", err)
+	}
+	return c
+}
+
+func TestGetRepositoryDataCoalescesConcurrentFetches(t *testing.T) {
+	var requests atomic.Int32
+	release := make(chan struct{})
+
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"name":"repo","description":"A repository","language":"Go","stargazers_count":42,"forks_count":7}`)
+	}))
+
+	const n = 25
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, err := c.GetRepositoryData(context.Background(), "owner", "repo")
+			if err != nil {
+				errs <- err
+				return
+			}
+			if data.StargazersCount != 42 {
+				errs <- fmt.Errorf("got %d stars, want 42", data.StargazersCount)
+			}
+		}()
+	}
+
+	// Let the callers pile up on the in-flight request before answering
+	deadline := time.Now().Add(5 * time.Second)
+	for requests.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if got := requests.Load(); got != 1 {
+		t.Errorf("GitHub API called %d times, want 1", got)
+	}
+}
+
+func TestGetRepositoryDataRespectsCallerCancellation(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.GetRepositoryData(ctx, "owner", "repo")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("caller waited %v after its context expired", elapsed)
+	}
+}
+
+func TestGetRepositoryDataRevalidatesWithETag(t *testing.T) {
+	var requests, notModified atomic.Int32
+
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, `{"name":"repo","description":"A repository","stargazers_count":42}`)
+	}))
+	// Every entry is immediately stale
+	c.cacheDuration = 0
+
+	for i := 0; i < 2; i++ {
+		data, err := c.GetRepositoryData(context.Background(), "owner", "repo")
+		if err != nil {
+			t.Fatalf("fetch %d failed: %v", i, err)
+		}
+		if data.Description != "A repository" || data.StargazersCount != 42 {
+			t.Errorf("fetch %d returned unexpected data: %+v", i, data)
+		}
+	}
+
+	if got := requests.Load(); got != 2 {
+		t.Errorf("GitHub API called %d times, want 2", got)
+	}
+	if got := notModified.Load(); got != 1 {
+		t.Errorf("got %d conditional hits, want 1", got)
+	}
+}
+
+func TestGetRepositoryDataObservesRequests(t *testing.T) {
+	var mu sync.Mutex
+	var statuses []int
+	var hosts []string
+	c := newTestClientWithOptions(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/missing") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"name":"repo"}`)
+	}), Options{
+		CacheDuration: time.Hour,
+		Observe: func(host string, status int, duration time.Duration) {
+			mu.Lock()
+			defer mu.Unlock()
+			hosts = append(hosts, host)
+			statuses = append(statuses, status)
+		},
+	})
+
+	if _, err := c.GetRepositoryData(context.Background(), "owner", "repo"); err != nil {
+		t.Fatalf("GetRepositoryData failed: %v", err)
+	}
+	if _, err := c.GetRepositoryData(context.Background(), "owner", "missing"); err == nil {
+		t.Fatal("GetRepositoryData of a missing repository succeeded")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(statuses) != 2 || statuses[0] != http.StatusOK || statuses[1] != http.StatusNotFound {
+		t.Errorf("observed statuses %v, want [200 404]", statuses)
+	}
+	for _, host := range hosts {
+		if host != c.hosts[0].name {
+			t.Errorf("observed host %q, want %q", host, c.hosts[0].name)
+		}
+	}
+}
+
+func TestGetRepositoryDataRateLimited(t *testing.T) {
+	var requests atomic.Int32
+	reset := time.Now().Add(time.Hour).Unix()
+
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-RateLimit-Limit", "60")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", fmt.Sprint(reset))
+		fmt.Fprint(w, `{"name":"repo","stargazers_count":42}`)
+	}))
+	c.cacheDuration = 0
+
+	// The last request of the window succeeds
+	if _, err := c.GetRepositoryData(context.Background(), "owner", "repo"); err != nil {
+		t.Fatalf("first fetch failed: %v", err)
+	}
+
+	state := c.RateLimit()
+	if !state.Known || state.Remaining != 0 || state.Limit != 60 {
+		t.Errorf("unexpected rate limit state: %+v", state)
+	}
+
+	// Stale data is served without calling the API
+	data, err := c.GetRepositoryData(context.Background(), "owner", "repo")
+	if err != nil {
+		t.Fatalf("stale fetch failed: %v", err)
+	}
+	if data.StargazersCount != 42 {
+		t.Errorf("got %d stars, want 42", data.StargazersCount)
+	}
+
+	// Without cached data, fail fast
+	_, err = c.GetRepositoryData(context.Background(), "owner", "other")
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("got error %v, want ErrRateLimited", err)
+	}
+
+	if got := requests.Load(); got != 1 {
+		t.Errorf("GitHub API called %d times, want 1", got)
+	}
+}
+
+func TestGetRepositoryDataRoutesOwnersToHosts(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	enterprise := httptest.NewServer(http.StripPrefix("/api/v3", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "10")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"description":"enterprise"}`)
+	})))
+	t.Cleanup(enterprise.Close)
+
+	c := newTestClientWithOptions(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"description":"public"}`)
+	}), Options{
+		CacheDuration: time.Hour,
+		Hosts: []HostOptions{{
+			BaseURL: enterprise.URL + "/api/v3/",
+			Owners:  []string{"Internal"},
+		}},
+	})
+
+	tests := []struct {
+		owner    string
+		expected string
+	}{
+		{"numtide", "public"},
+		{"internal", "enterprise"},
+	}
+
+	for _, tt := range tests {
+		data, err := c.GetRepositoryData(context.Background(), tt.owner, "repo")
+		if err != nil {
+			t.Fatalf("fetching %s/repo failed: %v", tt.owner, err)
+		}
+		if data.Description != tt.expected {
+			t.Errorf("%s/repo: got description %q, want %q", tt.owner, data.Description, tt.expected)
+		}
+	}
+
+	if got := len(c.HostStatus()); got != 2 {
+		t.Errorf("got %d hosts, want 2", got)
+	}
+	if url := c.RepositoryURL("internal", "repo"); url != enterprise.URL+"/internal/repo" {
+		t.Errorf("got repository URL %q", url)
+	}
+
+	// Quotas are those of the owner's host
+	if rate := c.RateLimitFor("Internal"); rate.Remaining != 10 || !rate.Reset.Equal(reset) {
+		t.Errorf("got rate limit %+v for the enterprise owner, want 10 left until %v", rate, reset)
+	}
+	if rate := c.RateLimitFor("numtide"); rate.Known {
+		t.Errorf("got rate limit %+v for the public owner, want none reported", rate)
+	}
+}
+
+// renamedRepoServer serves numtide/treefmt, which used to be called
+// numtide/prjfmt
+func renamedRepoServer(requests *atomic.Int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch strings.ToLower(r.URL.Path) {
+		case "/repos/numtide/prjfmt":
+			http.Redirect(w, r, "/api/v3/repos/numtide/treefmt", http.StatusMovedPermanently)
+		case "/repos/numtide/treefmt":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"id":1234,"name":"treefmt","full_name":"numtide/treefmt","owner":{"login":"numtide"},"stargazers_count":42}`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func TestGetRepositoryDataCachesByID(t *testing.T) {
+	var requests atomic.Int32
+	c := newTestClient(t, renamedRepoServer(&requests))
+	ctx := context.Background()
+
+	for _, name := range []string{"treefmt", "Treefmt", "prjfmt", "treefmt"} {
+		data, err := c.GetRepositoryData(ctx, "numtide", name)
+		if err != nil {
+			t.Fatalf("fetching %s failed: %v", name, err)
+		}
+		// The requested spelling is kept by default
+		if data.Name != name || data.StargazersCount != 42 || data.ID != 1234 {
+			t.Errorf("fetching %s returned %+v", name, data)
+		}
+	}
+
+	// The renamed repository needs a second request to learn its ID;
+	// the other spellings are served from the same entry
+	if got := requests.Load(); got != 3 {
+		t.Errorf("GitHub API called %d times, want 3", got)
+	}
+}
+
+func TestGetRepositoryDataCanonicalNames(t *testing.T) {
+	var requests atomic.Int32
+	c := newTestClientWithOptions(t, renamedRepoServer(&requests), Options{CacheDuration: time.Hour, CanonicalNames: true})
+
+	for _, name := range []string{"Treefmt", "prjfmt"} {
+		data, err := c.GetRepositoryData(context.Background(), "Numtide", name)
+		if err != nil {
+			t.Fatalf("fetching %s failed: %v", name, err)
+		}
+		if data.Owner != "numtide" || data.Name != "treefmt" {
+			t.Errorf("fetching %s returned %s/%s, want numtide/treefmt", name, data.Owner, data.Name)
+		}
+	}
+}
+
+func TestGetRepositoryDataRefetchesEntriesWithoutVisibility(t *testing.T) {
+	var requests atomic.Int32
+	c := newTestClient(t, renamedRepoServer(&requests))
+	ctx := context.Background()
+
+	data, err := c.GetRepositoryData(ctx, "numtide", "treefmt")
+	if err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+
+	// Rewrite the entry as cached before visibility was recorded
+	key := entryKey(c.hostFor("numtide"), data)
+	entry := c.loadEntry(ctx, key)
+	entry.Data.Visibility = ""
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatalf("encoding entry failed: %v", err)
+	}
+	if err := c.cache.Set(ctx, key, raw, time.Hour); err != nil {
+		t.Fatalf("writing entry failed: %v", err)
+	}
+
+	data, err = c.GetRepositoryData(ctx, "numtide", "treefmt")
+	if err != nil {
+		t.Fatalf("second fetch failed: %v", err)
+	}
+	if data.IsPrivate() {
+		t.Errorf("public repository reported as private")
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("GitHub API called %d times, want 2", got)
+	}
+}
+
+func TestRateLimitStateLow(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name  string
+		state RateLimitState
+		want  bool
+	}{
+		{"unknown", RateLimitState{}, false},
+		{"authenticated with quota", RateLimitState{Known: true, Limit: 5000, Remaining: 120, Reset: now.Add(time.Hour)}, false},
+		{"authenticated running low", RateLimitState{Known: true, Limit: 5000, Remaining: 80, Reset: now.Add(time.Hour)}, true},
+		{"unauthenticated with quota", RateLimitState{Known: true, Limit: 60, Remaining: 30, Reset: now.Add(time.Hour)}, false},
+		{"unauthenticated running low", RateLimitState{Known: true, Limit: 60, Remaining: 1, Reset: now.Add(time.Hour)}, true},
+		{"after reset", RateLimitState{Known: true, Limit: 60, Remaining: 0, Reset: now.Add(-time.Minute)}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.state.Low(now, DefaultLowQuotaPercent); got != tt.want {
+			t.Errorf("%s: Low() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRepositoryVisibility(t *testing.T) {
+	tests := []struct {
+		repository *github.Repository
+		expected   string
+	}{
+		{&github.Repository{}, forge.VisibilityPublic},
+		{&github.Repository{Visibility: github.String("public")}, forge.VisibilityPublic},
+		{&github.Repository{Private: github.Bool(true)}, forge.VisibilityPrivate},
+		{&github.Repository{Visibility: github.String("internal")}, forge.VisibilityPrivate},
+	}
+
+	for _, tt := range tests {
+		if got := repositoryVisibility(tt.repository); got != tt.expected {
+			t.Errorf("repositoryVisibility(private=%v, visibility=%q) = %q, want %q",
+				tt.repository.GetPrivate(), tt.repository.GetVisibility(), got, tt.expected)
+		}
+	}
+}
+
+func TestGetRepositoryDataFetchesLanguages(t *testing.T) {
+	c := newTestClientWithOptions(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/numtide/treefmt":
+			fmt.Fprint(w, `{"id":1,"name":"treefmt","language":"Go"}`)
+		case "/repos/numtide/treefmt/languages":
+			fmt.Fprint(w, `{"Go":3000,"Nix":1000}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}), Options{CacheDuration: time.Hour, Languages: true})
+
+	data, err := c.GetRepositoryData(context.Background(), "numtide", "treefmt")
+	if err != nil {
+		t.Fatalf("GetRepositoryData failed: %v", err)
+	}
+
+	expected := []forge.LanguageShare{{Name: "Go", Percent: 75}, {Name: "Nix", Percent: 25}}
+	if len(data.Languages) != len(expected) || data.Languages[0] != expected[0] || data.Languages[1] != expected[1] {
+		t.Errorf("got languages %+v, want %+v", data.Languages, expected)
+	}
+}
@@ -0,0 +1,68 @@
+package github
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/numtide/banner-generator/internal/config"
+)
+
+// OptionsFromConfig builds client options from the [github] configuration
+// section. Cache settings are left for the caller to fill in.
+func OptionsFromConfig(cfg config.GitHubConfig) (Options, error) {
+	opts := Options{
+		BaseURL:         cfg.BaseURL,
+		UploadURL:       cfg.UploadURL,
+		LowQuotaPercent: cfg.LowQuotaPercent,
+		CanonicalNames:  cfg.CanonicalNames,
+		Contributors:    cfg.Contributors,
+		Languages:       cfg.Languages,
+	}
+
+	// A token pool is configured on purpose, so ignoring it would surprise.
+	// A single token is often set through GITHUB_TOKEN for other tools and
+	// only loses to the app.
+	if cfg.App.Enabled() {
+		if len(cfg.Tokens) > 0 || len(cfg.TokenFiles) > 0 {
+			return Options{}, fmt.Errorf("GitHub App authentication cannot be combined with tokens or token_files")
+		}
+		if cfg.Token != "" {
+			slog.Warn("Ignoring the GitHub token in favor of GitHub App authentication")
+		}
+	}
+
+	tokens, err := cfg.AllTokens()
+	if err != nil {
+		return Options{}, err
+	}
+	opts.Tokens = tokens
+
+	if cfg.App.Enabled() {
+		privateKey, err := cfg.App.ReadPrivateKey()
+		if err != nil {
+			return Options{}, err
+		}
+		opts.App, err = NewAppCredentials(cfg.App.AppID, privateKey, cfg.App.InstallationID, cfg.App.Owner)
+		if err != nil {
+			return Options{}, err
+		}
+	}
+
+	for _, hostCfg := range cfg.Hosts {
+		if hostCfg.BaseURL == "" {
+			return Options{}, fmt.Errorf("GitHub host for %v is missing base_url", hostCfg.Owners)
+		}
+		hostTokens, err := hostCfg.AllTokens()
+		if err != nil {
+			return Options{}, err
+		}
+		opts.Hosts = append(opts.Hosts, HostOptions{
+			BaseURL:   hostCfg.BaseURL,
+			UploadURL: hostCfg.UploadURL,
+			Tokens:    hostTokens,
+			Owners:    hostCfg.Owners,
+		})
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,171 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"  // Register GIF avatars
+	_ "image/jpeg" // Register JPEG avatars
+	"image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v56/github"
+	"github.com/numtide/banner-generator/internal/forge"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// AvatarSize is the width and height of embedded avatars in pixels
+	AvatarSize = 64
+
+	// maxAvatarBytes limits the size of a downloaded avatar
+	maxAvatarBytes = 1 << 20
+
+	// maxAvatarDimension limits the width and height of a decoded avatar,
+	// as small files can still decode to huge images
+	maxAvatarDimension = 16 * AvatarSize
+
+	// maxAvatarFetches limits how many avatars of a repository are
+	// downloaded at once
+	maxAvatarFetches = 4
+)
+
+// avatarClient downloads avatars. Avatars are public, so no credentials are sent.
+var avatarClient = &http.Client{Timeout: 10 * time.Second}
+
+// fetchContributors fetches the top contributors with their avatars and the
+// total number of contributors
+func (c *Client) fetchContributors(ctx context.Context, h *host, owner, repo string) ([]forge.Contributor, int, error) {
+	opts := &github.ListContributorsOptions{ListOptions: github.ListOptions{PerPage: c.contributors}}
+	list, resp, err := h.client.Repositories.ListContributors(ctx, owner, repo, opts)
+	if resp != nil {
+		h.rate.update(resp.Rate)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch contributors: %w", err)
+	}
+
+	// With more than one page, listing one contributor per page makes the
+	// last page number the total
+	total := len(list)
+	if resp.LastPage > 1 {
+		opts.PerPage = 1
+		_, resp, err := h.client.Repositories.ListContributors(ctx, owner, repo, opts)
+		if resp != nil {
+			h.rate.update(resp.Rate)
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to count contributors: %w", err)
+		}
+		total = resp.LastPage
+	}
+
+	contributors := make([]forge.Contributor, len(list))
+	var g errgroup.Group
+	g.SetLimit(maxAvatarFetches)
+	for i, contributor := range list {
+		contributors[i].Login = contributor.GetLogin()
+		g.Go(func() error {
+			avatar, err := fetchAvatar(ctx, contributor.GetAvatarURL())
+			if err != nil {
+				// Show the contributor without an avatar rather than not at all
+				slog.WarnContext(ctx, "Failed to fetch avatar", "login", contributor.GetLogin(), "error", err)
+			}
+			contributors[i].Avatar = avatar
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	return contributors, total, nil
+}
+
+// fetchAvatar downloads an avatar and returns it downsampled to AvatarSize
+// as a PNG data URI, so it can be embedded in banners served through camo
+func fetchAvatar(ctx context.Context, avatarURL string) (string, error) {
+	if avatarURL == "" {
+		return "", nil
+	}
+
+	// Ask GitHub to scale the avatar down already
+	u, err := url.Parse(avatarURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid avatar URL: %w", err)
+	}
+	query := u.Query()
+	query.Set("s", strconv.Itoa(AvatarSize))
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := avatarClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxAvatarBytes))
+	if err != nil {
+		return "", fmt.Errorf("failed to read avatar: %w", err)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode avatar: %w", err)
+	}
+	if config.Width > maxAvatarDimension || config.Height > maxAvatarDimension {
+		return "", fmt.Errorf("avatar of %dx%d pixels is too large", config.Width, config.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode avatar: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, downsample(img, AvatarSize)); err != nil {
+		return "", fmt.Errorf("failed to encode avatar: %w", err)
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// downsample scales img to a size×size square by averaging the source
+// pixels covered by each target pixel. Smaller images are scaled up.
+func downsample(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+
+	for y := 0; y < size; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/size
+		y1 := max(bounds.Min.Y+(y+1)*bounds.Dy()/size, y0+1)
+		for x := 0; x < size; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/size
+			x1 := max(bounds.Min.X+(x+1)*bounds.Dx()/size, x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+
+			// Average premultiplied values, then convert back to straight alpha
+			avg := color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)}
+			dst.Set(x, y, color.NRGBAModel.Convert(avg))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,126 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// contributorsServer serves a repository with three contributors, listed
+// two per page, and their avatars
+func contributorsServer(t *testing.T, avatarBase *string) http.Handler {
+	var avatar bytes.Buffer
+	img := image.NewNRGBA(image.Rect(0, 0, 256, 256))
+	for y := 0; y < 256; y++ {
+		for x := 0; x < 256; x++ {
+			img.Set(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+	if err := png.Encode(&avatar, img); err != nil {
+		t.Fatal(err)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/numtide/treefmt":
+			fmt.Fprint(w, `{"id":1,"name":"treefmt","full_name":"numtide/treefmt","owner":{"login":"numtide"}}`)
+
+		case r.URL.Path == "/repos/numtide/treefmt/contributors":
+			perPage := r.URL.Query().Get("per_page")
+			last := map[string]string{"2": "2", "1": "3"}[perPage]
+			w.Header().Set("Link", fmt.Sprintf(`<%s?per_page=%s&page=%s>; rel="last"`, r.URL.Path, perPage, last))
+			fmt.Fprintf(w, `[{"login":"zimbatm","avatar_url":"%[1]s/avatars/1?v=4"},{"login":"brianmcgee","avatar_url":"%[1]s/avatars/missing"}]`, *avatarBase)
+
+		case r.URL.Path == "/avatars/1":
+			if r.URL.Query().Get("s") == "" {
+				t.Errorf("avatar requested without size")
+			}
+			_, _ = w.Write(avatar.Bytes())
+
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func TestGetRepositoryDataFetchesContributors(t *testing.T) {
+	var avatarBase string
+	c := newTestClientWithOptions(t, contributorsServer(t, &avatarBase), Options{CacheDuration: time.Hour, Contributors: 2})
+	avatarBase = strings.TrimSuffix(c.hosts[0].client.BaseURL.String(), "/")
+
+	data, err := c.GetRepositoryData(context.Background(), "numtide", "treefmt")
+	if err != nil {
+		t.Fatalf("GetRepositoryData failed: %v", err)
+	}
+
+	if data.ContributorCount != 3 {
+		t.Errorf("got %d contributors in total, want 3", data.ContributorCount)
+	}
+	if len(data.Contributors) != 2 {
+		t.Fatalf("got %d top contributors, want 2", len(data.Contributors))
+	}
+	if data.Contributors[0].Login != "zimbatm" || !strings.HasPrefix(data.Contributors[0].Avatar, "data:image/png;base64,") {
+		t.Errorf("unexpected first contributor: %+v", data.Contributors[0])
+	}
+	// A failed avatar download keeps the contributor without an avatar
+	if data.Contributors[1].Login != "brianmcgee" || data.Contributors[1].Avatar != "" {
+		t.Errorf("unexpected second contributor: %+v", data.Contributors[1])
+	}
+}
+
+func TestFetchAvatarRejectsHugeImages(t *testing.T) {
+	// A tiny PNG whose header claims 50000×50000 pixels
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	bomb := buf.Bytes()
+	binary.BigEndian.PutUint32(bomb[16:], 50000)
+	binary.BigEndian.PutUint32(bomb[20:], 50000)
+	binary.BigEndian.PutUint32(bomb[29:], crc32.ChecksumIEEE(bomb[12:29]))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(bomb)
+	}))
+	defer server.Close()
+
+	_, err := fetchAvatar(context.Background(), server.URL+"/avatar")
+	if err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("fetchAvatar() error = %v, want a too large error", err)
+	}
+}
+
+func TestDownsample(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			// Left half black, right half white
+			if x >= 2 {
+				img.Set(x, y, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+			} else {
+				img.Set(x, y, color.NRGBA{A: 255})
+			}
+		}
+	}
+
+	small := downsample(img, 2)
+	if small.Bounds().Dx() != 2 || small.Bounds().Dy() != 2 {
+		t.Fatalf("got size %v", small.Bounds())
+	}
+	if r, _, _, _ := small.At(0, 0).RGBA(); r != 0 {
+		t.Errorf("left pixel should be black, got red %d", r)
+	}
+	if r, _, _, _ := small.At(1, 1).RGBA(); r != 0xffff {
+		t.Errorf("right pixel should be white, got red %d", r)
+	}
+}
@@ -0,0 +1,175 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v56/github"
+	"golang.org/x/oauth2"
+)
+
+// HostOptions configures an additional GitHub host, such as a GitHub
+// Enterprise Server instance, serving a set of owners
+type HostOptions struct {
+	// BaseURL is the API URL (e.g., "https://github.example.com/api/v3/")
+	BaseURL string
+
+	// UploadURL is the uploads API URL (defaults to BaseURL)
+	UploadURL string
+
+	// Tokens are the API tokens for this host
+	Tokens []string
+
+	// Owners are the organizations and users routed to this host
+	Owners []string
+}
+
+// HostState reports the quota of one configured host
+type HostState struct {
+	Host      string         `json:"host"`
+	RateLimit RateLimitState `json:"rate_limit"`
+	Tokens    []TokenState   `json:"tokens,omitempty"`
+}
+
+// host is a GitHub API endpoint with its own credentials and quota
+type host struct {
+	// name is the API host name, used in cache keys (e.g., "api.github.com")
+	name   string
+	client *github.Client
+	rate   rateTracker
+	pool   *tokenPool
+	owners []string
+}
+
+// RequestObserver is called after every API request with the API host name,
+// the response status (0 if there was none) and the request's duration
+type RequestObserver func(host string, status int, duration time.Duration)
+
+// newHost creates a client for the API at baseURL. An empty baseURL means
+// the public api.github.com.
+func newHost(baseURL, uploadURL string, tokens []string, app *AppCredentials, observe RequestObserver) (*host, error) {
+	ctx := context.Background()
+	var tc *oauth2.TokenSource
+
+	if len(tokens) == 1 {
+		ts := oauth2.StaticTokenSource(
+			&oauth2.Token{AccessToken: tokens[0]},
+		)
+		tc = &ts
+	}
+
+	var httpClient *http.Client
+	var pool *tokenPool
+	switch {
+	case app != nil:
+		transport, err := newAppTransport(app, http.DefaultTransport, baseURL, uploadURL)
+		if err != nil {
+			return nil, err
+		}
+		httpClient = &http.Client{Transport: transport}
+	case len(tokens) > 1:
+		pool = newTokenPool(tokens, http.DefaultTransport)
+		httpClient = &http.Client{Transport: pool}
+	case tc != nil:
+		httpClient = oauth2.NewClient(ctx, *tc)
+	}
+	if observe != nil {
+		if httpClient == nil {
+			httpClient = &http.Client{}
+		}
+		httpClient.Transport = &observedTransport{base: httpClient.Transport, observe: observe}
+	}
+
+	client, err := newGitHubClient(httpClient, baseURL, uploadURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &host{
+		name:   client.BaseURL.Host,
+		client: client,
+		pool:   pool,
+	}, nil
+}
+
+// newGitHubClient creates a go-github client, pointed at a GitHub
+// Enterprise Server when baseURL is set
+func newGitHubClient(httpClient *http.Client, baseURL, uploadURL string) (*github.Client, error) {
+	client := github.NewClient(httpClient)
+	if baseURL == "" {
+		return client, nil
+	}
+
+	if uploadURL == "" {
+		uploadURL = baseURL
+	}
+	client, err := client.WithEnterpriseURLs(baseURL, uploadURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub API URL '%s': %w", baseURL, err)
+	}
+	return client, nil
+}
+
+// observedTransport reports every request to an observer
+type observedTransport struct {
+	base    http.RoundTripper
+	observe RequestObserver
+}
+
+func (t *observedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	t.observe(req.URL.Host, status, time.Since(start))
+	return resp, err
+}
+
+// serves reports whether owner is routed to this host
+func (h *host) serves(owner string) bool {
+	for _, o := range h.owners {
+		if strings.EqualFold(o, owner) {
+			return true
+		}
+	}
+	return false
+}
+
+// rateLimit returns the last known quota. With several tokens, this is
+// their combined quota.
+func (h *host) rateLimit() RateLimitState {
+	if h.pool != nil {
+		return h.pool.state(time.Now())
+	}
+	return h.rate.snapshot()
+}
+
+// state returns the quota report for this host
+func (h *host) state() HostState {
+	state := HostState{
+		Host:      h.name,
+		RateLimit: h.rateLimit(),
+	}
+	if h.pool != nil {
+		state.Tokens = h.pool.status(time.Now())
+	}
+	return state
+}
+
+// repositoryURL returns the web URL of a repository on this host
+func (h *host) repositoryURL(owner, repo string) string {
+	if h.name == "api.github.com" {
+		return fmt.Sprintf("https://github.com/%s/%s", owner, repo)
+	}
+	return fmt.Sprintf("%s://%s/%s/%s", h.client.BaseURL.Scheme, h.name, owner, repo)
+}
@@ -0,0 +1,221 @@
+package github
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TokenState reports the health of one token in a pool. It is published on
+// /health, so it carries no token material.
+type TokenState struct {
+	// Index is the token's 1-based position in the configuration
+	Index     int            `json:"index"`
+	RateLimit RateLimitState `json:"rate_limit"`
+	Exhausted bool           `json:"exhausted"`
+}
+
+// pooledToken is a token with its own rate limit state
+type pooledToken struct {
+	token string
+	rate  rateTracker
+}
+
+// available reports whether the token has quota left at now
+func (t *pooledToken) available(now time.Time) bool {
+	return !t.rate.snapshot().Exhausted(now)
+}
+
+// tokenPool is a transport that spreads requests across several tokens,
+// skipping tokens whose quota is exhausted until their reset time
+type tokenPool struct {
+	base   http.RoundTripper
+	tokens []*pooledToken
+
+	mu   sync.Mutex
+	next int
+}
+
+// newTokenPool creates a pool transport for the given tokens
+func newTokenPool(tokens []string, base http.RoundTripper) *tokenPool {
+	pool := &tokenPool{base: base}
+	for _, token := range tokens {
+		pool.tokens = append(pool.tokens, &pooledToken{token: token})
+	}
+	return pool
+}
+
+func (p *tokenPool) RoundTrip(req *http.Request) (*http.Response, error) {
+	tried := make(map[*pooledToken]bool)
+
+	for {
+		token := p.pick(time.Now(), tried)
+		tried[token] = true
+
+		attempt := req.Clone(req.Context())
+		attempt.Header.Set("Authorization", "Bearer "+token.token)
+		if req.Body != nil && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attempt.Body = body
+		}
+
+		resp, err := p.base.RoundTrip(attempt)
+		if err != nil {
+			return nil, err
+		}
+		token.rate.update(parseRate(resp.Header))
+
+		reset, limited := rateLimitedResponse(resp)
+		if !limited {
+			p.rewriteRate(resp.Header, time.Now())
+			return resp, nil
+		}
+		token.rate.exhaust(reset)
+
+		// Retry with another token if one has quota left and the request
+		// can be replayed
+		canReplay := req.Body == nil || req.GetBody != nil
+		if !canReplay || !p.hasAvailable(time.Now(), tried) {
+			p.rewriteRate(resp.Header, time.Now())
+			return resp, nil
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+}
+
+// rewriteRate replaces the quota of the token that served a response with
+// the limit and quota left across the pool. go-github refuses requests locally while
+// the last reported quota is used up, which must only happen once every
+// token is exhausted.
+func (p *tokenPool) rewriteRate(header http.Header, now time.Time) {
+	if header.Get("X-RateLimit-Remaining") == "" {
+		return
+	}
+
+	// Unused tokens are assumed to have the limit of this response
+	responseLimit, _ := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+
+	limit, remaining := 0, 0
+	var reset time.Time
+	for _, token := range p.tokens {
+		rate := token.rate.snapshot()
+		switch {
+		case !rate.Known:
+			limit += responseLimit
+			remaining += max(responseLimit, 1)
+		case !now.Before(rate.Reset):
+			limit += rate.Limit
+			remaining += max(rate.Limit, 1)
+		default:
+			limit += rate.Limit
+			remaining += rate.Remaining
+			if reset.IsZero() || rate.Reset.Before(reset) {
+				reset = rate.Reset
+			}
+		}
+	}
+
+	if limit > 0 {
+		header.Set("X-RateLimit-Limit", strconv.Itoa(limit))
+	}
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	if !reset.IsZero() {
+		header.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	}
+}
+
+// pick returns the next token with quota left in round-robin order. If
+// every token is exhausted, the one that resets first is returned.
+func (p *tokenPool) pick(now time.Time, exclude map[*pooledToken]bool) *pooledToken {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i := range p.tokens {
+		token := p.tokens[(p.next+i)%len(p.tokens)]
+		if !exclude[token] && token.available(now) {
+			p.next = (p.next + i + 1) % len(p.tokens)
+			return token
+		}
+	}
+
+	soonest := p.tokens[0]
+	for _, token := range p.tokens[1:] {
+		if token.rate.snapshot().Reset.Before(soonest.rate.snapshot().Reset) {
+			soonest = token
+		}
+	}
+	return soonest
+}
+
+// hasAvailable reports whether a token outside exclude has quota left
+func (p *tokenPool) hasAvailable(now time.Time, exclude map[*pooledToken]bool) bool {
+	for _, token := range p.tokens {
+		if !exclude[token] && token.available(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// state returns the combined quota of all tokens. It is only known once
+// every token has been used.
+func (p *tokenPool) state(now time.Time) RateLimitState {
+	combined := RateLimitState{Known: true}
+	for _, token := range p.tokens {
+		rate := token.rate.snapshot()
+		if !rate.Known {
+			return RateLimitState{}
+		}
+
+		combined.Limit += rate.Limit
+		if now.Before(rate.Reset) {
+			combined.Remaining += rate.Remaining
+		} else {
+			combined.Remaining += rate.Limit
+		}
+		if combined.Reset.IsZero() || rate.Reset.Before(combined.Reset) {
+			combined.Reset = rate.Reset
+		}
+	}
+	return combined
+}
+
+// status returns the health of each token
+func (p *tokenPool) status(now time.Time) []TokenState {
+	states := make([]TokenState, 0, len(p.tokens))
+	for i, token := range p.tokens {
+		states = append(states, TokenState{
+			Index:     i + 1,
+			RateLimit: token.rate.snapshot(),
+			Exhausted: !token.available(now),
+		})
+	}
+	return states
+}
+
+// rateLimitedResponse reports whether resp was rejected by a primary or
+// secondary rate limit, and when the limit lifts
+func rateLimitedResponse(resp *http.Response) (time.Time, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return time.Time{}, false
+	}
+
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return time.Now().Add(time.Duration(seconds) * time.Second), true
+		}
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		rate := parseRate(resp.Header)
+		return rate.Reset.Time, true
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,135 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeQuotaServer is a local GitHub API that gives each token a fixed quota
+type fakeQuotaServer struct {
+	mu    sync.Mutex
+	quota map[string]int
+	used  map[string]int
+}
+
+func (f *fakeQuotaServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	f.mu.Lock()
+	allowed := f.used[token] < f.quota[token]
+	if allowed {
+		f.used[token]++
+	}
+	remaining := f.quota[token] - f.used[token]
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-RateLimit-Limit", fmt.Sprint(f.quota[token]))
+	w.Header().Set("X-RateLimit-Remaining", fmt.Sprint(remaining))
+	w.Header().Set("X-RateLimit-Reset", fmt.Sprint(time.Now().Add(time.Hour).Unix()))
+
+	if !allowed {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message":"API rate limit exceeded"}`)
+		return
+	}
+	fmt.Fprint(w, `{"description":"ok"}`)
+}
+
+func newPoolTestClient(t *testing.T, quota map[string]int) (*Client, *fakeQuotaServer) {
+	t.Helper()
+
+	fake := &fakeQuotaServer{quota: quota, used: make(map[string]int)}
+
+	var tokens []string
+	for token := range quota {
+		tokens = append(tokens, token)
+	}
+
+	c := newTestClientWithOptions(t, fake, Options{Tokens: tokens, CacheDuration: time.Hour, LowQuotaPercent: 1})
+	return c, fake
+}
+
+func TestTokenPoolSpreadsRequests(t *testing.T) {
+	c, fake := newPoolTestClient(t, map[string]int{"token-a": 10, "token-b": 10})
+
+	for i := 0; i < 4; i++ {
+		if _, err := c.GetRepositoryData(context.Background(), "owner", fmt.Sprintf("repo%d", i)); err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+	}
+
+	if fake.used["token-a"] != 2 || fake.used["token-b"] != 2 {
+		t.Errorf("requests were not spread evenly: %v", fake.used)
+	}
+
+	state := c.RateLimit()
+	if !state.Known || state.Limit != 20 || state.Remaining != 16 {
+		t.Errorf("unexpected combined rate limit: %+v", state)
+	}
+}
+
+func TestTokenPoolSkipsExhaustedTokens(t *testing.T) {
+	// token-a has no quota left, which the pool only learns on first use
+	c, fake := newPoolTestClient(t, map[string]int{"token-a": 0, "token-b": 10})
+
+	for i := 0; i < 4; i++ {
+		data, err := c.GetRepositoryData(context.Background(), "owner", fmt.Sprintf("repo%d", i))
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+		if data.Description != "ok" {
+			t.Errorf("request %d got description %q", i, data.Description)
+		}
+	}
+
+	if fake.used["token-b"] != 4 {
+		t.Errorf("token-b served %d requests, want 4", fake.used["token-b"])
+	}
+
+	for i, state := range c.TokenStatus() {
+		exhausted := c.hosts[0].pool.tokens[i].token == "token-a"
+		if state.Exhausted != exhausted {
+			t.Errorf("token %d: exhausted = %v, want %v", state.Index, state.Exhausted, exhausted)
+		}
+	}
+}
+
+func TestTokenPoolLastRequestOfWindow(t *testing.T) {
+	// token-a succeeds with its last request, reporting no quota left;
+	// later requests must go to token-b rather than fail
+	c, fake := newPoolTestClient(t, map[string]int{"token-a": 1, "token-b": 10})
+
+	for i := 0; i < 4; i++ {
+		if _, err := c.GetRepositoryData(context.Background(), "owner", fmt.Sprintf("repo%d", i)); err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+	}
+
+	if fake.used["token-a"] != 1 || fake.used["token-b"] != 3 {
+		t.Errorf("unexpected token usage: %v", fake.used)
+	}
+	// The pool reports the limit and quota of all tokens together
+	if rate := c.RateLimit(); rate.Limit != 11 || rate.Remaining != 7 {
+		t.Errorf("got pool rate limit %d/%d, want 7/11", rate.Remaining, rate.Limit)
+	}
+}
+
+func TestTokenPoolAllExhausted(t *testing.T) {
+	c, _ := newPoolTestClient(t, map[string]int{"token-a": 0, "token-b": 0})
+
+	_, err := c.GetRepositoryData(context.Background(), "owner", "repo")
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("got error %v, want ErrRateLimited", err)
+	}
+
+	if !c.RateLimit().Exhausted(time.Now()) {
+		t.Errorf("combined rate limit should be exhausted: %+v", c.RateLimit())
+	}
+}
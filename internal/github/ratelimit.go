@@ -0,0 +1,108 @@
+package github
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v56/github"
+	"github.com/numtide/banner-generator/internal/forge"
+)
+
+// DefaultLowQuotaPercent is the share of the rate limit, in percent, below
+// which cached data is preferred over fresh API calls
+const DefaultLowQuotaPercent = 2.0
+
+// ErrRateLimited is returned when the GitHub API quota is exhausted and no
+// cached data is available
+var ErrRateLimited = forge.ErrRateLimited
+
+// RateLimitState is a snapshot of the GitHub API quota
+type RateLimitState struct {
+	// Known is false until the first API response has been seen
+	Known     bool      `json:"known"`
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+// Exhausted reports whether no requests are left until the reset time
+func (s RateLimitState) Exhausted(now time.Time) bool {
+	return s.Known && s.Remaining <= 0 && now.Before(s.Reset)
+}
+
+// Low reports whether fewer than percent of the limit's requests are left
+// until the reset time
+func (s RateLimitState) Low(now time.Time, percent float64) bool {
+	return s.Known && float64(s.Remaining) < float64(s.Limit)*percent/100 && now.Before(s.Reset)
+}
+
+// rateTracker records the quota reported in API response headers
+type rateTracker struct {
+	mu    sync.RWMutex
+	state RateLimitState
+}
+
+// update records the rate limit from a response
+func (t *rateTracker) update(rate github.Rate) {
+	if rate.Limit == 0 {
+		// Headers were missing (e.g. a network error)
+		return
+	}
+
+	t.mu.Lock()
+	t.state = RateLimitState{
+		Known:     true,
+		Limit:     rate.Limit,
+		Remaining: rate.Remaining,
+		Reset:     rate.Reset.Time,
+	}
+	t.mu.Unlock()
+}
+
+// exhaust marks the quota as used up until reset
+func (t *rateTracker) exhaust(reset time.Time) {
+	t.mu.Lock()
+	t.state.Known = true
+	t.state.Remaining = 0
+	if reset.After(t.state.Reset) {
+		t.state.Reset = reset
+	}
+	t.mu.Unlock()
+}
+
+// snapshot returns the current quota state
+func (t *rateTracker) snapshot() RateLimitState {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.state
+}
+
+// rateLimitReset extracts the reset time from a go-github rate limit error.
+// The boolean is false if err is not a rate limit error.
+func rateLimitReset(err error) (time.Time, bool) {
+	var rateErr *github.RateLimitError
+	if errors.As(err, &rateErr) {
+		return rateErr.Rate.Reset.Time, true
+	}
+
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) {
+		return time.Now().Add(abuseErr.GetRetryAfter()), true
+	}
+
+	return time.Time{}, false
+}
+
+// parseRate reads the rate limit headers of an API response
+func parseRate(header http.Header) github.Rate {
+	var rate github.Rate
+	rate.Limit, _ = strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	rate.Remaining, _ = strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		rate.Reset = github.Timestamp{Time: time.Unix(reset, 0)}
+	}
+	return rate
+}
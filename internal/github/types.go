@@ -1,11 +1,6 @@
 package github
 
+import "github.com/numtide/banner-generator/internal/forge"
+
 // Repository contains GitHub repository information
-type Repository struct {
-	Name            string
-	Description     string
-	Owner           string
-	Language        string
-	StargazersCount int
-	ForksCount      int
-}
+type Repository = forge.Repository
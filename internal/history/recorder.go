@@ -0,0 +1,63 @@
+package history
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/numtide/banner-generator/internal/forge"
+)
+
+// recordingProvider records the counts fetched by a provider and attaches
+// the recent history to the repositories it returns
+type recordingProvider struct {
+	forge.Provider
+	store    *Store
+	interval time.Duration
+	window   time.Duration
+}
+
+// Recording wraps a provider so every fetch records a sample, at most one
+// per interval unless the counts change, and returned repositories carry
+// the samples of the last window. Older samples are deleted.
+func Recording(p forge.Provider, store *Store, interval, window time.Duration) forge.Provider {
+	return &recordingProvider{
+		Provider: p,
+		store:    store,
+		interval: interval,
+		window:   window,
+	}
+}
+
+// GetRepositoryData fetches repository metadata and its recorded history
+func (r *recordingProvider) GetRepositoryData(ctx context.Context, owner, repo string) (*forge.Repository, error) {
+	data, err := r.Provider.GetRepositoryData(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prefer the canonical name so renamed repositories keep their history
+	key := Key(r.Name(), owner, repo)
+	if canonicalOwner, canonicalRepo, ok := strings.Cut(data.FullName, "/"); ok {
+		key = Key(r.Name(), canonicalOwner, canonicalRepo)
+	}
+	now := time.Now()
+
+	// History is decoration; failures only cost the sparkline
+	sample := Sample{Time: now, Stars: data.StargazersCount, Forks: data.ForksCount}
+	if err := r.store.Record(key, sample, r.interval, r.window); err != nil {
+		slog.WarnContext(ctx, "Failed to record history", "key", key, "error", err)
+	}
+
+	samples, err := r.store.Samples(key, now.Add(-r.window))
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to read history", "key", key, "error", err)
+		return data, nil
+	}
+
+	// Providers may share the returned repository with other callers
+	withHistory := *data
+	withHistory.History = samples
+	return &withHistory, nil
+}
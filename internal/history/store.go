@@ -0,0 +1,269 @@
+// Package history records star and fork counts over time, since forges
+// offer no cheap way to query their history.
+package history
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/numtide/banner-generator/internal/forge"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Sample is a star and fork count recorded at a point in time
+type Sample = forge.HistorySample
+
+// csvHeader is the first row of exported history
+var csvHeader = []string{"repository", "time", "stars", "forks"}
+
+// Store keeps samples in a single embedded database file, with one bucket
+// per repository keyed by sample time
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens (or creates) the history database at path
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history database: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Key identifies a repository in the store (e.g., "github:numtide/treefmt")
+func Key(forgeName, owner, repo string) string {
+	return strings.ToLower(forgeName + ":" + owner + "/" + repo)
+}
+
+// Record stores a sample unless the latest sample is more recent than
+// interval and has the same counts. Samples older than window before it,
+// which Samples no longer returns, are deleted unless window is zero.
+func (s *Store) Record(key string, sample Sample, interval, window time.Duration) error {
+	// Most fetches repeat the latest sample; check without a write transaction
+	var skip bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		skip = isRedundant(tx.Bucket([]byte(key)), sample, interval)
+		return nil
+	})
+	if err != nil || skip {
+		return err
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(key))
+		if err != nil {
+			return err
+		}
+		if isRedundant(bucket, sample, interval) {
+			return nil
+		}
+
+		k, v := encodeSample(sample)
+		if err := bucket.Put(k, v); err != nil {
+			return err
+		}
+		if window > 0 {
+			return prune(bucket, sample.Time.Add(-window))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record history: %w", err)
+	}
+	return nil
+}
+
+// prune deletes the samples in bucket before cutoff, except the latest one
+// that Samples returns as the baseline of a window starting at cutoff
+func prune(bucket *bolt.Bucket, cutoff time.Time) error {
+	start, _ := encodeSample(Sample{Time: cutoff})
+	c := bucket.Cursor()
+	c.Seek(start)
+	baseline, _ := c.Prev()
+	if baseline == nil {
+		return nil
+	}
+
+	// Collect the keys first, since deleting moves the cursor
+	var old [][]byte
+	for k, _ := c.First(); k != nil && bytes.Compare(k, baseline) < 0; k, _ = c.Next() {
+		old = append(old, bytes.Clone(k))
+	}
+	for _, k := range old {
+		if err := bucket.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isRedundant reports whether the latest sample in bucket has the same
+// counts as sample and is less than interval older
+func isRedundant(bucket *bolt.Bucket, sample Sample, interval time.Duration) bool {
+	if bucket == nil {
+		return false
+	}
+	k, v := bucket.Cursor().Last()
+	if k == nil {
+		return false
+	}
+	last := decodeSample(k, v)
+	unchanged := last.Stars == sample.Stars && last.Forks == sample.Forks
+	return unchanged && sample.Time.Sub(last.Time) < interval
+}
+
+// Samples returns the samples of a repository recorded since the given
+// time, oldest first. The latest sample before since is included as a
+// baseline.
+func (s *Store) Samples(key string, since time.Time) ([]Sample, error) {
+	var samples []Sample
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(key))
+		if bucket == nil {
+			return nil
+		}
+
+		start, _ := encodeSample(Sample{Time: since})
+		c := bucket.Cursor()
+		c.Seek(start)
+		if k, v := c.Prev(); k != nil {
+			samples = append(samples, decodeSample(k, v))
+		}
+
+		// Seek again, since Prev moved the cursor
+		for k, v := c.Seek(start); k != nil; k, v = c.Next() {
+			samples = append(samples, decodeSample(k, v))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return samples, nil
+}
+
+// Export writes all samples as CSV with a repository, time, stars and
+// forks column
+func (s *Store) Export(w io.Writer) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(csvHeader); err != nil {
+		return err
+	}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			return bucket.ForEach(func(k, v []byte) error {
+				sample := decodeSample(k, v)
+				return out.Write([]string{
+					string(name),
+					sample.Time.UTC().Format(time.RFC3339),
+					strconv.Itoa(sample.Stars),
+					strconv.Itoa(sample.Forks),
+				})
+			})
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export history: %w", err)
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// Import reads samples in the format written by Export and returns how many
+// were stored. Samples at an existing time replace the stored ones.
+func (s *Store) Import(r io.Reader) (int, error) {
+	in := csv.NewReader(r)
+	in.FieldsPerRecord = len(csvHeader)
+
+	records, err := in.ReadAll()
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse history CSV: %w", err)
+	}
+	if len(records) > 0 && records[0][0] == csvHeader[0] {
+		records = records[1:]
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		for i, record := range records {
+			sample, err := parseRecord(record)
+			if err != nil {
+				// Line numbers count the header
+				return fmt.Errorf("line %d: %w", i+2, err)
+			}
+
+			bucket, err := tx.CreateBucketIfNotExists([]byte(strings.ToLower(record[0])))
+			if err != nil {
+				return err
+			}
+			k, v := encodeSample(sample)
+			if err := bucket.Put(k, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to import history: %w", err)
+	}
+	return len(records), nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// parseRecord parses the time, stars and forks columns of a CSV record
+func parseRecord(record []string) (Sample, error) {
+	t, err := time.Parse(time.RFC3339, record[1])
+	if err != nil {
+		return Sample{}, fmt.Errorf("invalid time '%s': %w", record[1], err)
+	}
+	stars, err := strconv.Atoi(record[2])
+	if err != nil {
+		return Sample{}, fmt.Errorf("invalid star count '%s': %w", record[2], err)
+	}
+	forks, err := strconv.Atoi(record[3])
+	if err != nil {
+		return Sample{}, fmt.Errorf("invalid fork count '%s': %w", record[3], err)
+	}
+	return Sample{Time: t, Stars: stars, Forks: forks}, nil
+}
+
+// encodeSample encodes a sample as a big-endian Unix time key, so samples
+// sort chronologically, and a value of two big-endian counts
+func encodeSample(sample Sample) (key, value []byte) {
+	key = make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(sample.Time.Unix()))
+
+	value = make([]byte, 16)
+	binary.BigEndian.PutUint64(value[:8], uint64(sample.Stars))
+	binary.BigEndian.PutUint64(value[8:], uint64(sample.Forks))
+	return key, value
+}
+
+// decodeSample reverses encodeSample
+func decodeSample(key, value []byte) Sample {
+	sample := Sample{Time: time.Unix(int64(binary.BigEndian.Uint64(key)), 0)}
+	if len(value) == 16 {
+		sample.Stars = int(binary.BigEndian.Uint64(value[:8]))
+		sample.Forks = int(binary.BigEndian.Uint64(value[8:]))
+	}
+	return sample
+}
@@ -0,0 +1,158 @@
+package history
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := Open(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func TestRecordSkipsUnchangedSamples(t *testing.T) {
+	store := openTestStore(t)
+	start := time.Unix(1_700_000_000, 0)
+	key := Key("github", "Numtide", "treefmt")
+
+	for _, sample := range []Sample{
+		{Time: start, Stars: 10, Forks: 1},
+		{Time: start.Add(10 * time.Minute), Stars: 10, Forks: 1}, // unchanged within interval
+		{Time: start.Add(20 * time.Minute), Stars: 11, Forks: 1}, // changed
+		{Time: start.Add(2 * time.Hour), Stars: 11, Forks: 1},    // interval elapsed
+	} {
+		if err := store.Record(key, sample, time.Hour, 0); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	samples, err := store.Samples(key, start)
+	if err != nil {
+		t.Fatalf("Samples failed: %v", err)
+	}
+	expected := []Sample{
+		{Time: start, Stars: 10, Forks: 1},
+		{Time: start.Add(20 * time.Minute), Stars: 11, Forks: 1},
+		{Time: start.Add(2 * time.Hour), Stars: 11, Forks: 1},
+	}
+	if !reflect.DeepEqual(samples, expected) {
+		t.Errorf("Samples() = %v, expected %v", samples, expected)
+	}
+}
+
+func TestSamplesIncludesBaseline(t *testing.T) {
+	store := openTestStore(t)
+	start := time.Unix(1_700_000_000, 0)
+	key := Key("github", "numtide", "treefmt")
+
+	for i := range 3 {
+		sample := Sample{Time: start.Add(time.Duration(i) * 24 * time.Hour), Stars: i}
+		if err := store.Record(key, sample, time.Hour, 0); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		since time.Time
+		stars []int
+	}{
+		{"before all samples", start.Add(-time.Hour), []int{0, 1, 2}},
+		{"between samples", start.Add(36 * time.Hour), []int{1, 2}},
+		{"after all samples", start.Add(72 * time.Hour), []int{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samples, err := store.Samples(key, tt.since)
+			if err != nil {
+				t.Fatalf("Samples failed: %v", err)
+			}
+			var stars []int
+			for _, sample := range samples {
+				stars = append(stars, sample.Stars)
+			}
+			if !reflect.DeepEqual(stars, tt.stars) {
+				t.Errorf("Samples() stars = %v, expected %v", stars, tt.stars)
+			}
+		})
+	}
+
+	if samples, err := store.Samples(Key("github", "numtide", "unknown"), start); err != nil || samples != nil {
+		t.Errorf("Samples() of unknown repository = %v, %v", samples, err)
+	}
+}
+
+func TestRecordPrunesOldSamples(t *testing.T) {
+	store := openTestStore(t)
+	start := time.Unix(1_700_000_000, 0)
+	key := Key("github", "numtide", "treefmt")
+	window := 48 * time.Hour
+
+	for i := range 5 {
+		sample := Sample{Time: start.Add(time.Duration(i) * 24 * time.Hour), Stars: i}
+		if err := store.Record(key, sample, time.Hour, window); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	// The window starts at the third sample, so the second is kept as its
+	// baseline and the first is deleted
+	samples, err := store.Samples(key, start.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Samples failed: %v", err)
+	}
+	var stars []int
+	for _, sample := range samples {
+		stars = append(stars, sample.Stars)
+	}
+	if expected := []int{1, 2, 3, 4}; !reflect.DeepEqual(stars, expected) {
+		t.Errorf("stored stars = %v, expected %v", stars, expected)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	source := openTestStore(t)
+	start := time.Unix(1_700_000_000, 0)
+	for _, key := range []string{Key("github", "numtide", "treefmt"), Key("gitlab", "group/sub", "project")} {
+		for i := range 2 {
+			sample := Sample{Time: start.Add(time.Duration(i) * time.Hour), Stars: 10 + i, Forks: i}
+			if err := source.Record(key, sample, 0, 0); err != nil {
+				t.Fatalf("Record failed: %v", err)
+			}
+		}
+	}
+
+	var exported bytes.Buffer
+	if err := source.Export(&exported); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	target := openTestStore(t)
+	count, err := target.Import(bytes.NewReader(exported.Bytes()))
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if count != 4 {
+		t.Errorf("Import() = %d, expected 4", count)
+	}
+
+	var reexported bytes.Buffer
+	if err := target.Export(&reexported); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if reexported.String() != exported.String() {
+		t.Errorf("round trip changed history:\n%s\nexpected:\n%s", reexported.String(), exported.String())
+	}
+
+	if _, err := target.Import(bytes.NewBufferString("repository,time,stars,forks\ngithub:a/b,yesterday,1,1\n")); err == nil {
+		t.Error("Import() accepted an invalid time")
+	}
+}
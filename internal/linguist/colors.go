@@ -0,0 +1,19 @@
+// Package linguist provides the language colors used by GitHub, from a
+// bundled copy of the color table in Linguist's languages.yml.
+package linguist
+
+//go:generate go run gen.go -version v7.29.0
+
+import "strings"
+
+// DefaultColor is used for languages without a color, like GitHub's "Other"
+const DefaultColor = "#ededed"
+
+// Color returns the Linguist color of a language, or DefaultColor if it
+// has none
+func Color(language string) string {
+	if color, ok := colors[strings.ToLower(language)]; ok {
+		return color
+	}
+	return DefaultColor
+}
@@ -0,0 +1,134 @@
+// Partial table of Linguist's colors, in the form gen.go writes; run
+// go generate to replace it with the full table.
+
+package linguist
+
+// colors maps lowercase language names to their Linguist color
+var colors = map[string]string{
+	"actionscript":      "#882b0f",
+	"ada":               "#02f88c",
+	"agda":              "#315665",
+	"apex":              "#1797c0",
+	"assembly":          "#6e4c13",
+	"astro":             "#ff5a03",
+	"awk":               "#c30e9b",
+	"ballerina":         "#ff5000",
+	"batchfile":         "#c1f12e",
+	"bicep":             "#519aba",
+	"c":                 "#555555",
+	"c#":                "#178600",
+	"c++":               "#f34b7d",
+	"chapel":            "#8dc63f",
+	"clojure":           "#db5855",
+	"cmake":             "#da3434",
+	"coffeescript":      "#244776",
+	"common lisp":       "#3fb68b",
+	"coq":               "#d0b68c",
+	"crystal":           "#000100",
+	"css":               "#563d7c",
+	"cuda":              "#3a4e3a",
+	"cython":            "#fedf5b",
+	"d":                 "#ba595e",
+	"dart":              "#00b4ab",
+	"dhall":             "#dfafff",
+	"dockerfile":        "#384d54",
+	"ejs":               "#a91e50",
+	"elixir":            "#6e4a7e",
+	"elm":               "#60b5cc",
+	"emacs lisp":        "#c065db",
+	"erlang":            "#b83998",
+	"f#":                "#b845fc",
+	"fennel":            "#fff3d7",
+	"forth":             "#341708",
+	"fortran":           "#4d41b1",
+	"gleam":             "#ffaff3",
+	"glsl":              "#5686a5",
+	"go":                "#00add8",
+	"groovy":            "#4298b8",
+	"handlebars":        "#f7931e",
+	"hare":              "#9d7424",
+	"haskell":           "#5e5086",
+	"haxe":              "#df7900",
+	"hcl":               "#844fba",
+	"hlsl":              "#aace60",
+	"html":              "#e34c26",
+	"idris":             "#b30000",
+	"io":                "#a9188d",
+	"janet":             "#0886a5",
+	"java":              "#b07219",
+	"javascript":        "#f1e05a",
+	"jinja":             "#a52a22",
+	"jsonnet":           "#0064bd",
+	"julia":             "#a270ba",
+	"jupyter notebook":  "#da5b0b",
+	"just":              "#384d54",
+	"kotlin":            "#a97bff",
+	"less":              "#1d365d",
+	"lua":               "#000080",
+	"makefile":          "#427819",
+	"markdown":          "#083fa1",
+	"mdx":               "#fcb32c",
+	"meson":             "#007800",
+	"mustache":          "#724b3b",
+	"nextflow":          "#3ac486",
+	"nim":               "#ffc200",
+	"nix":               "#7e7eff",
+	"nunjucks":          "#3d8137",
+	"nushell":           "#4e9906",
+	"objective-c":       "#438eff",
+	"objective-c++":     "#6866fb",
+	"ocaml":             "#ef7a08",
+	"odin":              "#60affe",
+	"pascal":            "#e3f171",
+	"perl":              "#0298c3",
+	"php":               "#4f5d95",
+	"plpgsql":           "#336790",
+	"powershell":        "#012456",
+	"processing":        "#0096d8",
+	"prolog":            "#74283c",
+	"pug":               "#a86454",
+	"purescript":        "#1d222d",
+	"python":            "#3572a5",
+	"qml":               "#44a51c",
+	"r":                 "#198ce7",
+	"racket":            "#3c5caa",
+	"raku":              "#0000fb",
+	"reason":            "#ff5847",
+	"red":               "#f50000",
+	"rescript":          "#ed5051",
+	"roff":              "#ecdebe",
+	"ruby":              "#701516",
+	"rust":              "#dea584",
+	"sass":              "#a53b70",
+	"scala":             "#c22d40",
+	"scheme":            "#1e4aec",
+	"scss":              "#c6538c",
+	"shaderlab":         "#222c37",
+	"shell":             "#89e051",
+	"smalltalk":         "#596706",
+	"smarty":            "#f0c040",
+	"solidity":          "#aa6746",
+	"standard ml":       "#dc566d",
+	"starlark":          "#76d275",
+	"stylus":            "#ff6347",
+	"svelte":            "#ff3e00",
+	"swift":             "#f05138",
+	"systemverilog":     "#dae1c2",
+	"tcl":               "#e4cc98",
+	"tex":               "#3d6117",
+	"tsql":              "#e38c00",
+	"twig":              "#c1d026",
+	"typescript":        "#3178c6",
+	"typst":             "#239dad",
+	"v":                 "#4f87c4",
+	"vala":              "#a56de2",
+	"verilog":           "#b2b7f8",
+	"vhdl":              "#adb2cb",
+	"vim script":        "#199f4b",
+	"visual basic .net": "#945db7",
+	"vue":               "#41b883",
+	"webassembly":       "#04133b",
+	"xslt":              "#eb8ceb",
+	"yaml":              "#cb171e",
+	"zig":               "#ec915c",
+}
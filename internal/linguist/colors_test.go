@@ -0,0 +1,23 @@
+package linguist
+
+import "testing"
+
+func TestColor(t *testing.T) {
+	tests := []struct {
+		language string
+		expected string
+	}{
+		{"Go", "#00add8"},
+		{"c++", "#f34b7d"},
+		{"Jupyter Notebook", "#da5b0b"},
+		{"YAML", "#cb171e"},
+		{"Just", "#384d54"},
+		{"Brainfuck-NG", DefaultColor},
+	}
+
+	for _, tt := range tests {
+		if got := Color(tt.language); got != tt.expected {
+			t.Errorf("Color(%q) = %q, want %q", tt.language, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+//go:build ignore
+
+// gen.go writes colors_gen.go from the color table of Linguist's
+// languages.yml at the given release:
+//
+//	go run gen.go -version v7.29.0
+//
+// -input reads a local copy of languages.yml instead of fetching it.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const sourceURL = "https://raw.githubusercontent.com/github-linguist/linguist/%s/lib/linguist/languages.yml"
+
+func main() {
+	version := flag.String("version", "", "Linguist release to read languages.yml from")
+	input := flag.String("input", "", "local copy of languages.yml to read instead")
+	output := flag.String("output", "colors_gen.go", "file to write")
+	flag.Parse()
+	if *version == "" {
+		log.Fatal("-version is required")
+	}
+
+	source, err := open(*input, *version)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer source.Close()
+
+	colors, err := parseColors(source)
+	if err != nil {
+		log.Fatalf("failed to parse languages.yml: %v", err)
+	}
+
+	names := make([]string, 0, len(colors))
+	for name := range colors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by gen.go from Linguist %s; DO NOT EDIT.\n\n", *version)
+	fmt.Fprintf(&buf, "package linguist\n\n")
+	fmt.Fprintf(&buf, "// colors maps lowercase language names to their Linguist color\n")
+	fmt.Fprintf(&buf, "var colors = map[string]string{\n")
+	for _, name := range names {
+		fmt.Fprintf(&buf, "\t%q: %q,\n", name, colors[name])
+	}
+	fmt.Fprintf(&buf, "}\n")
+
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatalf("failed to format output: %v", err)
+	}
+	if err := os.WriteFile(*output, formatted, 0o644); err != nil {
+		log.Fatalf("failed to write %s: %v", *output, err)
+	}
+}
+
+// open returns the local copy of languages.yml at path, or fetches the one
+// of the given release
+func open(path, version string) (io.ReadCloser, error) {
+	if path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open languages.yml: %w", err)
+		}
+		return file, nil
+	}
+
+	url := fmt.Sprintf(sourceURL, version)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch languages.yml: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// parseColors reads the color of every language in languages.yml, keyed by
+// its lowercase name. Languages are the unindented keys; their color is an
+// indented "color:" line.
+func parseColors(r io.Reader) (map[string]string, error) {
+	colors := make(map[string]string)
+	var language string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || line == "---":
+		case !strings.HasPrefix(line, " "):
+			name, ok := strings.CutSuffix(line, ":")
+			if !ok {
+				return nil, fmt.Errorf("unexpected line %q", line)
+			}
+			language = strings.ToLower(unquote(name))
+		case strings.HasPrefix(strings.TrimSpace(line), "color:"):
+			if language == "" {
+				return nil, fmt.Errorf("color outside a language: %q", line)
+			}
+			color := unquote(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "color:")))
+			colors[language] = strings.ToLower(color)
+		}
+	}
+	return colors, scanner.Err()
+}
+
+// unquote removes YAML quotes around s
+func unquote(s string) string {
+	if strings.HasPrefix(s, `"`) {
+		if unquoted, err := strconv.Unquote(s); err == nil {
+			return unquoted
+		}
+	}
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	}
+	return s
+}
@@ -0,0 +1,142 @@
+// Package logging sets up structured logging with log/slog and carries the
+// fields of a request, such as its ID, owner and repository, through its
+// context into every log record written for it.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Output formats accepted in configuration
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// New creates a logger writing records of at least level ("debug", "info",
+// "warn" or "error") to w in format ("text" or "json"). Records logged with
+// a request context include the request's fields.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level '%s' (use debug, info, warn or error)", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		handler = slog.NewTextHandler(w, opts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("invalid log format '%s' (use text or json)", format)
+	}
+	return slog.New(&contextHandler{Handler: handler}), nil
+}
+
+// Setup makes a logger writing to stderr the default, which the log package
+// writes through as well
+func Setup(level, format string) error {
+	logger, err := New(os.Stderr, level, format)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+// fields are the attributes of one request, shared by the contexts derived
+// from its own
+type fields struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+type fieldsKey struct{}
+
+// NewContext returns a context for the request with the given ID. Fields
+// added to it or to contexts derived from it appear in all of the request's
+// log records.
+func NewContext(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, &fields{
+		attrs: []slog.Attr{slog.String("request_id", requestID)},
+	})
+}
+
+// RequestID returns the ID of the request ctx belongs to, or "" outside a
+// request
+func RequestID(ctx context.Context) string {
+	for _, attr := range Attrs(ctx) {
+		if attr.Key == "request_id" {
+			return attr.Value.String()
+		}
+	}
+	return ""
+}
+
+// Add sets fields of the request ctx belongs to, as alternating keys and
+// values like slog.Info takes them. A field set again replaces the earlier
+// value. Outside a request, Add does nothing.
+func Add(ctx context.Context, args ...any) {
+	f, ok := ctx.Value(fieldsKey{}).(*fields)
+	if !ok {
+		return
+	}
+
+	record := slog.NewRecord(time.Time{}, 0, "", 0)
+	record.Add(args...)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	record.Attrs(func(attr slog.Attr) bool {
+		for i := range f.attrs {
+			if f.attrs[i].Key == attr.Key {
+				f.attrs[i] = attr
+				return true
+			}
+		}
+		f.attrs = append(f.attrs, attr)
+		return true
+	})
+}
+
+// Attrs returns the fields of the request ctx belongs to
+func Attrs(ctx context.Context) []slog.Attr {
+	f, ok := ctx.Value(fieldsKey{}).(*fields)
+	if !ok {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]slog.Attr(nil), f.attrs...)
+}
+
+// contextHandler adds the request's fields to records logged with its
+// context
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if attrs := Attrs(ctx); len(attrs) > 0 {
+		record = record.Clone()
+		record.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
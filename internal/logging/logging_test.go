@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		level   string
+		format  string
+		wantErr bool
+	}{
+		{"info", "text", false},
+		{"DEBUG", "json", false},
+		{"warn", "", false},
+		{"verbose", "text", true},
+		{"info", "xml", true},
+	}
+
+	for _, tt := range tests {
+		_, err := New(&bytes.Buffer{}, tt.level, tt.format)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("New(%q, %q) error = %v, want error: %v", tt.level, tt.format, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRequestFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", FormatJSON)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	ctx := NewContext(context.Background(), "abc123")
+	Add(ctx, "owner", "numtide", "cache", "miss")
+
+	// Fields reach records logged with derived contexts, and records logged
+	// before a field changes keep the old value
+	derived, cancel := context.WithCancel(ctx)
+	defer cancel()
+	logger.InfoContext(derived, "first")
+	Add(derived, "cache", "hit")
+	logger.DebugContext(ctx, "filtered")
+	logger.InfoContext(ctx, "second", "bytes", 42)
+	logger.Info("outside")
+
+	var records []map[string]any
+	decoder := json.NewDecoder(&buf)
+	for decoder.More() {
+		var record map[string]any
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatalf("invalid JSON output: %v", err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3", len(records))
+	}
+
+	want := []map[string]any{
+		{"msg": "first", "request_id": "abc123", "owner": "numtide", "cache": "miss"},
+		{"msg": "second", "request_id": "abc123", "owner": "numtide", "cache": "hit", "bytes": float64(42)},
+		{"msg": "outside", "request_id": nil, "cache": nil},
+	}
+	for i, fields := range want {
+		for key, value := range fields {
+			if records[i][key] != value {
+				t.Errorf("record %d: %s = %v, want %v", i, key, records[i][key], value)
+			}
+		}
+	}
+
+	if got := RequestID(derived); got != "abc123" {
+		t.Errorf("RequestID() = %q, want %q", got, "abc123")
+	}
+	if got := RequestID(context.Background()); got != "" {
+		t.Errorf("RequestID() outside a request = %q", got)
+	}
+	Add(context.Background(), "ignored", true)
+}
+
+func TestSetup(t *testing.T) {
+	previous := slog.Default()
+	defer slog.SetDefault(previous)
+
+	if err := Setup("error", FormatText); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if slog.Default().Enabled(context.Background(), slog.LevelWarn) {
+		t.Error("warnings are logged at level error")
+	}
+}
@@ -0,0 +1,328 @@
+// Package metrics is a small registry of counters, histograms and gauges
+// exposed in the Prometheus text format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are histogram buckets in seconds suited to request latency
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Sample is one value of a metric collected by a function
+type Sample struct {
+	// LabelValues are in the order of the metric's label names
+	LabelValues []string
+	Value       float64
+}
+
+// collector writes one metric family
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// Registry holds metrics and serves them in the Prometheus text format
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+	names      map[string]bool
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// register adds a collector, panicking on duplicate names like
+// http.HandleFunc does, since that is a programming error
+func (r *Registry) register(name string, c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[name] {
+		panic(fmt.Sprintf("metrics: duplicate metric %s", name))
+	}
+	r.names[name] = true
+	r.collectors = append(r.collectors, c)
+}
+
+// Counter registers a counter with the given label names
+func (r *Registry) Counter(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{family: newFamily(name, help, labels), values: make(map[string]*counterValue)}
+	r.register(name, c)
+	return c
+}
+
+// Histogram registers a histogram with the given upper bucket bounds and
+// label names
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{
+		family:  newFamily(name, help, labels),
+		buckets: append([]float64(nil), buckets...),
+		values:  make(map[string]*histogramValue),
+	}
+	sort.Float64s(h.buckets)
+	r.register(name, h)
+	return h
+}
+
+// GaugeFunc registers a gauge whose samples are collected by fn on every
+// scrape
+func (r *Registry) GaugeFunc(name, help string, labels []string, fn func() []Sample) {
+	r.register(name, &funcCollector{family: newFamily(name, help, labels), kind: "gauge", fn: fn})
+}
+
+// CounterFunc registers a counter whose samples are collected by fn on
+// every scrape, for counts kept elsewhere
+func (r *Registry) CounterFunc(name, help string, labels []string, fn func() []Sample) {
+	r.register(name, &funcCollector{family: newFamily(name, help, labels), kind: "counter", fn: fn})
+}
+
+// WriteTo writes all metrics in the Prometheus text format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	counter := &countingWriter{w: w}
+	buf := bufio.NewWriter(counter)
+	for _, c := range collectors {
+		c.write(buf)
+	}
+	err := buf.Flush()
+	return counter.n, err
+}
+
+// ServeHTTP serves the metrics to a Prometheus scrape
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := r.WriteTo(w); err != nil {
+		slog.WarnContext(req.Context(), "Failed to write metrics", "error", err)
+	}
+}
+
+// family holds what all metric types share
+type family struct {
+	name   string
+	help   string
+	labels []string
+}
+
+func newFamily(name, help string, labels []string) family {
+	return family{name: name, help: help, labels: labels}
+}
+
+// header writes the HELP and TYPE lines
+func (f family) header(w *bufio.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, escapeHelp(f.help), f.name, kind)
+}
+
+// key joins label values into a map key
+func (f family) key(values []string) string {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// labelPairs formats label names and values, with extra pairs appended
+func (f family) labelPairs(values []string, extra ...string) string {
+	if len(values) == 0 && len(extra) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(values)+len(extra)/2)
+	for i, value := range values {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, f.labels[i], escapeLabel(value)))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, extra[i], escapeLabel(extra[i+1])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// CounterVec is a counter partitioned by labels
+type CounterVec struct {
+	family
+	mu     sync.Mutex
+	values map[string]*counterValue
+}
+
+type counterValue struct {
+	labels []string
+	value  float64
+}
+
+// Counter is one series of a CounterVec
+type Counter struct {
+	vec   *CounterVec
+	value *counterValue
+}
+
+// With returns the series with the given label values
+func (c *CounterVec) With(values ...string) Counter {
+	key := c.key(values)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.values[key]
+	if !ok {
+		v = &counterValue{labels: append([]string(nil), values...)}
+		c.values[key] = v
+	}
+	return Counter{vec: c, value: v}
+}
+
+// Inc adds one to the counter
+func (c Counter) Inc() {
+	c.Add(1)
+}
+
+// Add adds a non-negative value to the counter
+func (c Counter) Add(delta float64) {
+	if delta < 0 {
+		return
+	}
+	c.vec.mu.Lock()
+	c.value.value += delta
+	c.vec.mu.Unlock()
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.header(w, "counter")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range sortedKeys(c.values) {
+		v := c.values[key]
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.labelPairs(v.labels), formatValue(v.value))
+	}
+}
+
+// HistogramVec is a histogram partitioned by labels
+type HistogramVec struct {
+	family
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labels []string
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Histogram is one series of a HistogramVec
+type Histogram struct {
+	vec   *HistogramVec
+	value *histogramValue
+}
+
+// With returns the series with the given label values
+func (h *HistogramVec) With(values ...string) Histogram {
+	key := h.key(values)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	v, ok := h.values[key]
+	if !ok {
+		v = &histogramValue{labels: append([]string(nil), values...), counts: make([]uint64, len(h.buckets))}
+		h.values[key] = v
+	}
+	return Histogram{vec: h, value: v}
+}
+
+// Observe records a value
+func (h Histogram) Observe(value float64) {
+	h.vec.mu.Lock()
+	defer h.vec.mu.Unlock()
+	for i, bound := range h.vec.buckets {
+		if value <= bound {
+			h.value.counts[i]++
+		}
+	}
+	h.value.count++
+	h.value.sum += value
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.header(w, "histogram")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, key := range sortedKeys(h.values) {
+		v := h.values[key]
+		for i, bound := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(v.labels, "le", formatValue(bound)), v.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(v.labels, "le", "+Inf"), v.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labelPairs(v.labels), formatValue(v.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labelPairs(v.labels), v.count)
+	}
+}
+
+// funcCollector collects samples from a function on every scrape
+type funcCollector struct {
+	family
+	kind string
+	fn   func() []Sample
+}
+
+func (f *funcCollector) write(w *bufio.Writer) {
+	f.header(w, f.kind)
+	samples := f.fn()
+	sort.Slice(samples, func(i, j int) bool {
+		return f.key(samples[i].LabelValues) < f.key(samples[j].LabelValues)
+	})
+	for _, s := range samples {
+		fmt.Fprintf(w, "%s%s %s\n", f.name, f.labelPairs(s.LabelValues), formatValue(s.Value))
+	}
+}
+
+// sortedKeys returns the keys of m in order, so output is stable
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatValue formats a sample value as Prometheus expects
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// escapeHelp escapes a HELP text
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+// escapeLabel escapes a label value
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
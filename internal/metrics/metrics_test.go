@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+
+	requests := r.Counter("http_requests_total", "Requests served.", "route", "status")
+	requests.With("/banner", "200").Inc()
+	requests.With("/banner", "200").Add(2)
+	requests.With("/health", "500").Inc()
+
+	latency := r.Histogram("http_request_duration_seconds", "Request latency.", []float64{1, 0.1}, "route")
+	latency.With("/banner").Observe(0.05)
+	latency.With("/banner").Observe(0.5)
+	latency.With("/banner").Observe(2)
+
+	r.GaugeFunc("rate_limit_remaining", "Remaining \"requests\".", []string{"host"}, func() []Sample {
+		return []Sample{{LabelValues: []string{"b\\\"\n"}, Value: 1}, {LabelValues: []string{"a"}, Value: 4999}}
+	})
+	r.CounterFunc("reloads_total", "Reloads.", nil, func() []Sample {
+		return []Sample{{Value: 3}}
+	})
+
+	want := `# HELP http_requests_total Requests served.
+# TYPE http_requests_total counter
+http_requests_total{route="/banner",status="200"} 3
+http_requests_total{route="/health",status="500"} 1
+# HELP http_request_duration_seconds Request latency.
+# TYPE http_request_duration_seconds histogram
+http_request_duration_seconds_bucket{route="/banner",le="0.1"} 1
+http_request_duration_seconds_bucket{route="/banner",le="1"} 2
+http_request_duration_seconds_bucket{route="/banner",le="+Inf"} 3
+http_request_duration_seconds_sum{route="/banner"} 2.55
+http_request_duration_seconds_count{route="/banner"} 3
+# HELP rate_limit_remaining Remaining "requests".
+# TYPE rate_limit_remaining gauge
+rate_limit_remaining{host="a"} 4999
+rate_limit_remaining{host="b\\\"\n"} 1
+# HELP reloads_total Reloads.
+# TYPE reloads_total counter
+reloads_total 3
+`
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if got := rec.Body.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("got Content-Type %q", rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestRegistryRejectsDuplicates(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("requests_total", "Requests.")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a metric twice did not panic")
+		}
+	}()
+	r.Counter("requests_total", "Requests.")
+}
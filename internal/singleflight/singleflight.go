@@ -0,0 +1,93 @@
+package singleflight
+
+import (
+	"context"
+	"sync"
+)
+
+// call is an in-flight or completed Do call
+type call[T any] struct {
+	done    chan struct{}
+	val     T
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// Group coalesces concurrent calls for the same key into a single operation
+type Group[T any] struct {
+	mu    sync.Mutex
+	calls map[string]*call[T]
+}
+
+// Do executes fn for the given key, making sure only one execution is in
+// flight at a time. Concurrent callers with the same key wait for the
+// original call and receive its result; shared reports whether this caller
+// joined a call already in flight rather than starting it, so it is false
+// for the caller that ran fn even when others joined.
+//
+// fn runs with a context detached from any single caller, so one caller
+// giving up does not abort the work for the others. Each caller still
+// returns as soon as its own ctx is done, and the shared context is
+// cancelled once every waiter has given up.
+func (g *Group[T]) Do(ctx context.Context, key string, fn func(ctx context.Context) (T, error)) (v T, shared bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call[T])
+	}
+
+	if c, ok := g.calls[key]; ok {
+		c.waiters++
+		g.mu.Unlock()
+		return g.wait(ctx, key, c, true)
+	}
+
+	flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	c := &call[T]{
+		done:    make(chan struct{}),
+		waiters: 1,
+		cancel:  cancel,
+	}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	go func() {
+		defer cancel()
+
+		c.val, c.err = fn(flightCtx)
+
+		g.mu.Lock()
+		if g.calls[key] == c {
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+
+		close(c.done)
+	}()
+
+	return g.wait(ctx, key, c, false)
+}
+
+// wait blocks until the call completes or ctx is done
+func (g *Group[T]) wait(ctx context.Context, key string, c *call[T], shared bool) (T, bool, error) {
+	select {
+	case <-c.done:
+		return c.val, shared, c.err
+	case <-ctx.Done():
+	}
+
+	g.mu.Lock()
+	c.waiters--
+	if c.waiters == 0 {
+		// Nobody is interested anymore; abort the work and let the next
+		// caller start a fresh flight
+		c.cancel()
+		if g.calls[key] == c {
+			delete(g.calls, key)
+		}
+	}
+	g.mu.Unlock()
+
+	var zero T
+	return zero, shared, ctx.Err()
+}
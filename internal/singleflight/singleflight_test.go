@@ -0,0 +1,173 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoCoalescesConcurrentCalls(t *testing.T) {
+	var g Group[string]
+	var calls atomic.Int32
+	release := make(chan struct{})
+
+	const n = 20
+	var wg sync.WaitGroup
+	results := make([]string, n)
+	errs := make([]error, n)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _, errs[i] = g.Do(context.Background(), "key", func(ctx context.Context) (string, error) {
+				calls.Add(1)
+				<-release
+				return "value", nil
+			})
+		}(i)
+	}
+
+	// Give every goroutine a chance to join the flight
+	waitForWaiters(t, &g, "key", n)
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("fn called %d times, want 1", got)
+	}
+	for i := 0; i < n; i++ {
+		if errs[i] != nil {
+			t.Errorf("caller %d: unexpected error: %v", i, errs[i])
+		}
+		if results[i] != "value" {
+			t.Errorf("caller %d: got %q, want %q", i, results[i], "value")
+		}
+	}
+}
+
+func TestDoDistinctKeys(t *testing.T) {
+	var g Group[int]
+	var calls atomic.Int32
+
+	var wg sync.WaitGroup
+	for _, key := range []string{"a", "b", "c"} {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			_, _, _ = g.Do(context.Background(), key, func(ctx context.Context) (int, error) {
+				calls.Add(1)
+				return 0, nil
+			})
+		}(key)
+	}
+	wg.Wait()
+
+	if got := calls.Load(); got != 3 {
+		t.Errorf("fn called %d times, want 3", got)
+	}
+}
+
+func TestDoWaiterCancellation(t *testing.T) {
+	var g Group[string]
+	release := make(chan struct{})
+	defer close(release)
+
+	leaderDone := make(chan error, 1)
+	go func() {
+		_, _, err := g.Do(context.Background(), "key", func(ctx context.Context) (string, error) {
+			<-release
+			return "value", nil
+		})
+		leaderDone <- err
+	}()
+	waitForWaiters(t, &g, "key", 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	waiterDone := make(chan error, 1)
+	go func() {
+		_, shared, err := g.Do(ctx, "key", func(ctx context.Context) (string, error) {
+			t.Error("waiter should not start its own flight")
+			return "", nil
+		})
+		if !shared {
+			t.Error("waiter result should be shared")
+		}
+		waiterDone <- err
+	}()
+	waitForWaiters(t, &g, "key", 2)
+
+	cancel()
+	select {
+	case err := <-waiterDone:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("waiter got %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cancelled waiter did not return")
+	}
+
+	// The leader is unaffected by the waiter giving up
+	select {
+	case err := <-leaderDone:
+		t.Fatalf("leader returned early: %v", err)
+	default:
+	}
+}
+
+func TestDoCancelsFlightWhenAllWaitersLeave(t *testing.T) {
+	var g Group[string]
+	flightCancelled := make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _, _ = g.Do(ctx, "key", func(ctx context.Context) (string, error) {
+			<-ctx.Done()
+			close(flightCancelled)
+			return "", ctx.Err()
+		})
+	}()
+	waitForWaiters(t, &g, "key", 1)
+
+	cancel()
+	<-done
+
+	select {
+	case <-flightCancelled:
+	case <-time.After(time.Second):
+		t.Fatal("flight context was not cancelled after the last waiter left")
+	}
+
+	// A new caller starts a fresh flight
+	v, shared, err := g.Do(context.Background(), "key", func(ctx context.Context) (string, error) {
+		return "fresh", nil
+	})
+	if err != nil || v != "fresh" || shared {
+		t.Errorf("got (%q, %v, %v), want (\"fresh\", false, nil)", v, shared, err)
+	}
+}
+
+// waitForWaiters blocks until the flight for key has n waiters
+func waitForWaiters[T any](t *testing.T, g *Group[T], key string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		g.mu.Lock()
+		c, ok := g.calls[key]
+		waiters := 0
+		if ok {
+			waiters = c.waiters
+		}
+		g.mu.Unlock()
+		if waiters >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d waiters on %q", n, key)
+}
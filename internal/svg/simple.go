@@ -29,8 +29,10 @@ func (d *SimpleDocument) UpdateTextByID(id, newText string) error {
 		return fmt.Errorf("element with id '%s' not found", id)
 	}
 
-	// Replace with opening tag + tspan with new text + closing tag
-	d.content = re.ReplaceAllString(d.content, "${1}<tspan>"+escapeXML(newText)+"</tspan>${2}")
+	// Replace with opening tag + tspan with new text + closing tag; "$" in
+	// the text must not expand as a submatch reference
+	text := strings.ReplaceAll(EscapeXML(newText), "$", "$$")
+	d.content = re.ReplaceAllString(d.content, "${1}<tspan>"+text+"</tspan>${2}")
 	return nil
 }
 
@@ -71,9 +73,9 @@ func (d *SimpleDocument) UpdateMultilineText(id string, lines []string) error {
 
 	for i, line := range lines {
 		if i == 0 {
-			newElement.WriteString(fmt.Sprintf(`<tspan x="%s" dy="0">%s</tspan>`, x, escapeXML(line)))
+			newElement.WriteString(fmt.Sprintf(`<tspan x="%s" dy="0">%s</tspan>`, x, EscapeXML(line)))
 		} else {
-			newElement.WriteString(fmt.Sprintf(`<tspan x="%s" dy="1.2em">%s</tspan>`, x, escapeXML(line)))
+			newElement.WriteString(fmt.Sprintf(`<tspan x="%s" dy="1.2em">%s</tspan>`, x, EscapeXML(line)))
 		}
 	}
 
@@ -87,7 +89,7 @@ func (d *SimpleDocument) UpdateMultilineText(id string, lines []string) error {
 // HideElementByID hides an element by adding visibility="hidden"
 func (d *SimpleDocument) HideElementByID(id string) error {
 	// Pattern to match any element with the given ID
-	pattern := fmt.Sprintf(`(<[^>]+\sid="%s"[^>]*)>`, id)
+	pattern := fmt.Sprintf(`(<[^>]+\sid="%s"[^>]*?)(\s*/?)>`, id)
 	re, err := regexp.Compile(pattern)
 	if err != nil {
 		return fmt.Errorf("failed to compile regex: %w", err)
@@ -98,7 +100,7 @@ func (d *SimpleDocument) HideElementByID(id string) error {
 	}
 
 	// Add visibility="hidden" attribute
-	d.content = re.ReplaceAllString(d.content, `${1} visibility="hidden">`)
+	d.content = re.ReplaceAllString(d.content, `${1} visibility="hidden"${2}>`)
 	return nil
 }
 
@@ -125,13 +127,94 @@ func (d *SimpleDocument) InjectCSS(styleID, css string) error {
 	return nil
 }
 
+// ElementAttributes returns the attributes of the element with the given ID
+func (d *SimpleDocument) ElementAttributes(id string) (map[string]string, error) {
+	loc, err := d.findOpeningTag(id)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := make(map[string]string)
+	for _, attr := range attrRe.FindAllStringSubmatch(d.content[loc[4]:loc[5]], -1) {
+		attrs[attr[1]] = attr[2]
+	}
+	return attrs, nil
+}
+
+// attrRe matches one name="value" attribute
+var attrRe = regexp.MustCompile(`([a-zA-Z_:][-a-zA-Z0-9_:.]*)="([^"]*)"`)
+
+// SetElementContent replaces the children of the element with the given ID
+// with raw SVG markup. The element must not contain nested elements of the
+// same type; self-closing elements are expanded.
+func (d *SimpleDocument) SetElementContent(id, content string) error {
+	loc, err := d.findOpeningTag(id)
+	if err != nil {
+		return err
+	}
+
+	tag := d.content[loc[2]:loc[3]]
+	attrs := d.content[loc[4]:loc[5]]
+	end := loc[1]
+	if loc[6] < 0 {
+		// Not self-closing: replace everything up to the closing tag
+		closing := "</" + tag + ">"
+		i := strings.Index(d.content[end:], closing)
+		if i < 0 {
+			return fmt.Errorf("element with id '%s' is not closed", id)
+		}
+		end += i + len(closing)
+	}
+
+	element := fmt.Sprintf("<%s%s>%s</%s>", tag, attrs, content, tag)
+	d.content = d.content[:loc[0]] + element + d.content[end:]
+	return nil
+}
+
+// SetAttributeByID sets an attribute on the element with the given ID,
+// replacing any existing value
+func (d *SimpleDocument) SetAttributeByID(id, name, value string) error {
+	loc, err := d.findOpeningTag(id)
+	if err != nil {
+		return err
+	}
+
+	attrs := d.content[loc[4]:loc[5]]
+	attr := fmt.Sprintf(`%s="%s"`, name, EscapeXML(value))
+	existing := regexp.MustCompile(`\s` + regexp.QuoteMeta(name) + `="[^"]*"`)
+	if existing.MatchString(attrs) {
+		attrs = existing.ReplaceAllLiteralString(attrs, " "+attr)
+	} else {
+		attrs += " " + attr
+	}
+
+	d.content = d.content[:loc[4]] + attrs + d.content[loc[5]:]
+	return nil
+}
+
+// findOpeningTag locates the opening tag of the element with the given ID.
+// The submatches are the tag name, its attributes and the self-closing slash.
+func (d *SimpleDocument) findOpeningTag(id string) ([]int, error) {
+	pattern := fmt.Sprintf(`<([a-zA-Z]+)((?:\s[^>]*)?\sid="%s"[^>]*?)\s*(/)?>`, regexp.QuoteMeta(id))
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile regex: %w", err)
+	}
+
+	loc := re.FindStringSubmatchIndex(d.content)
+	if loc == nil {
+		return nil, fmt.Errorf("element with id '%s' not found", id)
+	}
+	return loc, nil
+}
+
 // String returns the SVG content as a string
 func (d *SimpleDocument) String() string {
 	return d.content
 }
 
-// escapeXML escapes special XML characters
-func escapeXML(s string) string {
+// EscapeXML escapes special XML characters for text and attribute values
+func EscapeXML(s string) string {
 	s = strings.ReplaceAll(s, "&", "&amp;")
 	s = strings.ReplaceAll(s, "<", "&lt;")
 	s = strings.ReplaceAll(s, ">", "&gt;")
@@ -39,6 +39,18 @@ func TestUpdateTextByID(t *testing.T) {
 	}
 }
 
+func TestUpdateTextByIDLiteral(t *testing.T) {
+	doc := NewSimpleDocument(`<svg><text id="title">Old</text></svg>`)
+	if err := doc.UpdateTextByID("title", "$1 & ${2} <b>"); err != nil {
+		t.Fatalf("Failed to update title: %v", err)
+	}
+
+	want := `<svg><text id="title"><tspan>$1 &amp; ${2} &lt;b&gt;</tspan></text></svg>`
+	if got := doc.String(); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
 func TestUpdateMultilineText(t *testing.T) {
 	svg := `<svg>
 		<text id="description" x="50" y="100">
@@ -128,9 +140,72 @@ func TestEscapeXML(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		result := escapeXML(tt.input)
+		result := EscapeXML(tt.input)
 		if result != tt.expected {
-			t.Errorf("escapeXML(%q) = %q, want %q", tt.input, result, tt.expected)
+			t.Errorf("EscapeXML(%q) = %q, want %q", tt.input, result, tt.expected)
 		}
 	}
 }
+
+func TestElementAttributes(t *testing.T) {
+	doc := NewSimpleDocument(`<svg><g
+   id="contributors" data-count="5"
+   transform="translate(10 20)"/></svg>`)
+
+	attrs, err := doc.ElementAttributes("contributors")
+	if err != nil {
+		t.Fatalf("ElementAttributes failed: %v", err)
+	}
+	if attrs["data-count"] != "5" || attrs["transform"] != "translate(10 20)" {
+		t.Errorf("unexpected attributes: %v", attrs)
+	}
+
+	if _, err := doc.ElementAttributes("missing"); err == nil {
+		t.Error("expected error for missing element")
+	}
+}
+
+func TestSetElementContent(t *testing.T) {
+	tests := []struct {
+		name     string
+		svg      string
+		expected string
+	}{
+		{
+			"self-closing",
+			`<svg><g id="slot" data-size="48"/></svg>`,
+			`<svg><g id="slot" data-size="48"><circle r="1"/></g></svg>`,
+		},
+		{
+			"with placeholder",
+			`<svg><g id="slot"><text>placeholder</text></g><g id="other"/></svg>`,
+			`<svg><g id="slot"><circle r="1"/></g><g id="other"/></svg>`,
+		},
+	}
+
+	for _, tt := range tests {
+		doc := NewSimpleDocument(tt.svg)
+		if err := doc.SetElementContent("slot", `<circle r="1"/>`); err != nil {
+			t.Fatalf("%s: SetElementContent failed: %v", tt.name, err)
+		}
+		if doc.String() != tt.expected {
+			t.Errorf("%s: got %s, want %s", tt.name, doc.String(), tt.expected)
+		}
+	}
+}
+
+func TestSetAttributeByID(t *testing.T) {
+	doc := NewSimpleDocument(`<svg><circle id="dot" fill="#000" r="4"/><rect id="bar"/></svg>`)
+
+	if err := doc.SetAttributeByID("dot", "fill", "#00add8"); err != nil {
+		t.Fatalf("SetAttributeByID failed: %v", err)
+	}
+	if err := doc.SetAttributeByID("bar", "width", "10"); err != nil {
+		t.Fatalf("SetAttributeByID failed: %v", err)
+	}
+
+	expected := `<svg><circle id="dot" fill="#00add8" r="4"/><rect id="bar" width="10"/></svg>`
+	if doc.String() != expected {
+		t.Errorf("got %s, want %s", doc.String(), expected)
+	}
+}